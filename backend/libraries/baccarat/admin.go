@@ -0,0 +1,88 @@
+package baccarat
+
+// admin.go
+// This file contains what admins see of a baccarat table, the shoe included, and
+// pausing the table: betting stays open but no round is dealt until it is resumed.
+
+import (
+	carddeck "cardgames/backend/libraries/cardDeck"
+	"cardgames/backend/libraries/game"
+)
+
+// AdminState is the full state of a baccarat table for admins.
+type AdminState struct {
+	Mode       string
+	Rules      Rules
+	Players    []AdminPlayer
+	PlayerHand []carddeck.Card
+	BankerHand []carddeck.Card
+	Outcome    *Outcome
+	Shoe       []carddeck.Card // cards left in the shoe, in dealing order
+	Tally      Tally           // results since the shoe was started
+	HostID     uint
+}
+
+// AdminPlayer is a player at the table as admins see them.
+type AdminPlayer struct {
+	PlayerInfo
+	Balance   int
+	Won       int
+	Connected bool
+}
+
+// Inspect returns the table state for admins.
+func (t *Table) Inspect() game.Inspection {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state := AdminState{
+		Mode:       t.mode,
+		Rules:      t.rules,
+		PlayerHand: append([]carddeck.Card(nil), t.playerHand...),
+		BankerHand: append([]carddeck.Card(nil), t.bankerHand...),
+		Shoe:       append([]carddeck.Card(nil), t.shoe...),
+		Tally:      t.roadmap.Tally,
+		HostID:     t.hostID,
+	}
+	if t.outcome != nil {
+		outcome := *t.outcome
+		state.Outcome = &outcome
+	}
+	ids := make([]uint, 0, len(t.Players))
+	for _, p := range t.Players {
+		bets := make(map[BetSpot]int, len(p.Bets))
+		for spot, amount := range p.Bets {
+			bets[spot] = amount
+		}
+		state.Players = append(state.Players, AdminPlayer{
+			PlayerInfo: PlayerInfo{ID: p.ID, Username: p.Account.Username, Bets: bets},
+			Balance:    p.Account.Balance,
+			Won:        p.Won,
+			Connected:  p.Connected,
+		})
+		ids = append(ids, p.ID)
+	}
+
+	return game.Inspection{
+		Phase:        string(t.phase),
+		PlayerIDs:    ids,
+		ShoeDepth:    len(t.shoe),
+		RoundsPlayed: t.roundsPlayed,
+		Paused:       t.paused,
+		State:        state,
+	}
+}
+
+// SetPaused stops (or resumes) dealing rounds. The round in progress is played out.
+func (t *Table) SetPaused(paused bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.paused = paused
+}
+
+// Paused reports whether the table is paused.
+func (t *Table) Paused() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.paused
+}
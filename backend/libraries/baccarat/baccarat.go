@@ -0,0 +1,694 @@
+package baccarat
+
+// baccarat.go
+// This file contains the main logic for a punto banco baccarat table: seating, bets,
+// the betting and dealing loop, settlement and the state sent to players.
+
+// Like blackjack, a round opens with a timed betting phase. Players bet on the Player
+// hand, the Banker hand or a tie, with optional pair side bets, and the bets come out
+// of their account balance when betting closes. The hands are then dealt by the fixed
+// drawing rules in rules.go, the bets are paid and every player's round is recorded
+// as a wager.
+
+// All table state is guarded by the table mutex. The game loop holds it for every step
+// and releases it while it pauses between cards.
+
+import (
+	carddeck "cardgames/backend/libraries/cardDeck"
+	"cardgames/backend/libraries/game"
+	"cardgames/backend/models"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// ------------------------------------------------------------------
+// Constants and Types
+// ------------------------------------------------------------------
+const (
+	BettingTimeLimit   = 15 // seconds
+	MaxPlayersPerTable = 14
+	cardDelay          = 700 * time.Millisecond // pause between cards
+	resultDelay        = 4 * time.Second        // time the result stays up before betting opens
+)
+
+// Table modes, chosen in the lobby.
+const (
+	StandardMode     = "standard"      // Banker wins pay 0.95:1 after the 5% commission
+	NoCommissionMode = "no_commission" // Banker wins pay 1:1, except 1:2 when the Banker wins with 6
+)
+
+// GamePhase represents the current phase of the round.
+type GamePhase string
+
+const (
+	Betting GamePhase = "betting"
+	Dealing GamePhase = "dealing"
+	Result  GamePhase = "result"
+)
+
+// Action represents the type of action a player can take.
+type Action string
+
+const (
+	BetAction   Action = "bet"   // add Amount to the bet on Spot
+	ClearAction Action = "clear" // take back every bet placed this round
+	LeaveAction Action = "leave"
+)
+
+// BetSpot is a place on the layout a player can bet on.
+type BetSpot string
+
+const (
+	PlayerSpot     BetSpot = "player"
+	BankerSpot     BetSpot = "banker"
+	TieSpot        BetSpot = "tie"
+	PlayerPairSpot BetSpot = "player_pair" // the Player's first two cards are a pair
+	BankerPairSpot BetSpot = "banker_pair" // the Banker's first two cards are a pair
+)
+
+// betSpots lists every spot in layout order.
+var betSpots = []BetSpot{PlayerSpot, BankerSpot, TieSpot, PlayerPairSpot, BankerPairSpot}
+
+// Winner is the result of a round.
+type Winner string
+
+const (
+	PlayerWin Winner = "player"
+	BankerWin Winner = "banker"
+	Tie       Winner = "tie"
+)
+
+// Outcome describes how a round ended.
+type Outcome struct {
+	Winner      Winner
+	PlayerTotal int
+	BankerTotal int
+	PlayerPair  bool
+	BankerPair  bool
+	Natural     bool // one of the hands was a natural 8 or 9
+}
+
+// IncomingUpdate is a message from a player to the table.
+type IncomingUpdate struct {
+	PlayerID uint
+	Action   Action
+	Spot     BetSpot // Optional, only used for BetAction
+	Amount   int     // Optional, only used for BetAction
+}
+
+// OutgoingUpdate is a message from the table to a player.
+type OutgoingUpdate struct {
+	Phase       GamePhase
+	Mode        string
+	YourID      uint
+	YourBets    map[BetSpot]int
+	YourBalance int // account balance less the bets placed this round
+	PlayerHand  []carddeck.Card
+	BankerHand  []carddeck.Card
+	Outcome     *Outcome `json:",omitempty"` // set once the round is decided
+	YourWin     int      // chips returned to this player by the last round, stakes included
+	Players     []PlayerInfo
+	Roadmap     Roadmap
+	Rules       Rules
+	Notice      string `json:",omitempty"` // message for this player only
+}
+
+// PlayerInfo contains public information about a player.
+type PlayerInfo struct {
+	ID       uint
+	Username string
+	Bets     map[BetSpot]int
+}
+
+//------------------------------------------------------------------
+// Structs
+//------------------------------------------------------------------
+
+// Player represents a player at the table.
+type Player struct {
+	ID        uint
+	Account   *models.Account
+	Bets      map[BetSpot]int // bets placed this round
+	Won       int             // chips returned by the last round
+	Connected bool
+	Incoming  chan json.RawMessage
+	Outgoing  chan any
+	stop      chan struct{}
+	heldUntil time.Time // player restored from a snapshot is kept at the table until then
+}
+
+// totalBet returns the sum of the player's bets this round.
+func (p *Player) totalBet() int {
+	total := 0
+	for _, amount := range p.Bets {
+		total += amount
+	}
+	return total
+}
+
+// Options customizes a baccarat table.
+type Options struct {
+	Mode       string                      // StandardMode or NoCommissionMode, defaults to StandardMode
+	Rules      *Rules                      // table rules, defaults to DefaultRules
+	HostID     uint                        // creator of a private table
+	Password   string                      // optional password required to join
+	OnSnapshot func(state json.RawMessage) // optional hook the table state is saved through, see snapshot.go
+}
+
+// Table is a baccarat table.
+type Table struct {
+	DB           *gorm.DB
+	Players      []*Player
+	shoe         carddeck.Deck
+	playerHand   []carddeck.Card
+	bankerHand   []carddeck.Card
+	outcome      *Outcome
+	roadmap      Roadmap
+	phase        GamePhase
+	roundsPlayed int
+	paused       bool // betting stays open and no round is dealt
+	mode         string
+	rules        Rules
+	hostID       uint
+	passwordHash []byte
+	onSnapshot   func(state json.RawMessage)
+	incoming     chan IncomingUpdate
+	idleSince    time.Time
+	done         chan struct{}
+	closeOnce    sync.Once
+	suspended    bool // closed by Suspend, the bets of a round being dealt are left to the snapshot
+	mu           sync.Mutex
+}
+
+// NewTable creates a baccarat table customized by opts and starts its game loop.
+func NewTable(db *gorm.DB, opts Options) *Table {
+	t := newTable(db, opts)
+	go t.gameLoop()
+	return t
+}
+
+// newTable creates a baccarat table customized by opts without starting its game loop.
+func newTable(db *gorm.DB, opts Options) *Table {
+	t := &Table{
+		DB:         db,
+		phase:      Betting,
+		mode:       opts.Mode,
+		rules:      DefaultRules(),
+		hostID:     opts.HostID,
+		onSnapshot: opts.OnSnapshot,
+		incoming:   make(chan IncomingUpdate),
+		idleSince:  time.Now(),
+		done:       make(chan struct{}),
+	}
+	if t.mode == "" {
+		t.mode = StandardMode
+	}
+	if opts.Rules != nil {
+		t.rules = *opts.Rules
+	}
+	if opts.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(opts.Password), bcrypt.DefaultCost)
+		if err != nil {
+			log.Println("Failed to hash table password:", err)
+		} else {
+			t.passwordHash = hash
+		}
+	}
+	t.newShoe()
+
+	return t
+}
+
+//------------------------------------------------------------------
+// Seating
+//------------------------------------------------------------------
+
+// Join adds a player to the table, or reconnects them if they are already at it.
+func (t *Table) Join(req game.JoinRequest) (*game.Seat, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	select {
+	case <-t.done:
+		return nil, game.ErrTableClosed
+	default:
+	}
+
+	if p := t.findPlayer(req.PlayerID); p != nil {
+		// Reconnect: close the old connection's channels and hand out new ones
+		t.detach(p)
+		p.Incoming = make(chan json.RawMessage)
+		p.Outgoing = make(chan any, 10)
+		p.Connected = true
+		p.heldUntil = time.Time{}
+		t.updateIdle()
+		t.startForwarding(p)
+		return seatOf(p), nil
+	}
+
+	if !req.Invited && t.passwordHash != nil && bcrypt.CompareHashAndPassword(t.passwordHash, []byte(req.Password)) != nil {
+		return nil, game.ErrWrongPassword
+	}
+
+	if len(t.Players) >= MaxPlayersPerTable {
+		return nil, game.ErrTableFull
+	}
+
+	var account models.Account
+	if err := t.DB.First(&account, req.PlayerID).Error; err != nil {
+		return nil, game.ErrNotAllowed
+	}
+
+	p := &Player{
+		ID:        req.PlayerID,
+		Account:   &account,
+		Bets:      make(map[BetSpot]int),
+		Connected: true,
+		Incoming:  make(chan json.RawMessage),
+		Outgoing:  make(chan any, 10),
+	}
+	t.Players = append(t.Players, p)
+	t.updateIdle()
+	t.startForwarding(p)
+
+	return seatOf(p), nil
+}
+
+// seatOf returns the seat handed to a player's connection.
+func seatOf(p *Player) *game.Seat {
+	return &game.Seat{PlayerID: p.ID, Actions: p.Incoming, State: p.Outgoing}
+}
+
+// Leave marks a player as disconnected. Bets they placed this round still play and
+// they are removed from the table before the next round. A seat the player has since
+// reconnected on is left alone.
+func (t *Table) Leave(seat *game.Seat) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if p := t.findPlayer(seat.PlayerID); p != nil && p.Outgoing == seat.State {
+		p.Connected = false
+		if t.phase == Betting {
+			// Bets are not taken until betting closes, so nothing is lost
+			t.removePlayer(p)
+		}
+		t.updateIdle()
+	}
+}
+
+// IsSeated reports whether the player is already at the table.
+func (t *Table) IsSeated(playerID uint) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.findPlayer(playerID) != nil
+}
+
+// Seats returns the number of players at the table and the most it takes.
+func (t *Table) Seats() (int, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.Players), MaxPlayersPerTable
+}
+
+// Describe summarizes the table for the lobby's table browser. The stakes are the
+// limits on a single spot, players without a bet this round count as spectators.
+func (t *Table) Describe() game.Summary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	spectators := 0
+	ids := make([]uint, 0, len(t.Players))
+	for _, p := range t.Players {
+		if p.totalBet() == 0 {
+			spectators++
+		}
+		ids = append(ids, p.ID)
+	}
+	return game.Summary{
+		Phase:         string(t.phase),
+		Rules:         t.rules.summary(t.mode),
+		MinStake:      t.rules.MinBet,
+		MaxStake:      t.rules.MaxBet,
+		Spectators:    spectators,
+		TurnSeconds:   t.rules.BettingSeconds,
+		BetweenRounds: t.phase == Betting,
+		PlayerIDs:     ids,
+	}
+}
+
+// HostID returns the player who created the table, 0 for public tables.
+func (t *Table) HostID() uint {
+	return t.hostID
+}
+
+// KeepWhenEmpty reports whether the table must not be cleaned up when idle.
+func (t *Table) KeepWhenEmpty() bool {
+	return false
+}
+
+// Greet sends a newly connected player the table state.
+func (t *Table) Greet(playerID uint) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if p := t.findPlayer(playerID); p != nil {
+		t.send(p, "")
+	}
+}
+
+// findPlayer returns the player with the given ID, or nil.
+func (t *Table) findPlayer(playerID uint) *Player {
+	for _, p := range t.Players {
+		if p.ID == playerID {
+			return p
+		}
+	}
+	return nil
+}
+
+// removePlayer takes a player off the table and closes their connection's channels.
+func (t *Table) removePlayer(p *Player) {
+	for i, other := range t.Players {
+		if other == p {
+			t.Players = append(t.Players[:i], t.Players[i+1:]...)
+			break
+		}
+	}
+	t.detach(p)
+}
+
+//------------------------------------------------------------------
+// Game Loop
+//------------------------------------------------------------------
+
+// gameLoop runs rounds until the table is closed: betting until the timer runs out,
+// then dealing and settling if anyone has bet.
+func (t *Table) gameLoop() {
+	defer t.shutdown()
+
+	timer := time.NewTimer(time.Duration(t.rules.BettingSeconds) * time.Second)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-t.done:
+			return
+		case update := <-t.incoming:
+			t.mu.Lock()
+			t.processUpdate(update)
+			t.mu.Unlock()
+		case <-timer.C:
+			t.mu.Lock()
+			paused := t.paused
+			t.mu.Unlock()
+			if paused {
+				timer.Reset(time.Duration(t.rules.BettingSeconds) * time.Second)
+				continue
+			}
+			if !t.playRound() {
+				return
+			}
+			timer.Reset(time.Duration(t.rules.BettingSeconds) * time.Second)
+		}
+	}
+}
+
+// processUpdate handles one message from a player.
+func (t *Table) processUpdate(update IncomingUpdate) {
+	p := t.findPlayer(update.PlayerID)
+	if p == nil {
+		return
+	}
+
+	switch update.Action {
+	case BetAction:
+		if notice := t.placeBet(p, update.Spot, update.Amount); notice != "" {
+			t.send(p, notice)
+			return
+		}
+	case ClearAction:
+		if t.phase != Betting {
+			return
+		}
+		p.Bets = make(map[BetSpot]int)
+	case LeaveAction:
+		if t.phase != Betting {
+			t.send(p, "You can leave once the round is over")
+			return
+		}
+		t.removePlayer(p)
+	default:
+		return
+	}
+	t.broadcast()
+}
+
+// placeBet adds chips to one of the player's bets. It returns a notice for the player
+// if the bet is not allowed.
+func (t *Table) placeBet(p *Player, spot BetSpot, amount int) string {
+	if t.phase != Betting {
+		return "Betting is closed"
+	}
+	valid := false
+	for _, s := range betSpots {
+		if s == spot {
+			valid = true
+		}
+	}
+	if !valid || amount <= 0 {
+		return "Invalid bet"
+	}
+	if !t.rules.betAllowed(p.Bets[spot] + amount) {
+		return "Bet is outside the table limits"
+	}
+	if p.totalBet()+amount > p.Account.Balance {
+		return "Not enough balance"
+	}
+	p.Bets[spot] += amount
+	return ""
+}
+
+// playRound closes betting, deals the hands and settles the bets. Nothing is dealt if
+// nobody has bet. Returns false if the table was closed in the middle of the round.
+func (t *Table) playRound() bool {
+	t.mu.Lock()
+	if !t.lockBets() {
+		t.mu.Unlock()
+		return true
+	}
+	t.phase = Dealing
+	t.outcome = nil
+	t.playerHand = nil
+	t.bankerHand = nil
+	t.saveSnapshot()
+	t.mu.Unlock()
+
+	// Player, Banker, Player, Banker
+	for i := 0; i < 4; i++ {
+		if !t.deal(i%2 == 0) {
+			return false
+		}
+	}
+
+	// Third cards
+	t.mu.Lock()
+	playerDrew := playerDraws(t.playerHand, t.bankerHand)
+	t.mu.Unlock()
+	if playerDrew && !t.deal(true) {
+		return false
+	}
+
+	t.mu.Lock()
+	var playerThird *carddeck.Card
+	if playerDrew {
+		playerThird = &t.playerHand[2]
+	}
+	bankerDrew := bankerDraws(t.playerHand, t.bankerHand, playerThird)
+	t.mu.Unlock()
+	if bankerDrew && !t.deal(false) {
+		return false
+	}
+
+	t.mu.Lock()
+	t.settle()
+	t.roundsPlayed++
+	t.phase = Result
+	t.saveSnapshot()
+	t.broadcast()
+	t.mu.Unlock()
+
+	if !t.sleep(resultDelay) {
+		return false
+	}
+
+	t.mu.Lock()
+	t.resetRound()
+	t.saveSnapshot()
+	t.broadcast()
+	t.mu.Unlock()
+	return true
+}
+
+// lockBets takes every player's bets out of their account balance. Players whose
+// balance no longer covers their bets sit the round out. Returns false if nobody bet.
+func (t *Table) lockBets() bool {
+	anyBets := false
+	for _, p := range t.Players {
+		total := p.totalBet()
+		if total == 0 {
+			continue
+		}
+		// Only take the chips if the balance still covers them
+		res := t.DB.Model(&models.Account{}).
+			Where("id = ? AND balance >= ?", p.ID, total).
+			Update("balance", gorm.Expr("balance - ?", total))
+		if res.Error != nil || res.RowsAffected == 0 {
+			p.Bets = make(map[BetSpot]int)
+			t.send(p, "Not enough balance, your bets were returned")
+			continue
+		}
+		p.Account.Balance -= total
+		anyBets = true
+	}
+	return anyBets
+}
+
+// deal draws a card for the Player hand (toPlayer) or the Banker hand and shows it.
+// Returns false if the table was closed during the pause that follows.
+func (t *Table) deal(toPlayer bool) bool {
+	t.mu.Lock()
+	card := t.shoe.Draw()
+	if toPlayer {
+		t.playerHand = append(t.playerHand, card)
+	} else {
+		t.bankerHand = append(t.bankerHand, card)
+	}
+	t.broadcast()
+	t.mu.Unlock()
+	return t.sleep(cardDelay)
+}
+
+// settle decides the round, pays every bet and records each player's wager.
+func (t *Table) settle() {
+	o := Outcome{
+		PlayerTotal: handTotal(t.playerHand),
+		BankerTotal: handTotal(t.bankerHand),
+		PlayerPair:  isPair(t.playerHand),
+		BankerPair:  isPair(t.bankerHand),
+		Natural:     isNatural(t.playerHand[:2]) || isNatural(t.bankerHand[:2]),
+	}
+	switch {
+	case o.PlayerTotal > o.BankerTotal:
+		o.Winner = PlayerWin
+	case o.BankerTotal > o.PlayerTotal:
+		o.Winner = BankerWin
+	default:
+		o.Winner = Tie
+	}
+	t.outcome = &o
+	t.roadmap.add(o)
+
+	for _, p := range t.Players {
+		total := p.totalBet()
+		if total == 0 {
+			continue
+		}
+		returned := 0
+		for spot, amount := range p.Bets {
+			returned += payout(spot, amount, o, t.mode)
+		}
+		p.Won = returned
+
+		if returned > 0 {
+			err := t.DB.Model(&models.Account{}).
+				Where("id = ?", p.ID).
+				Update("balance", gorm.Expr("balance + ?", returned)).Error
+			if err != nil {
+				log.Println("Failed to pay", returned, "chips to player", p.ID, ":", err)
+			} else {
+				p.Account.Balance += returned
+			}
+		}
+
+		t.DB.Create(&models.Wager{
+			AccountID:   p.ID,
+			WagerAmount: total,
+			WagerWon:    returned > total,
+			AmountWon:   returned,
+			GameType:    GameName,
+		})
+	}
+}
+
+// resetRound clears the table for the next round, removes disconnected players whose
+// seat is not being held and starts a new shoe when the current one runs low.
+func (t *Table) resetRound() {
+	now := time.Now()
+	for _, p := range append([]*Player(nil), t.Players...) {
+		if !p.Connected && !now.Before(p.heldUntil) {
+			t.removePlayer(p)
+			continue
+		}
+		p.Bets = make(map[BetSpot]int)
+		p.Won = 0
+	}
+	t.playerHand = nil
+	t.bankerHand = nil
+	t.outcome = nil
+	t.phase = Betting
+	if len(t.shoe) < 52 {
+		t.newShoe()
+	}
+}
+
+// newShoe shuffles a fresh shoe and starts a new roadmap.
+func (t *Table) newShoe() {
+	t.shoe = carddeck.NewDeck(t.rules.Decks)
+	t.shoe.Shuffle()
+	t.roadmap = Roadmap{}
+}
+
+//------------------------------------------------------------------
+// Updates
+//------------------------------------------------------------------
+
+// broadcast sends every player their view of the table.
+func (t *Table) broadcast() {
+	for _, p := range t.Players {
+		t.send(p, "")
+	}
+}
+
+// send delivers a player's view of the table without blocking the game loop.
+func (t *Table) send(p *Player, notice string) {
+	update := OutgoingUpdate{
+		Phase:       t.phase,
+		Mode:        t.mode,
+		YourID:      p.ID,
+		YourBets:    p.Bets,
+		YourBalance: p.Account.Balance,
+		PlayerHand:  t.playerHand,
+		BankerHand:  t.bankerHand,
+		Outcome:     t.outcome,
+		YourWin:     p.Won,
+		Roadmap:     t.roadmap,
+		Rules:       t.rules,
+		Notice:      notice,
+	}
+	if t.phase == Betting {
+		update.YourBalance -= p.totalBet()
+	}
+	for _, other := range t.Players {
+		update.Players = append(update.Players, PlayerInfo{ID: other.ID, Username: other.Account.Username, Bets: other.Bets})
+	}
+
+	select {
+	case p.Outgoing <- update:
+	default:
+		log.Println("Failed to send update to player", p.ID)
+	}
+}
@@ -0,0 +1,138 @@
+package baccarat
+
+// lifecycle.go
+// This file contains the lifecycle of a baccarat table: closing it, the goroutines that
+// forward player input to the game loop, and idle detection for the game instance manager.
+// Bets are only taken from balances once betting closes, so a round cut short by closing
+// the table pays every stake back.
+
+import (
+	"cardgames/backend/models"
+	"encoding/json"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Close stops the game loop, refunds any round in progress and closes the players'
+// Outgoing channels. It is safe to call more than once.
+func (t *Table) Close() {
+	t.closeOnce.Do(func() {
+		close(t.done)
+	})
+}
+
+// Suspend closes the table for a server restart. Unlike Close, the bets of a round
+// being dealt are not refunded, the last snapshot refunds them when it is restored.
+func (t *Table) Suspend() {
+	t.closeOnce.Do(func() {
+		t.suspended = true
+		close(t.done)
+	})
+}
+
+// Done returns a channel that is closed once the table has been closed.
+func (t *Table) Done() <-chan struct{} {
+	return t.done
+}
+
+// IdleFor returns how long the table has had no connected players, or 0 if
+// someone is connected right now.
+func (t *Table) IdleFor() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.idleSince.IsZero() {
+		return 0
+	}
+	return time.Since(t.idleSince)
+}
+
+// updateIdle records when the last connected player left. Must be called with t.mu held.
+func (t *Table) updateIdle() {
+	for _, p := range t.Players {
+		if p.Connected {
+			t.idleSince = time.Time{}
+			return
+		}
+	}
+	if t.idleSince.IsZero() {
+		t.idleSince = time.Now()
+	}
+}
+
+// startForwarding starts the goroutine that moves a player's input onto the game loop.
+// Messages that are not valid updates are dropped, and every update is marked as
+// coming from the seated player whatever the client put in PlayerID.
+func (t *Table) startForwarding(p *Player) {
+	p.stop = make(chan struct{})
+	go func(playerID uint, incoming chan json.RawMessage, stop chan struct{}) {
+		for {
+			select {
+			case raw := <-incoming:
+				var update IncomingUpdate
+				if err := json.Unmarshal(raw, &update); err != nil {
+					continue
+				}
+				update.PlayerID = playerID
+				select {
+				case t.incoming <- update:
+				case <-stop:
+					return
+				case <-t.done:
+					return
+				}
+			case <-stop:
+				return
+			case <-t.done:
+				return
+			}
+		}
+	}(p.ID, p.Incoming, p.stop)
+}
+
+// detach stops a player's forwarding goroutine and closes their Outgoing channel.
+// Incoming is left open: the WebSocket reader may still be sending on it.
+func (t *Table) detach(p *Player) {
+	close(p.stop)
+	close(p.Outgoing)
+}
+
+// shutdown refunds the bets of a round that was dealt but not settled and detaches
+// every player once the game loop has stopped. A suspended table only detaches them.
+func (t *Table) shutdown() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.phase == Dealing && !t.suspended {
+		for _, p := range t.Players {
+			total := p.totalBet()
+			if total == 0 {
+				continue
+			}
+			err := t.DB.Model(&models.Account{}).
+				Where("id = ?", p.ID).
+				Update("balance", gorm.Expr("balance + ?", total)).Error
+			if err != nil {
+				log.Println("Failed to refund", total, "chips to player", p.ID, ":", err)
+			}
+		}
+	}
+	for _, p := range t.Players {
+		t.detach(p)
+	}
+	t.Players = nil
+}
+
+// sleep pauses the game loop, returning false early if the table is closed.
+func (t *Table) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-t.done:
+		return false
+	}
+}
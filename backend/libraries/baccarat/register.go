@@ -0,0 +1,60 @@
+package baccarat
+
+// register.go
+// This file registers baccarat with the game registry, so the lobby can create baccarat
+// tables in either commission mode and the WebSocket handler can connect players to them.
+
+import (
+	"cardgames/backend/libraries/game"
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// GameName is the name baccarat is registered under.
+const GameName = "baccarat"
+
+func init() {
+	game.Register(game.Definition{
+		Name:  GameName,
+		Modes: []string{StandardMode, NoCommissionMode},
+		New:   newFromConfig,
+	})
+}
+
+// newFromConfig creates a baccarat table from the settings chosen in the lobby, or
+// restores one from a snapshot.
+func newFromConfig(db *gorm.DB, cfg game.Config) (game.Game, error) {
+	opts := Options{Mode: cfg.Mode}
+	if cfg.Private {
+		opts.HostID = cfg.HostID
+		opts.Password = cfg.Password
+	}
+	if len(cfg.Rules) > 0 && string(cfg.Rules) != "null" {
+		rules := DefaultRules()
+		if err := json.Unmarshal(cfg.Rules, &rules); err != nil {
+			return nil, fmt.Errorf("%w: %v", game.ErrInvalidConfig, err)
+		}
+		if err := rules.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %v", game.ErrInvalidConfig, err)
+		}
+		opts.Rules = &rules
+	}
+	opts.OnSnapshot = cfg.OnSnapshot
+
+	if len(cfg.Snapshot) == 0 {
+		return NewTable(db, opts), nil
+	}
+	var snap snapshot
+	if err := json.Unmarshal(cfg.Snapshot, &snap); err != nil {
+		return nil, fmt.Errorf("%w: %v", game.ErrInvalidConfig, err)
+	}
+	if err := snap.Rules.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", game.ErrInvalidConfig, err)
+	}
+	t := newTable(db, opts)
+	t.restore(snap)
+	go t.gameLoop()
+	return t, nil
+}
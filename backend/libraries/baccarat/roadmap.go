@@ -0,0 +1,89 @@
+package baccarat
+
+// roadmap.go
+// This file contains the scoreboard players use to follow a shoe. The bead plate lists
+// every round in order, and the big road groups consecutive Player or Banker wins into
+// columns, marking ties on the entry before them. Both start over with a new shoe.
+
+// Bead is one round on the bead plate.
+type Bead struct {
+	Winner      Winner
+	PlayerTotal int
+	BankerTotal int
+	PlayerPair  bool
+	BankerPair  bool
+}
+
+// RoadCell is one entry on the big road.
+type RoadCell struct {
+	Winner     Winner // PlayerWin or BankerWin, Tie only if the shoe opened with ties
+	Ties       int    // ties that followed this entry
+	PlayerPair bool
+	BankerPair bool
+}
+
+// Tally counts the outcomes in the current shoe.
+type Tally struct {
+	Player     int
+	Banker     int
+	Tie        int
+	PlayerPair int
+	BankerPair int
+}
+
+// Roadmap is the scoreboard of the current shoe.
+type Roadmap struct {
+	Beads   []Bead
+	BigRoad [][]RoadCell // columns, left to right
+	Tally   Tally
+}
+
+// add records the outcome of a round.
+func (r *Roadmap) add(o Outcome) {
+	r.Beads = append(r.Beads, Bead{
+		Winner:      o.Winner,
+		PlayerTotal: o.PlayerTotal,
+		BankerTotal: o.BankerTotal,
+		PlayerPair:  o.PlayerPair,
+		BankerPair:  o.BankerPair,
+	})
+
+	switch o.Winner {
+	case PlayerWin:
+		r.Tally.Player++
+	case BankerWin:
+		r.Tally.Banker++
+	case Tie:
+		r.Tally.Tie++
+	}
+	if o.PlayerPair {
+		r.Tally.PlayerPair++
+	}
+	if o.BankerPair {
+		r.Tally.BankerPair++
+	}
+
+	cell := RoadCell{Winner: o.Winner, PlayerPair: o.PlayerPair, BankerPair: o.BankerPair}
+	n := len(r.BigRoad)
+	if n == 0 {
+		r.BigRoad = [][]RoadCell{{cell}}
+		if o.Winner == Tie {
+			r.BigRoad[0][0].Ties = 1
+		}
+		return
+	}
+
+	col := r.BigRoad[n-1]
+	last := &col[len(col)-1]
+	switch {
+	case o.Winner == Tie:
+		last.Ties++
+	case last.Winner == Tie:
+		// Ties at the start of the shoe take the colour of the first decided round
+		last.Winner = o.Winner
+	case last.Winner == o.Winner:
+		r.BigRoad[n-1] = append(col, cell)
+	default:
+		r.BigRoad = append(r.BigRoad, []RoadCell{cell})
+	}
+}
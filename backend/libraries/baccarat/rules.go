@@ -0,0 +1,164 @@
+package baccarat
+
+// rules.go
+// This file contains the punto banco drawing rules, the bet payouts and the table
+// settings. The drawing rules are fixed: neither players nor the dealer make any
+// decisions once the bets are in.
+
+import (
+	carddeck "cardgames/backend/libraries/cardDeck"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidRules is returned when a Rules value fails validation.
+var ErrInvalidRules = errors.New("invalid table rules")
+
+// Rules are the settings a baccarat table plays by.
+type Rules struct {
+	Decks          int // decks in the shoe
+	MinBet         int // smallest bet accepted on a single spot
+	MaxBet         int // largest bet accepted on a single spot, 0 for no limit
+	BettingSeconds int // length of the betting phase
+}
+
+// DefaultRules returns the rules used by tables that do not set their own.
+func DefaultRules() Rules {
+	return Rules{
+		Decks:          8,
+		MinBet:         1,
+		MaxBet:         0,
+		BettingSeconds: BettingTimeLimit,
+	}
+}
+
+// Validate checks that the rules describe a playable table.
+func (r Rules) Validate() error {
+	if r.Decks < 1 || r.Decks > 8 {
+		return ErrInvalidRules
+	}
+	if r.MinBet < 1 || (r.MaxBet != 0 && r.MaxBet < r.MinBet) {
+		return ErrInvalidRules
+	}
+	if r.BettingSeconds < 5 || r.BettingSeconds > 60 {
+		return ErrInvalidRules
+	}
+	return nil
+}
+
+// summary describes the rules of a table played in mode in a few words, e.g. "8 decks, 5% commission".
+func (r Rules) summary(mode string) string {
+	if mode == NoCommissionMode {
+		return fmt.Sprintf("%d decks, no commission", r.Decks)
+	}
+	return fmt.Sprintf("%d decks, 5%% commission", r.Decks)
+}
+
+// betAllowed reports whether a total bet on one spot is within the table limits.
+func (r Rules) betAllowed(total int) bool {
+	return total >= r.MinBet && (r.MaxBet == 0 || total <= r.MaxBet)
+}
+
+// cardPoints returns the baccarat value of a card: aces count 1, tens and faces 0.
+func cardPoints(c carddeck.Card) int {
+	if c.Value == carddeck.Ten || c.Value.IsFace() {
+		return 0
+	}
+	return c.Value.Number()
+}
+
+// handTotal returns the total of a baccarat hand, the last digit of the sum of its cards.
+func handTotal(hand []carddeck.Card) int {
+	total := 0
+	for _, c := range hand {
+		total += cardPoints(c)
+	}
+	return total % 10
+}
+
+// isPair reports whether the first two cards of a hand have the same rank.
+func isPair(hand []carddeck.Card) bool {
+	return len(hand) >= 2 && hand[0].Value == hand[1].Value
+}
+
+// playerDraws reports whether the Player hand takes a third card.
+func playerDraws(player, banker []carddeck.Card) bool {
+	if isNatural(player) || isNatural(banker) {
+		return false
+	}
+	return handTotal(player) <= 5
+}
+
+// bankerDraws reports whether the Banker hand takes a third card. playerThird is the
+// Player hand's third card, or nil if the Player stood.
+func bankerDraws(player, banker []carddeck.Card, playerThird *carddeck.Card) bool {
+	if isNatural(player) || isNatural(banker) {
+		return false
+	}
+	total := handTotal(banker)
+	if playerThird == nil {
+		// Player stood, the Banker follows the same rule as the Player
+		return total <= 5
+	}
+
+	third := cardPoints(*playerThird)
+	switch total {
+	case 0, 1, 2:
+		return true
+	case 3:
+		return third != 8
+	case 4:
+		return third >= 2 && third <= 7
+	case 5:
+		return third >= 4 && third <= 7
+	case 6:
+		return third == 6 || third == 7
+	default:
+		return false
+	}
+}
+
+// isNatural reports whether a two card hand totals 8 or 9.
+func isNatural(hand []carddeck.Card) bool {
+	return len(hand) == 2 && handTotal(hand) >= 8
+}
+
+// payout returns the chips a bet on spot returns for a round with the given outcome,
+// including the stake, or 0 if the bet lost.
+func payout(spot BetSpot, bet int, outcome Outcome, mode string) int {
+	switch spot {
+	case PlayerSpot:
+		switch outcome.Winner {
+		case PlayerWin:
+			return bet * 2
+		case Tie:
+			return bet // Player and Banker bets push on a tie
+		}
+	case BankerSpot:
+		switch outcome.Winner {
+		case BankerWin:
+			if mode == NoCommissionMode {
+				if outcome.BankerTotal == 6 {
+					return bet + bet/2 // Banker winning with 6 pays 1:2
+				}
+				return bet * 2
+			}
+			return bet + bet*95/100 // 5% commission
+		case Tie:
+			return bet
+		}
+	case TieSpot:
+		if outcome.Winner == Tie {
+			return bet * 9 // 8:1
+		}
+	case PlayerPairSpot:
+		if outcome.PlayerPair {
+			return bet * 12 // 11:1
+		}
+	case BankerPairSpot:
+		if outcome.BankerPair {
+			return bet * 12
+		}
+	}
+	return 0
+}
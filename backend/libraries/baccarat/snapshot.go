@@ -0,0 +1,115 @@
+package baccarat
+
+// snapshot.go
+// This file contains saving a baccarat table so it survives a server restart, and
+// restoring it. The table is saved when betting closes, when the round is settled and
+// when the next round opens. The snapshot keeps the rules, the host controls, the
+// players, the shoe and its roadmap.
+
+// A restored table opens with betting. Bets taken from the players in a round cut short
+// by the restart are paid back, and every player is kept at the table for
+// game.SeatHoldTime so they can reconnect and carry on.
+
+import (
+	carddeck "cardgames/backend/libraries/cardDeck"
+	"cardgames/backend/libraries/game"
+	"cardgames/backend/models"
+	"encoding/json"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// snapshot is the saved state of a table.
+type snapshot struct {
+	Rules        Rules
+	HostID       uint
+	PasswordHash []byte `json:",omitempty"`
+	Players      []playerSnapshot
+	Shoe         carddeck.Deck
+	Roadmap      Roadmap
+	RoundsPlayed int
+}
+
+// playerSnapshot is the saved state of a player at the table.
+type playerSnapshot struct {
+	ID     uint
+	Staked int `json:",omitempty"` // bets taken from the balance for the round being dealt
+}
+
+// saveSnapshot hands the table state to the OnSnapshot hook, if there is one.
+// Must be called with t.mu held.
+func (t *Table) saveSnapshot() {
+	if t.onSnapshot == nil {
+		return
+	}
+
+	snap := snapshot{
+		Rules:        t.rules,
+		HostID:       t.hostID,
+		PasswordHash: t.passwordHash,
+		Shoe:         t.shoe,
+		Roadmap:      t.roadmap,
+		RoundsPlayed: t.roundsPlayed,
+	}
+	for _, p := range t.Players {
+		player := playerSnapshot{ID: p.ID}
+		if t.phase == Dealing {
+			player.Staked = p.totalBet()
+		}
+		snap.Players = append(snap.Players, player)
+	}
+
+	state, err := json.Marshal(snap)
+	if err != nil {
+		log.Println("Failed to encode baccarat snapshot:", err)
+		return
+	}
+	t.onSnapshot(state)
+}
+
+// restore sets the table up from a snapshot. It is called before the game loop starts.
+// Players whose account is gone are left out.
+func (t *Table) restore(snap snapshot) {
+	t.rules = snap.Rules
+	t.hostID = snap.HostID
+	t.passwordHash = snap.PasswordHash
+	if len(snap.Shoe) > 0 {
+		t.shoe = snap.Shoe
+		t.roadmap = snap.Roadmap
+	}
+	t.roundsPlayed = snap.RoundsPlayed
+
+	heldUntil := time.Now().Add(game.SeatHoldTime)
+	for _, player := range snap.Players {
+		if player.Staked > 0 {
+			// The round was cut short, give the bets back
+			err := t.DB.Model(&models.Account{}).
+				Where("id = ?", player.ID).
+				Update("balance", gorm.Expr("balance + ?", player.Staked)).Error
+			if err != nil {
+				log.Println("Failed to refund", player.Staked, "chips to player", player.ID, ":", err)
+			}
+		}
+
+		var account models.Account
+		if err := t.DB.First(&account, player.ID).Error; err != nil {
+			log.Println("Not restoring player", player.ID, ":", err)
+			continue
+		}
+		t.Players = append(t.Players, &Player{
+			ID:        player.ID,
+			Account:   &account,
+			Bets:      make(map[BetSpot]int),
+			Incoming:  make(chan json.RawMessage),
+			Outgoing:  make(chan any, 10),
+			stop:      make(chan struct{}),
+			heldUntil: heldUntil,
+		})
+	}
+	t.updateIdle()
+
+	// Save right away so the bets paid back are not paid again after another restart
+	t.saveSnapshot()
+}
@@ -0,0 +1,68 @@
+package blackjack
+
+// admin.go
+// This file contains what admins see of a blackjack instance: the whole table state,
+// including the dealer's hole card and the cards left in the shoe.
+
+import (
+	carddeck "cardgames/backend/libraries/cardDeck"
+	"cardgames/backend/libraries/game"
+)
+
+// AdminState is the full state of a blackjack instance for admins.
+type AdminState struct {
+	Mode             TableMode
+	Rules            Rules
+	Players          []AdminPlayer
+	DealerHand       []carddeck.Card // hole card included
+	CurrentTurnIndex int
+	HostID           uint
+	Locked           bool
+	Banned           []uint
+	Unsettled        bool // bets are locked in and the round has not been paid out yet
+}
+
+// AdminPlayer is a seated player as admins see them.
+type AdminPlayer struct {
+	PlayerInfo
+	Connected bool
+}
+
+// Inspect returns the table state for admins.
+func (b *BlackJackInstance) Inspect() game.Inspection {
+	var in game.Inspection
+	b.call(func() { in = b.inspect() })
+	return in
+}
+
+// inspect collects the table state for admins on the game loop.
+func (b *BlackJackInstance) inspect() game.Inspection {
+	state := AdminState{
+		Mode:             b.opts.Mode,
+		Rules:            b.rules,
+		DealerHand:       append([]carddeck.Card(nil), b.DealerHand...),
+		CurrentTurnIndex: b.currentTurnIndex,
+		HostID:           b.hostID,
+		Locked:           b.locked,
+		Unsettled:        b.unsettled,
+	}
+	ids := make([]uint, 0, len(b.Players))
+	for _, p := range b.Players {
+		info := p.ToPlayerInfo()
+		info.Balance = b.wallet.Balance(p)
+		state.Players = append(state.Players, AdminPlayer{PlayerInfo: info, Connected: p.Connected})
+		ids = append(ids, p.ID)
+	}
+	for id := range b.banned {
+		state.Banned = append(state.Banned, id)
+	}
+
+	return game.Inspection{
+		Phase:        string(b.gamePhase),
+		PlayerIDs:    ids,
+		ShoeDepth:    len(b.Deck),
+		RoundsPlayed: b.RoundsPlayed,
+		Paused:       b.paused,
+		State:        state,
+	}
+}
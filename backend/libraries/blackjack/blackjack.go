@@ -12,14 +12,18 @@ package blackjack
 // It also manages player connections, disconnections, and game state transitions.
 // The game instance can handle multiple players and provides real-time updates to all connected players.
 
+// All of the instance state is owned by the GameLoop goroutine. Other goroutines never
+// touch it, they post requests to the loop instead, see mailbox.go.
+
 //Author : Benjamin Stonesreet
 // Date : 2025-11-07
 
 import (
 	carddeck "cardgames/backend/libraries/cardDeck"
+	"cardgames/backend/libraries/game"
 	"cardgames/backend/models"
+	"encoding/json"
 	"log"
-	"strconv"
 	"sync"
 	"time"
 
@@ -33,6 +37,10 @@ const (
 	BettingTimeLimit      = 5 // seconds
 	ActionTimeLimit       = 5 // seconds
 	MaxPlayersPerInstance = 7 // Standard blackjack table size
+
+	dealerRevealDelay = 500 * time.Millisecond // pause before the dealer's hole card is played
+	dealerDrawDelay   = 1 * time.Second        // pause after each card the dealer draws
+	roundOverDelay    = 2 * time.Second        // results stay up this long before the next round
 )
 
 // GamePhase represents the current phase of the game.
@@ -53,209 +61,471 @@ const (
 	DoubleAction Action = "double"
 	SplitAction  Action = "split"
 	LeaveAction  Action = "leave"
+	RefillAction Action = "refill" // practice tables only, tops the play-money stack back up
+
+	// Spanish 21 action
+	SurrenderAction Action = "surrender" // late surrender, or double-down rescue on a doubled hand
+
+	// Trainer table actions
+	QuizAnswerAction Action = "quiz_answer" // answer the open count quiz with Answer
+	OverlayAction    Action = "overlay"     // show or hide the count overlay
+
+	// Host actions for private tables
+	KickAction         Action = "kick"          // remove TargetID from the table
+	BanAction          Action = "ban"           // remove TargetID and keep them from rejoining
+	UnbanAction        Action = "unban"         // allow TargetID to join again
+	LockAction         Action = "lock"          // stop new players from joining
+	UnlockAction       Action = "unlock"        // allow new players to join again
+	SetRulesAction     Action = "set_rules"     // change the table Rules, applied between rounds
+	TransferHostAction Action = "transfer_host" // make TargetID the host
+
+	// Chat actions
+	ChatAction   Action = "chat"   // send Message to the table
+	MuteAction   Action = "mute"   // stop receiving chat from TargetID
+	UnmuteAction Action = "unmute" // receive chat from TargetID again
+)
+
+// UpdateType marks what an OutgoingUpdate carries.
+type UpdateType string
+
+const (
+	StateUpdate UpdateType = "state" // game state
+	ChatUpdate  UpdateType = "chat"  // chat lines in Chat and/or a chat Notice, game fields are not filled in
+)
+
+// TableMode describes what a table is played for.
+type TableMode string
+
+const (
+	StandardMode TableMode = "standard" // real money, bets come from the account balance
+	PracticeMode TableMode = "practice" // refillable play-money chips, nothing is saved
+	TrainerMode  TableMode = "trainer"  // practice chips plus the card counting trainer overlay
 )
 
 // IncomingUpdate is a message from a player to the game instance.
 type PlayerStatus string
 const (
-	PlayerStatusPlaying   PlayerStatus = "playing"
-	PlayerStatusBusted    PlayerStatus = "busted"
-	PlayerStatusStand     PlayerStatus = "stand"
-	PlayerStatusStandby   PlayerStatus = "standby" //user is active in lobby but not participating in current round
-	PlayerStatusWon       PlayerStatus = "won"
-	PlayerStatusLost      PlayerStatus = "lost"
-	PlayerStatusPush      PlayerStatus = "push"
-	PlayerStatusBlackjack PlayerStatus = "blackjack"
+	PlayerStatusPlaying     PlayerStatus = "playing"
+	PlayerStatusBusted      PlayerStatus = "busted"
+	PlayerStatusStand       PlayerStatus = "stand"
+	PlayerStatusStandby     PlayerStatus = "standby" //user is active in lobby but not participating in current round
+	PlayerStatusWon         PlayerStatus = "won"
+	PlayerStatusLost        PlayerStatus = "lost"
+	PlayerStatusPush        PlayerStatus = "push"
+	PlayerStatusBlackjack   PlayerStatus = "blackjack"
+	PlayerStatusSurrendered PlayerStatus = "surrendered"
 )
 
 // IncomingUpdate is a message from a player to the game instance.
 type IncomingUpdate struct {
 	PlayerID uint
 	Action   Action
-	Bet      int // Optional, only used for BetAction
+	Bet      int    // Optional, only used for BetAction
+	Answer   int    // Optional, only used for QuizAnswerAction
+	Message  string // Optional, only used for ChatAction
+	TargetID uint   // Optional, the player targeted by mute, kick, ban and host transfer actions
+	Rules    *Rules // Optional, only used for SetRulesAction
 }
 
 // OutgoingUpdate is a message from the game instance to a player.
 type OutgoingUpdate struct {
+	Type           UpdateType
 	Phase          GamePhase
+	Mode           TableMode
 	YourID         uint
 	YourHand       []carddeck.Card
 	DealerHand     []carddeck.Card
 	Players        []PlayerInfo // Info about all players
 	ActivePlayerID uint
-	GameResult     string // e.g., "Player busts", "Dealer wins"
+	GameResult     string        // e.g., "Player busts", "Dealer wins"
+	Notice         string        `json:",omitempty"` // message for this player only, e.g. why they were removed
+	MoveTo         string        `json:",omitempty"` // game ID the player should reconnect to, set when they are moved to another table
+	Trainer        *TrainerInfo  `json:",omitempty"` // card counting overlay, only sent at trainer tables
+	Chat           []ChatMessage `json:",omitempty"` // chat lines, only set on ChatUpdate messages
+	HostID         uint          // host of a private table, 0 for tables without a host
+	Locked         bool          // host has locked the table to new players
+	Rules          Rules
 }
 
 // PlayerInfo contains public information about a player.
 type PlayerInfo struct {
 	ID       uint
 	Username string
-	Hand     []carddeck.Card
-	Bet      int
-	Status   PlayerStatus
-	Balance  int
+	Hand       []carddeck.Card // the hand being played
+	Bet        int             // chips on the table, all hands together
+	Status     PlayerStatus
+	Balance    int
+	Hands      []Hand          // every hand once the cards are dealt, more than one after a split
+	ActiveHand int             // index in Hands of the hand being played
 }
 
 // Map defining allowed actions for each game phase.
 var allowedActions = map[GamePhase][]Action{
-	Betting:    {BetAction, LeaveAction},
-	PlayerTurn: {HitAction, StandAction, DoubleAction, LeaveAction, SplitAction},
+	Betting:    {BetAction, LeaveAction, RefillAction},
+	PlayerTurn: {HitAction, StandAction, DoubleAction, LeaveAction, SplitAction, SurrenderAction},
 	DealerTurn: {},
 }
 
+// Actions that are accepted in every game phase.
+var anyPhaseActions = []Action{
+	QuizAnswerAction, OverlayAction,
+	ChatAction, MuteAction, UnmuteAction,
+	KickAction, BanAction, UnbanAction, LockAction, UnlockAction, SetRulesAction, TransferHostAction,
+}
+
 //------------------------------------------------------------------
 // Structs
 //------------------------------------------------------------------
 
-//Hand instance, represents a single game. If a user splits they will have multiple hands
-
-// type Hand struct {
-// 	Cards []carddeck.Card
-// 	Status PlayerStatus
-// }
-
 // Player represents a player in the blackjack game.
 type Player struct {
 	ID        uint
 	Account   *models.Account
 	Wager     *models.Wager
-	Hand      []carddeck.Card
+	Hands     []*Hand              // the player's hands this round, see hand.go
+	Active    int                  // index of the hand being played
 	Status    PlayerStatus
-	Bet       int
-	Incoming  chan IncomingUpdate
-	Outgoing  chan OutgoingUpdate
-	Connected bool // indicates if the player is currently connected
+	Bet       int                  // bet placed for the round, each hand starts with it
+	Incoming  chan json.RawMessage // raw IncomingUpdate messages from the player's connection
+	Outgoing  chan any             // OutgoingUpdate messages for the player's connection
+	Connected bool                 // indicates if the player is currently connected
+	stop      chan struct{}        // closed to stop the goroutine forwarding Incoming to the game loop
+	heldUntil time.Time            // seat of a dropped connection or a restored table is kept for the player until then
 }
 
 // ToPlayerInfo returns a PlayerInfo struct with public information.
 func (p *Player) ToPlayerInfo() PlayerInfo {
+	hands := make([]Hand, 0, len(p.Hands))
+	for _, h := range p.Hands {
+		hands = append(hands, *h)
+	}
 	return PlayerInfo{
-		ID:       p.ID,
-		Username: p.Account.Username,
-		Hand:     p.Hand,
-		Bet:      p.Bet,
-		Status:   p.Status,
-		Balance:  p.Account.Balance,
+		ID:         p.ID,
+		Username:   p.Account.Username,
+		Hand:       p.activeCards(),
+		Bet:        p.staked(),
+		Status:     p.Status,
+		Balance:    p.Account.Balance,
+		Hands:      hands,
+		ActiveHand: p.Active,
 	}
 }
 
+// Options customizes a blackjack instance. The zero value gives a regular table
+// that plays with the players' account balances.
+type Options struct {
+	Mode          TableMode                  // defaults to StandardMode
+	Wallet        Wallet                     // where chips come from, defaults to the account balance
+	CanJoin       func(playerID uint) bool   // optional admission check run before a player is seated
+	OnRoundEnd    func(b *BlackJackInstance) // optional hook called by the game loop after every round, must not wait on the table
+	KeepWhenEmpty bool                       // keep the table alive when nobody is seated
+	Rules         *Rules                     // table rules, defaults to DefaultRules
+	HostID        uint                       // creator of a private table, enables host controls
+	Password      string                     // optional password required to join
+	OnSnapshot    func(state json.RawMessage) // optional hook the table state is saved through, see snapshot.go
+}
+
+// BlackJackInstance is a blackjack table. Its fields belong to the game loop, see mailbox.go.
 type BlackJackInstance struct {
 	Players          []*Player
 	Deck             carddeck.Deck
 	DealerHand       []carddeck.Card
+	RoundsPlayed     int
 	gamePhase        GamePhase
 	incoming         chan IncomingUpdate
 	DB               *gorm.DB
 	currentTurnIndex int
-	mu               sync.Mutex
+	paused           bool
+	unsettled        bool // bets are locked in and the round has not been paid out yet
+	dealerDone       bool // dealer has played and bets are paid, the next round starts when the timer fires
+	wallet           Wallet
+	trainer          *trainerState // nil unless this is a trainer table
+	chat             *chatState
+	rules            Rules
+	pendingRules     *Rules // rules the host changed mid-round, applied when the round ends
+	hosted           bool   // table has host controls
+	hostID           uint
+	passwordHash     []byte
+	locked           bool
+	banned           map[uint]bool
+	kicked           map[uint]bool // players kicked during a round, their seats are freed when it ends
+	idleSince        time.Time // when the last connected player left, zero while someone is connected
+	mailbox          *mailbox
+	done             chan struct{} // closed by Close to stop the game loop
+	stopped          chan struct{} // closed by the game loop once it has shut down
+	closeOnce        sync.Once
+	suspended        bool // closed by Suspend, the chips on the table are left to the snapshot
+	opts             Options
 }
 
 func NewBlackJackInstance(db *gorm.DB) *BlackJackInstance {
+	return NewBlackJackInstanceWithOptions(db, Options{})
+}
+
+// NewBlackJackInstanceWithOptions creates a blackjack instance customized by opts
+// and starts its game loop.
+func NewBlackJackInstanceWithOptions(db *gorm.DB, opts Options) *BlackJackInstance {
+	b := newInstance(db, opts)
+	go b.GameLoop()
+	return b
+}
+
+// newInstance creates a blackjack instance customized by opts without starting its game loop.
+func newInstance(db *gorm.DB, opts Options) *BlackJackInstance {
+	if opts.Mode == "" {
+		opts.Mode = StandardMode
+	}
+	if opts.Wallet == nil {
+		if opts.Mode == PracticeMode || opts.Mode == TrainerMode {
+			opts.Wallet = NewPlayMoneyWallet(PracticeStack)
+		} else {
+			opts.Wallet = NewAccountWallet(db)
+		}
+	}
+
 	b := &BlackJackInstance{
 		Players:          make([]*Player, 0),
-		Deck:             carddeck.NewDeck(4), // Example: 4 decks
 		gamePhase:        Betting,
 		incoming:         make(chan IncomingUpdate),
 		DB:               db,
 		currentTurnIndex: 0,
+		wallet:           opts.Wallet,
+		chat:             newChatState(),
+		rules:            DefaultRules(),
+		hosted:           opts.HostID != 0,
+		hostID:           opts.HostID,
+		banned:           make(map[uint]bool),
+		kicked:           make(map[uint]bool),
+		idleSince:        time.Now(),
+		mailbox:          newMailbox(),
+		done:             make(chan struct{}),
+		stopped:          make(chan struct{}),
+		opts:             opts,
 	}
-	b.Deck.Shuffle()
-
-	// Start the game loop
-	go b.GameLoop()
+	if opts.Rules != nil {
+		b.rules = *opts.Rules
+	}
+	if opts.Password != "" {
+		b.setPassword(opts.Password)
+	}
+	if opts.Mode == TrainerMode {
+		b.trainer = newTrainerState()
+	}
+	b.newShoe()
 
 	return b
 }
 
-// AddPlayer adds a player to the blackjack instance or reconnects an existing player
-func (b *BlackJackInstance) AddPlayer(playerID uint) *Player {
-	b.mu.Lock()
-	defer b.mu.Unlock()
+// Join adds a player to the blackjack instance or reconnects an existing player, and
+// returns the seat the player's connection talks to the table through.
+// New players must pass the table's admission checks: bans, the host lock, the
+// table password and any CanJoin hook. Returns the reason as an error if they do not.
+func (b *BlackJackInstance) Join(req JoinRequest) (*game.Seat, error) {
+	// Admission check runs outside the game loop, it may call back into the table's owner
+	if b.opts.CanJoin != nil && !b.opts.CanJoin(req.PlayerID) {
+		return nil, ErrNotAllowed
+	}
+
+	// The account is loaded here so the game loop does not wait on the database
+	var account models.Account
+	accountErr := b.DB.Where("id = ?", req.PlayerID).First(&account).Error
+
+	var seat *game.Seat
+	var err error
+	if !b.call(func() { seat, err = b.join(req, &account, accountErr) }) {
+		return nil, ErrTableClosed
+	}
+	return seat, err
+}
+
+// join seats or reconnects a player on the game loop. account is the player's account
+// as loaded by Join, accountErr the error loading it.
+func (b *BlackJackInstance) join(req JoinRequest, account *models.Account, accountErr error) (*game.Seat, error) {
+	playerID := req.PlayerID
+
+	select {
+	case <-b.done:
+		return nil, ErrTableClosed
+	default:
+	}
+
+	if b.banned[playerID] {
+		return nil, ErrBanned
+	}
+	if b.kicked[playerID] {
+		// Kicked mid-round, the seat is not theirs to reconnect to
+		return nil, ErrNotAllowed
+	}
 
 	existingPlayer := b.findPlayerByID(playerID)
 	if existingPlayer != nil {
 		// Player already exists - reconnect them
-		// Create new channels (old ones will be garbage collected when old goroutines exit)
-		oldIncoming := existingPlayer.Incoming
-		oldOutgoing := existingPlayer.Outgoing
+		// Stop the old forwarder and close the old Outgoing so the old connection's handler exits
+		b.detach(existingPlayer)
 
-		existingPlayer.Incoming = make(chan IncomingUpdate)
-		existingPlayer.Outgoing = make(chan OutgoingUpdate, 10)
+		existingPlayer.Incoming = make(chan json.RawMessage)
+		existingPlayer.Outgoing = make(chan any, 10)
 		existingPlayer.Connected = true
+		existingPlayer.heldUntil = time.Time{}
+		b.updateIdle()
 
-		// Close old channels to signal old goroutines to exit
-		close(oldIncoming)
-		close(oldOutgoing)
-
-		// Reload account balance from database
-		var account models.Account
-		if err := b.DB.Where("id = ?", playerID).First(&account).Error; err == nil {
-			existingPlayer.Account = &account
+		// Pick up the account balance as it is in the database now
+		if accountErr == nil {
+			existingPlayer.Account = account
 		}
 
 		// Start new goroutine to forward incoming updates
-		go func() {
-			for update := range existingPlayer.Incoming {
-				b.incoming <- update
-			}
-		}()
+		b.startForwarding(existingPlayer)
+
+		return seatOf(existingPlayer), nil
+	}
 
-		return existingPlayer
+	if err := b.checkAdmission(req); err != nil {
+		return nil, err
 	}
 
 	if len(b.Players) >= MaxPlayersPerInstance {
 		// Table full
-		return nil
+		return nil, ErrTableFull
 	}
 
-	// Load account from database
-	var account models.Account
-	err := b.DB.Where("id = ?", playerID).First(&account).Error
-	if err != nil {
-		// Handle error - account not found or database error
-		return nil
+	if accountErr != nil {
+		// Account not found or database error
+		return nil, ErrNotAllowed
 	}
 
 	p := &Player{
 		ID:        playerID,
-		Account:   &account,
+		Account:   account,
 		Status:    PlayerStatusStandby,
-		Incoming:  make(chan IncomingUpdate),
-		Outgoing:  make(chan OutgoingUpdate, 10),      // Buffered channel to prevent blocking
+		Incoming:  make(chan json.RawMessage),
+		Outgoing:  make(chan any, 10),                 // Buffered channel to prevent blocking
 		Connected: true,                               //assumes this is called in the beggining of the websocket connection
 		Wager:     &models.Wager{AccountID: playerID}, // Initialize a new wager for the player
 	}
 	b.Players = append(b.Players, p)
+	b.updateIdle()
+	if b.hosted && b.hostID == 0 {
+		b.hostID = playerID
+	}
 
 	// listens to all of the input channels and forwards to the game instance incoming channel
-	go func() {
-		for update := range p.Incoming {
-			b.incoming <- update
+	b.startForwarding(p)
+
+	return seatOf(p), nil
+}
+
+// seatOf returns the seat handed to a player's connection.
+func seatOf(p *Player) *game.Seat {
+	return &game.Seat{PlayerID: p.ID, Actions: p.Incoming, State: p.Outgoing}
+}
+
+// Leave marks a player as disconnected without closing channels
+// This is called when a WebSocket connection closes. The seat, and the host role, are
+// kept for game.SeatHoldTime so the player can reconnect, e.g. after refreshing the page.
+// A seat the player has since reconnected on is left alone
+func (b *BlackJackInstance) Leave(seat *game.Seat) {
+	b.post(func() {
+		if p := b.findPlayerByID(seat.PlayerID); p != nil && p.Outgoing == seat.State {
+			b.disconnectPlayer(seat.PlayerID)
 		}
-	}()
+	})
+}
 
-	return p
+// Kick removes a player from the table and sends them a final update with the
+// given notice. If moveTo is set the client is told to reconnect to that game.
+// During the betting phase the seat is freed immediately, otherwise the player's
+// connection is closed and they are removed when the current round ends, they cannot
+// reconnect until then. The player is removed by the game loop shortly after Kick returns.
+func (b *BlackJackInstance) Kick(playerID uint, notice string, moveTo string) {
+	b.post(func() { b.kick(playerID, notice, moveTo) })
 }
 
-// MarkPlayerDisconnected marks a player as disconnected without closing channels
-// This is called when a WebSocket connection closes
-func (b *BlackJackInstance) MarkPlayerDisconnected(playerID uint) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
+// kick removes a player from the table on the game loop, see Kick.
+func (b *BlackJackInstance) kick(playerID uint, notice string, moveTo string) {
+	p := b.findPlayerByID(playerID)
+	if p == nil {
+		return
+	}
+
+	if p.Connected {
+		select {
+		case p.Outgoing <- OutgoingUpdate{Type: StateUpdate, Phase: b.gamePhase, Mode: b.opts.Mode, YourID: p.ID, Notice: notice, MoveTo: moveTo}:
+		default:
+			log.Println("Failed to send kick notice to player", p.ID)
+		}
+	}
+	p.Connected = false
+	p.heldUntil = time.Time{}
+
+	if b.gamePhase == Betting {
+		for i, seated := range b.Players {
+			if seated == p {
+				b.Players = append(b.Players[:i], b.Players[i+1:]...)
+				break
+			}
+		}
+	} else {
+		b.kicked[p.ID] = true
+	}
+	b.detach(p)
+	b.updateIdle()
+	if p.ID == b.hostID {
+		b.passHost()
+	}
+}
+
+// SetPaused stops (or resumes) dealing new rounds. A paused table stays in the
+// betting phase until it is resumed.
+func (b *BlackJackInstance) SetPaused(paused bool) {
+	b.post(func() { b.paused = paused })
+}
 
+// Paused reports whether the table is currently paused.
+func (b *BlackJackInstance) Paused() bool {
+	var paused bool
+	b.call(func() { paused = b.paused })
+	return paused
+}
+
+// Mode returns what the table is played for.
+func (b *BlackJackInstance) Mode() TableMode {
+	return b.opts.Mode
+}
+
+// KeepWhenEmpty reports whether the table should survive empty-table cleanup.
+func (b *BlackJackInstance) KeepWhenEmpty() bool {
+	return b.opts.KeepWhenEmpty
+}
+
+// PlayerBalance returns the chips a seated player has available at this table.
+func (b *BlackJackInstance) PlayerBalance(p *Player) int {
+	var balance int
+	b.call(func() { balance = b.wallet.Balance(p) })
+	return balance
+}
+
+// disconnectPlayer marks a player whose connection dropped. Their seat is held until
+// game.SeatHoldTime from now, the host role passes on once the seat is freed.
+func (b *BlackJackInstance) disconnectPlayer(playerID uint) {
 	p := b.findPlayerByID(playerID)
 	if p != nil {
 		p.Connected = false
+		p.heldUntil = time.Now().Add(game.SeatHoldTime)
+		b.updateIdle()
 	}
 }
 
+// removePlayer marks a player as gone. Their seat is freed when the round ends.
 func (b *BlackJackInstance) removePlayer(playerID uint) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
 	p := b.findPlayerByID(playerID)
 	if p != nil {
 		p.Connected = false // rest of logic will be handled in resetRound. makes sure user can still win the round if they disconnected mid round
+		p.heldUntil = time.Time{}
+		b.updateIdle()
+		if p.ID == b.hostID {
+			b.passHost()
+		}
 	}
 }
 
@@ -263,38 +533,64 @@ func (b *BlackJackInstance) lockBets() {
 	// lock in player bets
 	for _, p := range b.Players {
 		if p.Bet > 0 {
+			if !b.wallet.Debit(p, p.Bet) {
+				// The chips were spent elsewhere since the bet was placed, sit the round out
+				p.Bet = 0
+				continue
+			}
 
 			p.Wager = &models.Wager{
 				AccountID:   p.ID,
 				WagerAmount: p.Bet,
+				GameType:    GameName,
 			}
-
-			p.Account.Balance -= p.Bet
-			b.DB.Save(p.Account)
+			b.unsettled = true
 		}
 	}
 }
 func (b *BlackJackInstance) checkForBlackjack() {
 	// Check for player blackjacks
 	for _, p := range b.Players {
-		if p.Bet > 0 && len(p.Hand) == 2 && b.calculateHandValue(p.Hand) == 21 {
-			p.Status = PlayerStatusBlackjack
+		if len(p.Hands) == 1 && b.isNatural(p.Hands[0]) {
+			p.Hands[0].Status = PlayerStatusBlackjack
+			p.syncStatus()
 		}
 	}
 }
 
-// GameLoop is the main loop for the game instance.
+// GameLoop is the main loop for the game instance. It owns all of the table state:
+// besides the phase timer and player actions it runs the requests posted to the
+// mailbox. It never sleeps, pauses between steps are timers, so requests are always
+// answered promptly.
 func (b *BlackJackInstance) GameLoop() {
-	timer := time.NewTimer(time.Duration(BettingTimeLimit) * time.Second)
+	timer := time.NewTimer(time.Duration(b.rules.BettingSeconds) * time.Second)
+	defer func() {
+		timer.Stop()
+		b.shutdown()
+		close(b.stopped)
+	}()
+
 	for {
 		select {
+		case <-b.done:
+			return
+
+		case <-b.mailbox.ready:
+			b.runRequests()
+
 		case <-timer.C:
 			// Advance game phase on timer and reset timer accordingly
 			switch b.gamePhase {
 			case Betting: // betting phase ending
+				if b.paused {
+					// Table is on hold, keep taking bets until it is resumed
+					timer = time.NewTimer(time.Duration(b.rules.BettingSeconds) * time.Second)
+					continue
+				}
 				b.gamePhase = PlayerTurn
 
 				b.lockBets()
+				b.saveSnapshot()
 
 				// Deal initial cards
 				b.dealInitialCards()
@@ -305,18 +601,18 @@ func (b *BlackJackInstance) GameLoop() {
 					// Dealer has blackjack - skip player turns and go directly to dealer
 					b.gamePhase = DealerTurn
 					b.broadcastUpdate()
-					timer = time.NewTimer(1 * time.Millisecond) // Fire immediately
+					timer = time.NewTimer(dealerRevealDelay)
 				} else {
 					// Start with first player who needs to act (skip blackjacks)
 					b.currentTurnIndex = -1 // Start at -1 so moveToNextPlayer finds the first valid player
 					if b.moveToNextPlayer() {
 						b.broadcastUpdate()
-						timer = time.NewTimer(time.Duration(ActionTimeLimit) * time.Second)
+						timer = time.NewTimer(time.Duration(b.rules.ActionSeconds) * time.Second)
 					} else {
 						// All players have blackjack, go to dealer turn
 						b.gamePhase = DealerTurn
 						b.broadcastUpdate()
-						timer = time.NewTimer(1 * time.Millisecond)
+						timer = time.NewTimer(dealerRevealDelay)
 					}
 				}
 
@@ -324,34 +620,54 @@ func (b *BlackJackInstance) GameLoop() {
 				// Time's up for current player - automatically stand or handle if they left
 				if b.currentTurnIndex < len(b.Players) {
 					p := b.Players[b.currentTurnIndex]
-					if p.Status == PlayerStatusPlaying {
-						p.Status = PlayerStatusStand
+					if h := p.activeHand(); h != nil && h.Status == PlayerStatusPlaying {
+						h.Status = PlayerStatusStand
+						p.syncStatus()
 					}
 				}
 				// Move to next player or dealer turn
 				if b.moveToNextPlayer() {
 					b.broadcastUpdate()
-					timer = time.NewTimer(time.Duration(ActionTimeLimit) * time.Second)
+					timer = time.NewTimer(time.Duration(b.rules.ActionSeconds) * time.Second)
 				} else {
 					b.gamePhase = DealerTurn
 					b.broadcastUpdate()
-					timer = time.NewTimer(1 * time.Millisecond)
+					timer = time.NewTimer(dealerRevealDelay)
 				}
 			case DealerTurn:
-				b.playDealerHand()
-				b.broadcastUpdate()
-				b.settleAllBets()
-				b.broadcastUpdate()
-
-				time.Sleep(2 * time.Second) // Pause before next round
+				if !b.dealerDone {
+					if b.dealerShouldHit() {
+						b.DealerHand = append(b.DealerHand, b.Deck.Draw())
+						b.broadcastUpdate()
+						timer = time.NewTimer(dealerDrawDelay)
+						continue
+					}
+					b.broadcastUpdate()
+					b.settleAllBets()
+					// Save before the pause, a restart now must not pay the stakes back on top
+					b.saveSnapshot()
+					b.broadcastUpdate()
+					b.dealerDone = true
+					timer = time.NewTimer(roundOverDelay) // Pause before next round
+					continue
+				}
 
+				b.dealerDone = false
 				b.resetRound()
 				b.gamePhase = Betting
+				b.RoundsPlayed++
+				if b.trainer != nil && b.RoundsPlayed%quizEvery == 0 {
+					b.askQuiz()
+				}
+				if b.opts.OnRoundEnd != nil {
+					b.opts.OnRoundEnd(b)
+				}
+				b.saveSnapshot()
 				b.broadcastUpdate()
-				timer = time.NewTimer(time.Duration(BettingTimeLimit) * time.Second)
+				timer = time.NewTimer(time.Duration(b.rules.BettingSeconds) * time.Second)
 			default:
 				b.gamePhase = Betting
-				timer = time.NewTimer(time.Duration(BettingTimeLimit) * time.Second)
+				timer = time.NewTimer(time.Duration(b.rules.BettingSeconds) * time.Second)
 			}
 
 		case update := <-b.incoming:
@@ -359,7 +675,7 @@ func (b *BlackJackInstance) GameLoop() {
 			if needsTimerReset && b.gamePhase == PlayerTurn {
 				// Reset timer when moving to next player
 				timer.Stop()
-				timer = time.NewTimer(time.Duration(ActionTimeLimit) * time.Second)
+				timer = time.NewTimer(time.Duration(b.rules.ActionSeconds) * time.Second)
 			}
 		}
 	}
@@ -371,6 +687,11 @@ func (b *BlackJackInstance) isActionAllowed(action Action) bool {
 			return true
 		}
 	}
+	for _, allowedAction := range anyPhaseActions {
+		if action == allowedAction {
+			return true
+		}
+	}
 	return false
 }
 
@@ -392,7 +713,12 @@ func (b *BlackJackInstance) processUpdate(update IncomingUpdate) bool {
 				return needsTimerReset
 			}
 
-			if p.Account.Balance < update.Bet {
+			if !b.betAllowed(update.Bet) {
+				// Outside the table limits
+				return needsTimerReset
+			}
+
+			if b.wallet.Balance(p) < update.Bet {
 				// Handle insufficient balance
 				return needsTimerReset
 			}
@@ -401,78 +727,63 @@ func (b *BlackJackInstance) processUpdate(update IncomingUpdate) bool {
 			b.broadcastUpdate()
 		}
 	case HitAction:
-		p := b.findPlayerByID(update.PlayerID)
-		// Only allow action if it's the active player's turn and they are in playing status
-		if p != nil && b.gamePhase == PlayerTurn && b.Players[b.currentTurnIndex].ID == update.PlayerID && p.Status == PlayerStatusPlaying {
-
-			// Deal a card to the player
-			card := b.Deck.Draw()
-			p.Hand = append(p.Hand, card)
-			// Check for bust
-			if b.calculateHandValue(p.Hand) > 21 {
-				p.Status = PlayerStatusBusted
-				// Move to next player after bust
-				if !b.moveToNextPlayer() {
-					b.gamePhase = DealerTurn
-				}
-			}
+		// Only allow action if it's the active player's turn and their hand is in play
+		if p, h := b.turnHand(update.PlayerID); h != nil && b.hit(h) {
 			// Reset timer on any hit action
 			needsTimerReset = true
-			b.broadcastUpdate()
+			b.afterHandAction(p)
 		}
 	case StandAction:
-		p := b.findPlayerByID(update.PlayerID)
-		// Only allow action if it's the active player's turn and they are in playing status
-		if p != nil && b.gamePhase == PlayerTurn && b.Players[b.currentTurnIndex].ID == update.PlayerID && p.Status == PlayerStatusPlaying {
-			p.Status = PlayerStatusStand
-			// Move to next player after stand
-			if !b.moveToNextPlayer() {
-				b.gamePhase = DealerTurn
-			}
+		if p, h := b.turnHand(update.PlayerID); h != nil {
+			h.Status = PlayerStatusStand
 			needsTimerReset = true
-			b.broadcastUpdate()
+			b.afterHandAction(p)
 		}
 	case LeaveAction:
 		b.removePlayer(update.PlayerID)
 		b.broadcastUpdate()
+	case RefillAction:
+		p := b.findPlayerByID(update.PlayerID)
+		refiller, ok := b.wallet.(Refiller)
+		if p != nil && ok && refiller.Refill(p) {
+			b.broadcastUpdate()
+		}
+	case QuizAnswerAction:
+		if b.trainer != nil && b.findPlayerByID(update.PlayerID) != nil && b.answerQuiz(update.PlayerID, update.Answer) {
+			b.broadcastUpdate()
+		}
+	case ChatAction:
+		if p := b.findPlayerByID(update.PlayerID); p != nil {
+			b.handleChat(p, update.Message)
+		}
+	case MuteAction, UnmuteAction:
+		if p := b.findPlayerByID(update.PlayerID); p != nil {
+			b.setMuted(p, update.TargetID, update.Action == MuteAction)
+		}
+	case KickAction, BanAction, UnbanAction, LockAction, UnlockAction, SetRulesAction, TransferHostAction:
+		b.handleHostAction(update)
+	case OverlayAction:
+		if b.trainer != nil && b.findPlayerByID(update.PlayerID) != nil {
+			seat := b.trainer.seat(update.PlayerID)
+			seat.hideOverlay = !seat.hideOverlay
+			b.broadcastUpdate()
+		}
 	case SplitAction:
-		// TODO: Implement split logic
+		if p, h := b.turnHand(update.PlayerID); h != nil && b.split(p, h) {
+			needsTimerReset = true
+			b.afterHandAction(p)
+			b.saveSnapshot()
+		}
 	case DoubleAction:
-		p := b.findPlayerByID(update.PlayerID)
-		// Only allow double if it's the active player's turn, they have exactly 2 cards, and have enough balance
-		if p != nil && b.gamePhase == PlayerTurn && b.Players[b.currentTurnIndex].ID == update.PlayerID && p.Status == PlayerStatusPlaying {
-			// Check if player has exactly 2 cards (can only double on first action)
-			if len(p.Hand) != 2 {
-				return needsTimerReset
-			}
-			// Check if player has enough balance to double
-			if p.Account.Balance < p.Bet {
-				return needsTimerReset
-			}
-
-			// Double the bet and deduct from balance
-			p.Account.Balance -= p.Bet
-			b.DB.Save(p.Account)
-			p.Bet *= 2
-			p.Wager.WagerAmount = p.Bet
-
-			// Deal exactly one card
-			card := b.Deck.Draw()
-			p.Hand = append(p.Hand, card)
-
-			// Check for bust
-			if b.calculateHandValue(p.Hand) > 21 {
-				p.Status = PlayerStatusBusted
-			} else {
-				p.Status = PlayerStatusStand // After double, player must stand
-			}
-
-			// Move to next player
-			if !b.moveToNextPlayer() {
-				b.gamePhase = DealerTurn
-			}
+		if p, h := b.turnHand(update.PlayerID); h != nil && b.double(p, h) {
 			needsTimerReset = true
-			b.broadcastUpdate()
+			b.afterHandAction(p)
+			b.saveSnapshot()
+		}
+	case SurrenderAction:
+		if p, h := b.turnHand(update.PlayerID); h != nil && b.surrender(p, h) {
+			needsTimerReset = true
+			b.afterHandAction(p)
 		}
 	}
 
@@ -491,7 +802,9 @@ func (b *BlackJackInstance) findPlayerByID(playerID uint) *Player {
 func (b *BlackJackInstance) broadcastUpdate() {
 	playersInfo := make([]PlayerInfo, 0, len(b.Players))
 	for _, p := range b.Players {
-		playersInfo = append(playersInfo, p.ToPlayerInfo())
+		info := p.ToPlayerInfo()
+		info.Balance = b.wallet.Balance(p)
+		playersInfo = append(playersInfo, info)
 	}
 
 	activePlayerID := uint(0)
@@ -507,7 +820,7 @@ func (b *BlackJackInstance) broadcastUpdate() {
 		if len(b.DealerHand) > 0 {
 			broadcastDealerHand = make([]carddeck.Card, len(b.DealerHand))
 			broadcastDealerHand[0] = b.DealerHand[0]
-			broadcastDealerHand[1] = carddeck.Card{Suit: "0", Value: "0"} // face down card
+			broadcastDealerHand[1] = carddeck.Hidden // face down card
 		}
 	}
 
@@ -518,12 +831,20 @@ func (b *BlackJackInstance) broadcastUpdate() {
 		}
 
 		update := OutgoingUpdate{
+			Type:           StateUpdate,
 			Phase:          b.gamePhase,
+			Mode:           b.opts.Mode,
 			YourID:         p.ID,
-			YourHand:       p.Hand,
+			YourHand:       p.activeCards(),
 			DealerHand:     broadcastDealerHand,
 			Players:        playersInfo,
 			ActivePlayerID: activePlayerID,
+			HostID:         b.hostID,
+			Locked:         b.locked,
+			Rules:          b.rules,
+		}
+		if b.trainer != nil {
+			update.Trainer = b.trainerOverlay(p)
 		}
 
 		// Non-blocking send - if channel is full, skip this player
@@ -536,19 +857,36 @@ func (b *BlackJackInstance) broadcastUpdate() {
 	}
 }
 
-func (b *BlackJackInstance) FirstBroadcastUpdate() {
-	b.broadcastUpdate()
+// Greet sends a newly connected player the table state and the recent chat.
+func (b *BlackJackInstance) Greet(playerID uint) {
+	b.post(func() {
+		b.broadcastUpdate()
+		b.sendChatHistory(playerID)
+	})
 }
 
-// moveToNextPlayer advances to the next player who needs to act.
-// Returns true if there's another player, false if all players are done.
+// moveToNextPlayer advances to the next hand that needs to act: the current player's
+// remaining split hands first, then the hands of the players after them.
+// Returns true if there's another hand, false if all players are done.
 func (b *BlackJackInstance) moveToNextPlayer() bool {
+	if b.currentTurnIndex >= 0 && b.currentTurnIndex < len(b.Players) {
+		p := b.Players[b.currentTurnIndex]
+		for p.Active+1 < len(p.Hands) {
+			p.Active++
+			if p.Hands[p.Active].Status == PlayerStatusPlaying {
+				return true
+			}
+		}
+	}
 	b.currentTurnIndex++
 	// Skip players who are already busted or standing or have blackjack
 	for b.currentTurnIndex < len(b.Players) {
 		p := b.Players[b.currentTurnIndex]
-		if p.Status == PlayerStatusPlaying {
-			return true
+		for i, h := range p.Hands {
+			if h.Status == PlayerStatusPlaying {
+				p.Active = i
+				return true
+			}
 		}
 		b.currentTurnIndex++
 	}
@@ -560,8 +898,9 @@ func (b *BlackJackInstance) dealInitialCards() {
 	// Deal 2 cards to each player who placed a bet
 	for _, p := range b.Players {
 		if p.Bet > 0 {
-			p.Hand = append(p.Hand, b.Deck.Draw())
-			p.Hand = append(p.Hand, b.Deck.Draw())
+			cards := []carddeck.Card{b.Deck.Draw(), b.Deck.Draw()}
+			p.Hands = []*Hand{{Cards: cards, Bet: p.Bet, Status: PlayerStatusPlaying}}
+			p.Active = 0
 			p.Status = PlayerStatusPlaying
 		}
 	}
@@ -570,115 +909,54 @@ func (b *BlackJackInstance) dealInitialCards() {
 	b.DealerHand = append(b.DealerHand, b.Deck.Draw())
 }
 
-// playDealerHand plays out the dealer's hand according to standard rules.
-func (b *BlackJackInstance) playDealerHand() {
-	time.Sleep(500 * time.Millisecond)
-
-	for b.calculateHandValue(b.DealerHand) < 17 {
-		card := b.Deck.Draw()
-		b.DealerHand = append(b.DealerHand, card)
-		b.broadcastUpdate()
-		time.Sleep(1 * time.Second)
+// dealerShouldHit reports whether the dealer draws another card: below 17, or on a
+// soft 17 when the table rules say the dealer hits soft 17.
+func (b *BlackJackInstance) dealerShouldHit() bool {
+	value := b.calculateHandValue(b.DealerHand)
+	if value < 17 {
+		return true
 	}
+	return value == 17 && b.rules.DealerHitsSoft17 && b.isSoftHand(b.DealerHand)
 }
 
 // settleAllBets determines winners and updates account balances.
+// Each hand is settled by the table's variant, see settleHand. The player is paid
+// once for all their hands and their wager records the total.
 func (b *BlackJackInstance) settleAllBets() {
-	dealerValue := b.calculateHandValue(b.DealerHand)
-	dealerBusted := dealerValue > 21
-
 	for _, p := range b.Players {
-		if p.Bet == 0 {
+		if len(p.Hands) == 0 {
 			continue
 		}
 
-		playerValue := b.calculateHandValue(p.Hand)
-
-		// Player busted - already lost bet
-		if p.Status == PlayerStatusBusted {
-			p.Status = PlayerStatusLost
-			p.Wager.WagerWon = false
-			p.Wager.AmountWon = 0
-			b.DB.Save(p.Wager)
-			continue
-		}
-
-		// Check for player blackjack
-		isPlayerBlackjack := len(p.Hand) == 2 && playerValue == 21
-		isDealerBlackjack := len(b.DealerHand) == 2 && dealerValue == 21
-
-		// Both have blackjack - push
-		if isPlayerBlackjack && isDealerBlackjack {
-			// player account update
-			p.Status = PlayerStatusPush
-			p.Account.Balance += p.Bet
-			b.DB.Save(p.Account)
-
-			// wager update
-			p.Wager.WagerWon = true
-			p.Wager.AmountWon = p.Bet
-			b.DB.Save(p.Wager)
-
-			continue
+		staked := p.staked()
+		returned := 0
+		for _, h := range p.Hands {
+			status, amount := b.settleHand(h)
+			h.Status = status
+			returned += amount
 		}
 
-		// Player blackjack - pays 3:2
-		if isPlayerBlackjack {
-			p.Status = PlayerStatusBlackjack
-			p.Account.Balance += p.Bet + (p.Bet * 3 / 2)
-			b.DB.Save(p.Account)
-
-			// wager update
-			p.Wager.WagerWon = true
-			p.Wager.AmountWon = p.Bet + (p.Bet * 3 / 2)
-			b.DB.Save(p.Wager)
-
-			continue
-		}
-
-		// Dealer busted - player wins
-		if dealerBusted {
-			// player account update
-			p.Status = PlayerStatusWon
-			p.Account.Balance += p.Bet * 2
-			b.DB.Save(p.Account)
-
-			// wager update
-			p.Wager.WagerWon = true
-			p.Wager.AmountWon = p.Bet * 2
-			b.DB.Save(p.Wager)
-
-			continue
-		}
-
-		// Compare values
-		if playerValue > dealerValue {
-			// Player wins
+		if len(p.Hands) == 1 {
+			p.Status = p.Hands[0].Status
+		} else if returned > staked {
 			p.Status = PlayerStatusWon
-			p.Account.Balance += p.Bet * 2
-
-			// wager update
-			p.Wager.WagerWon = true
-			p.Wager.AmountWon = p.Bet * 2
-		} else if playerValue == dealerValue {
-			// Push - return bet
+		} else if returned == staked {
 			p.Status = PlayerStatusPush
-			p.Account.Balance += p.Bet
-
-			// wager update
-			p.Wager.WagerWon = true
-			p.Wager.AmountWon = p.Bet
 		} else {
-			// Player loses
 			p.Status = PlayerStatusLost
-			p.Wager.WagerWon = false
-			p.Wager.AmountWon = 0
 		}
-		// Player loses - bet already deducted
 
-		b.DB.Save(p.Account)
-		b.DB.Save(p.Wager)
+		if returned > 0 {
+			b.wallet.Credit(p, returned)
+		}
+
+		// wager update, a push counts as won like before
+		p.Wager.WagerAmount = staked
+		p.Wager.WagerWon = returned >= staked
+		p.Wager.AmountWon = returned
+		b.wallet.RecordWager(p)
 	}
+	b.unsettled = false
 }
 
 // resetRound clears hands and bets for the next round.
@@ -687,29 +965,48 @@ func (b *BlackJackInstance) resetRound() {
 
 	// Remove players who left and reset remaining players
 	activePlayers := make([]*Player, 0)
+	now := time.Now()
 	for _, p := range b.Players {
-		if !p.Connected {
-			// Close channels and remove player
-			close(p.Incoming)
-			close(p.Outgoing)
+		if !p.Connected && !now.Before(p.heldUntil) {
+			// Stop forwarding and remove player
+			b.detach(p)
 			// Player will be garbage collected automatically
 			continue
 		}
 
 		// Reset player for next round
-		p.Hand = []carddeck.Card{}
+		p.Hands = nil
+		p.Active = 0
 		p.Bet = 0
 		// Keep players in joined status so they can choose to bet or spectate
 		p.Status = PlayerStatusStandby
 		activePlayers = append(activePlayers, p)
 	}
 	b.Players = activePlayers
+	clear(b.kicked) // every kicked player was disconnected without a hold, and is gone now
 	b.currentTurnIndex = 0
+	if b.hosted && b.findPlayerByID(b.hostID) == nil {
+		b.passHost()
+	}
+
+	// Apply rule changes the host made during the round
+	if b.pendingRules != nil {
+		b.applyRules(*b.pendingRules)
+		b.pendingRules = nil
+	}
 
 	// Check if deck needs reshuffling (less than 25% remaining)
-	if len(b.Deck) < 52 {
-		b.Deck = carddeck.NewDeck(4)
-		b.Deck.Shuffle()
+	if len(b.Deck) < b.rules.reshuffleAt() {
+		b.newShoe()
+	}
+}
+
+// newShoe replaces the deck with a freshly shuffled shoe.
+func (b *BlackJackInstance) newShoe() {
+	b.Deck = b.rules.Variant.deck().Build(b.rules.Decks)
+	b.Deck.Shuffle()
+	if b.trainer != nil {
+		b.trainer.shoeTotal = b.Deck.HiLoTotal()
 	}
 }
 
@@ -718,16 +1015,13 @@ func (b *BlackJackInstance) calculateHandValue(hand []carddeck.Card) int {
 	numAces := 0
 	for _, card := range hand {
 		switch card.Value {
-		case "A":
+		case carddeck.Ace:
 			numAces++
 			value += 11
-		case "K", "Q", "J":
+		case carddeck.King, carddeck.Queen, carddeck.Jack:
 			value += 10
 		default:
-			cardValue, err := strconv.Atoi(card.Value)
-			if err == nil {
-				value += cardValue
-			}
+			value += card.Value.Number()
 		}
 	}
 
@@ -739,3 +1033,28 @@ func (b *BlackJackInstance) calculateHandValue(hand []carddeck.Card) int {
 
 	return value
 }
+
+// isSoftHand reports whether the hand counts an ace as 11.
+func (b *BlackJackInstance) isSoftHand(hand []carddeck.Card) bool {
+	hard := 0
+	hasAce := false
+	for _, card := range hand {
+		if card.Value == carddeck.Ace {
+			hasAce = true
+		}
+	}
+	if !hasAce {
+		return false
+	}
+	for _, card := range hand {
+		switch card.Value {
+		case carddeck.Ace:
+			hard++
+		case carddeck.King, carddeck.Queen, carddeck.Jack:
+			hard += 10
+		default:
+			hard += card.Value.Number()
+		}
+	}
+	return hard+10 <= 21
+}
@@ -0,0 +1,271 @@
+package blackjack
+
+import (
+	"cardgames/backend/libraries/game"
+	"cardgames/backend/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB returns an in-memory database holding accounts 1 to n.
+func testDB(t testing.TB, n int) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: opens its own database, keep to one
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&models.Account{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= n; i++ {
+		account := models.Account{Email: fmt.Sprintf("player%d@example.com", i), Username: fmt.Sprintf("player%d", i)}
+		if err := db.Create(&account).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+// play sends random actions on the seat until its State channel is closed, draining
+// State all along the way a WebSocket handler does.
+func play(seat *game.Seat, rng *rand.Rand) {
+	closed := make(chan struct{})
+	go func() {
+		for range seat.State {
+		}
+		close(closed)
+	}()
+
+	actions := []IncomingUpdate{
+		{Action: BetAction, Bet: 10},
+		{Action: HitAction},
+		{Action: StandAction},
+		{Action: DoubleAction},
+		{Action: SplitAction},
+		{Action: SurrenderAction},
+		{Action: RefillAction},
+		{Action: ChatAction, Message: "good luck"},
+		{Action: LockAction},
+		{Action: UnlockAction},
+		{Action: KickAction, TargetID: uint(rng.Intn(10) + 1)},
+		{Action: TransferHostAction, TargetID: uint(rng.Intn(10) + 1)},
+	}
+	for i := 0; i < 20; i++ {
+		raw, _ := json.Marshal(actions[rng.Intn(len(actions))])
+		select {
+		case seat.Actions <- raw:
+		case <-closed:
+			return
+		case <-time.After(50 * time.Millisecond):
+			// The table is busy, e.g. the dealer is drawing
+		}
+	}
+	<-closed
+}
+
+func TestConcurrentUse(t *testing.T) {
+	const players = 10 // more than the table seats
+	db := testDB(t, players)
+
+	rules := DefaultRules()
+	rules.BettingSeconds = 1
+	rules.ActionSeconds = 1
+	b := NewBlackJackInstanceWithOptions(db, Options{Mode: PracticeMode, Rules: &rules, HostID: 1})
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for id := uint(1); id <= players; id++ {
+		wg.Add(1)
+		go func(id uint) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(id)))
+			var seats sync.WaitGroup
+			defer seats.Wait()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				seat, err := b.Join(game.JoinRequest{PlayerID: id})
+				if errors.Is(err, ErrTableClosed) {
+					return
+				}
+				if err != nil {
+					time.Sleep(10 * time.Millisecond)
+					continue
+				}
+				connect := func(seat *game.Seat) {
+					seats.Add(1)
+					go func(rng *rand.Rand) {
+						defer seats.Done()
+						play(seat, rng)
+					}(rand.New(rand.NewSource(rng.Int63())))
+				}
+				connect(seat)
+
+				time.Sleep(time.Duration(rng.Intn(100)) * time.Millisecond)
+				switch rng.Intn(4) {
+				case 0:
+					b.Kick(id, "kicked", "")
+				case 1:
+					// Reconnect over the seat, then let the old connection leave late
+					if again, err := b.Join(game.JoinRequest{PlayerID: id}); err == nil {
+						connect(again)
+						b.Leave(seat)
+					}
+				default:
+					b.Leave(seat)
+				}
+			}
+		}(id)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for paused := true; ; paused = !paused {
+			select {
+			case <-stop:
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+			b.SetPaused(paused)
+			if taken, max := b.Seats(); taken > max {
+				t.Errorf("Seats = %d of %d", taken, max)
+			}
+			b.Describe()
+			b.Inspect()
+			b.IdleFor()
+			b.IsSeated(1)
+		}
+	}()
+
+	time.Sleep(3 * time.Second)
+	close(stop)
+	b.Close()
+
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	// play only returns once the seat's State channel is closed
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("players still connected after Close")
+	}
+
+	if _, err := b.Join(game.JoinRequest{PlayerID: 1}); !errors.Is(err, ErrTableClosed) {
+		t.Errorf("Join after Close = %v, want %v", err, ErrTableClosed)
+	}
+	// The loop has stopped, its state can be read directly
+	<-b.stopped
+	if len(b.Players) != 0 {
+		t.Errorf("%d players still seated after Close, want 0", len(b.Players))
+	}
+}
+
+func TestKickMidRound(t *testing.T) {
+	db := testDB(t, 2)
+	b := newInstance(db, Options{Mode: PracticeMode, HostID: 1, Password: "secret"})
+	defer b.Close()
+
+	join := func(id uint, password string) (*game.Seat, error) {
+		var account models.Account
+		err := db.First(&account, id).Error
+		return b.join(game.JoinRequest{PlayerID: id, Password: password}, &account, err)
+	}
+	if _, err := join(1, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	seat, err := join(2, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b.gamePhase = PlayerTurn
+	b.locked = true
+	b.kick(2, "kicked", "")
+
+	// The kicked connection gets the notice and is closed
+	var notice string
+	for update := range seat.State {
+		notice = update.(OutgoingUpdate).Notice
+	}
+	if notice != "kicked" {
+		t.Errorf("last notice = %q, want %q", notice, "kicked")
+	}
+
+	// The seat is still there until the round ends, but it cannot be reconnected to
+	if _, err := join(2, "secret"); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("rejoin mid-round = %v, want %v", err, ErrNotAllowed)
+	}
+	if b.findPlayerByID(2) == nil {
+		t.Fatal("kicked player removed before the round ended")
+	}
+
+	b.resetRound()
+	if b.findPlayerByID(2) != nil {
+		t.Fatal("kicked player still seated after the round")
+	}
+	// A kick is not a ban, but the player now goes through the lock like anyone new
+	if _, err := join(2, "secret"); !errors.Is(err, ErrTableLocked) {
+		t.Errorf("rejoin after the round = %v, want %v", err, ErrTableLocked)
+	}
+	b.locked = false
+	if _, err := join(2, "secret"); err != nil {
+		t.Errorf("rejoin after unlocking = %v, want nil", err)
+	}
+}
+
+func TestShutdownMidRound(t *testing.T) {
+	tests := []struct {
+		name    string
+		close   func(b *BlackJackInstance)
+		balance int
+	}{
+		{"close refunds the bet", (*BlackJackInstance).Close, PracticeStack},
+		{"suspend leaves the bet to the snapshot", (*BlackJackInstance).Suspend, PracticeStack - 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t, 1)
+			b := newInstance(db, Options{Mode: PracticeMode, HostID: 1})
+			var account models.Account
+			err := db.First(&account, 1).Error
+			if _, err := b.join(game.JoinRequest{PlayerID: 1}, &account, err); err != nil {
+				t.Fatal(err)
+			}
+			p := b.findPlayerByID(1)
+			p.Bet = 10
+			b.wallet.Debit(p, p.Bet)
+			b.unsettled = true
+
+			// The loop is not running, stop the instance by hand
+			tt.close(b)
+			b.shutdown()
+			if got := b.wallet.Balance(p); got != tt.balance {
+				t.Errorf("balance = %d, want %d", got, tt.balance)
+			}
+		})
+	}
+}
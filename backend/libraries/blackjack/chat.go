@@ -0,0 +1,187 @@
+package blackjack
+
+// chat.go
+// This file contains the in-table chat carried over the blackjack WebSocket.
+// Each table keeps a short history so late joiners see recent lines, limits how fast
+// each player can send, masks profanity and lets players mute each other.
+// Chat lines are delivered through the same Outgoing channels as game state, marked
+// with the ChatUpdate type.
+
+import (
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	chatHistorySize   = 50               // lines kept per table for late joiners
+	chatMaxLength     = 200              // characters per message
+	chatRateLimit     = 5                // messages allowed per player...
+	chatRateWindow    = 10 * time.Second // ...within this window
+	chatSystemMessage = 0                // PlayerID used for messages sent by the table itself
+)
+
+// profanity is the list of words masked out of chat messages.
+var profanity = []string{
+	"fuck", "shit", "bitch", "bastard", "asshole", "dick", "cunt", "damn", "crap", "piss",
+}
+
+// profanitySuffixes are the endings masked along with a listed word, such as "fucking"
+// or "shitty". Words that only contain a listed word, like "scrap" or "Dickens", are left alone.
+var profanitySuffixes = []string{"s", "es", "ed", "er", "ers", "ing", "y", "ty", "py"}
+
+// profanityPattern matches a whole word from the profanity list, with an optional suffix.
+var profanityPattern = regexp.MustCompile(`(?i)\b(?:` + strings.Join(profanity, "|") + `)(?:` + strings.Join(profanitySuffixes, "|") + `)?\b`)
+
+// ChatMessage is a single line of table chat.
+type ChatMessage struct {
+	ID       int
+	PlayerID uint // 0 for messages from the table itself
+	Username string
+	Text     string
+	Time     time.Time
+}
+
+// chatState is the chat data of a table.
+type chatState struct {
+	seq     int
+	history []ChatMessage
+	sent    map[uint][]time.Time   // recent send times per player, for rate limiting
+	mutes   map[uint]map[uint]bool // player -> players they have muted
+}
+
+func newChatState() *chatState {
+	return &chatState{
+		sent:  make(map[uint][]time.Time),
+		mutes: make(map[uint]map[uint]bool),
+	}
+}
+
+// allow records a send attempt and reports whether the player is within the rate limit.
+func (c *chatState) allow(playerID uint, now time.Time) bool {
+	recent := c.sent[playerID][:0]
+	for _, t := range c.sent[playerID] {
+		if now.Sub(t) < chatRateWindow {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= chatRateLimit {
+		c.sent[playerID] = recent
+		return false
+	}
+	c.sent[playerID] = append(recent, now)
+	return true
+}
+
+// isMuted reports whether listener has muted speaker.
+func (c *chatState) isMuted(listener, speaker uint) bool {
+	return c.mutes[listener][speaker]
+}
+
+// visibleTo returns the messages in history that listener has not muted.
+func (c *chatState) visibleTo(listener uint) []ChatMessage {
+	visible := make([]ChatMessage, 0, len(c.history))
+	for _, m := range c.history {
+		if !c.isMuted(listener, m.PlayerID) {
+			visible = append(visible, m)
+		}
+	}
+	return visible
+}
+
+// filterProfanity replaces profane words with asterisks.
+func filterProfanity(text string) string {
+	return profanityPattern.ReplaceAllStringFunc(text, func(word string) string {
+		return strings.Repeat("*", len([]rune(word)))
+	})
+}
+
+// handleChat validates, filters and fans out a chat message from a player.
+func (b *BlackJackInstance) handleChat(p *Player, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	if runes := []rune(text); len(runes) > chatMaxLength {
+		text = string(runes[:chatMaxLength])
+	}
+
+	if !b.chat.allow(p.ID, time.Now()) {
+		b.sendChatNotice(p, "You are sending messages too quickly")
+		return
+	}
+
+	b.chat.seq++
+	msg := ChatMessage{
+		ID:       b.chat.seq,
+		PlayerID: p.ID,
+		Username: p.Account.Username,
+		Text:     filterProfanity(text),
+		Time:     time.Now(),
+	}
+	b.chat.history = append(b.chat.history, msg)
+	if len(b.chat.history) > chatHistorySize {
+		b.chat.history = b.chat.history[len(b.chat.history)-chatHistorySize:]
+	}
+
+	for _, listener := range b.Players {
+		if !listener.Connected || b.chat.isMuted(listener.ID, p.ID) {
+			continue
+		}
+		b.sendChat(listener, []ChatMessage{msg}, "")
+	}
+}
+
+// setMuted mutes or unmutes target for the given player.
+func (b *BlackJackInstance) setMuted(p *Player, target uint, muted bool) {
+	if target == p.ID || target == chatSystemMessage {
+		return
+	}
+	if muted {
+		if b.chat.mutes[p.ID] == nil {
+			b.chat.mutes[p.ID] = make(map[uint]bool)
+		}
+		b.chat.mutes[p.ID][target] = true
+		b.sendChatNotice(p, "Player muted")
+	} else {
+		delete(b.chat.mutes[p.ID], target)
+		b.sendChatNotice(p, "Player unmuted")
+	}
+}
+
+// SendChatHistory sends the recent chat history to a player, normally right after they join.
+func (b *BlackJackInstance) SendChatHistory(playerID uint) {
+	b.post(func() { b.sendChatHistory(playerID) })
+}
+
+// sendChatHistory sends the recent chat history to a player on the game loop.
+func (b *BlackJackInstance) sendChatHistory(playerID uint) {
+	p := b.findPlayerByID(playerID)
+	if p == nil {
+		return
+	}
+	b.sendChat(p, b.chat.visibleTo(p.ID), "")
+}
+
+// sendChatNotice sends a message from the table to a single player.
+func (b *BlackJackInstance) sendChatNotice(p *Player, notice string) {
+	b.sendChat(p, nil, notice)
+}
+
+// sendChat delivers chat lines to a player without blocking the game loop.
+func (b *BlackJackInstance) sendChat(p *Player, messages []ChatMessage, notice string) {
+	update := OutgoingUpdate{
+		Type:   ChatUpdate,
+		Phase:  b.gamePhase,
+		Mode:   b.opts.Mode,
+		YourID: p.ID,
+		Chat:   messages,
+		Notice: notice,
+	}
+	select {
+	case p.Outgoing <- update:
+	default:
+		log.Println("Failed to send chat to player", p.ID)
+	}
+}
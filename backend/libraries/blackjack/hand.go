@@ -0,0 +1,210 @@
+package blackjack
+
+// hand.go
+// This file contains a player's hands and the moves played on them.
+// Every player starts the round with one hand, splitting a pair gives them another
+// one, up to MaxHands. Players play their hands in order, the active one is Active.
+
+import (
+	carddeck "cardgames/backend/libraries/cardDeck"
+)
+
+// MaxHands is the most hands a player can split into.
+const MaxHands = 4
+
+// Hand is one of a player's hands.
+type Hand struct {
+	Cards     []carddeck.Card
+	Bet       int          // chips the player has on the hand
+	FreeBet   int          // chips the house put up for free doubles and splits, Free Bet only
+	DoubleBet int          // chips the player paid to double, handed back by a double-down rescue
+	Doubled   bool         // the hand was doubled
+	FromSplit bool         // the hand came from a split, 21 on it is not a blackjack
+	SplitOff  bool         // the hand was split off another one, its bet was put up after the deal
+	Status    PlayerStatus // playing, stand, busted, blackjack or surrendered, then the result once settled
+}
+
+// activeHand returns the hand the player is playing, nil outside a round.
+func (p *Player) activeHand() *Hand {
+	if p.Active < len(p.Hands) {
+		return p.Hands[p.Active]
+	}
+	return nil
+}
+
+// activeCards returns the cards of the hand the player is playing.
+func (p *Player) activeCards() []carddeck.Card {
+	if h := p.activeHand(); h != nil {
+		return h.Cards
+	}
+	return []carddeck.Card{}
+}
+
+// staked returns the chips the player has on the table. Before the cards are dealt
+// that is the bet they placed, afterwards what they put on all their hands.
+func (p *Player) staked() int {
+	if len(p.Hands) == 0 {
+		return p.Bet
+	}
+	total := 0
+	for _, h := range p.Hands {
+		total += h.Bet
+	}
+	return total
+}
+
+// syncStatus sets the player's status from their hands. A player with a single hand
+// shows that hand's status, a player with split hands is playing until they all finish.
+func (p *Player) syncStatus() {
+	switch {
+	case len(p.Hands) == 0:
+		return
+	case len(p.Hands) == 1:
+		p.Status = p.Hands[0].Status
+	default:
+		p.Status = PlayerStatusStand
+		for _, h := range p.Hands {
+			if h.Status == PlayerStatusPlaying {
+				p.Status = PlayerStatusPlaying
+				return
+			}
+		}
+	}
+}
+
+// turnHand returns the hand playerID may act on now, or nil if it is not their turn.
+func (b *BlackJackInstance) turnHand(playerID uint) (*Player, *Hand) {
+	if b.gamePhase != PlayerTurn || b.currentTurnIndex < 0 || b.currentTurnIndex >= len(b.Players) {
+		return nil, nil
+	}
+	p := b.Players[b.currentTurnIndex]
+	if p.ID != playerID {
+		return nil, nil
+	}
+	h := p.activeHand()
+	if h == nil || h.Status != PlayerStatusPlaying {
+		return nil, nil
+	}
+	return p, h
+}
+
+// afterHandAction moves play on once the active hand is finished and sends the new state.
+func (b *BlackJackInstance) afterHandAction(p *Player) {
+	p.syncStatus()
+	if h := p.activeHand(); h == nil || h.Status != PlayerStatusPlaying {
+		if !b.moveToNextPlayer() {
+			b.gamePhase = DealerTurn
+		}
+	}
+	b.broadcastUpdate()
+}
+
+// hit deals the hand a card. A doubled hand can only stand or be rescued.
+func (b *BlackJackInstance) hit(h *Hand) bool {
+	if h.Doubled {
+		return false
+	}
+	h.Cards = append(h.Cards, b.Deck.Draw())
+	if b.calculateHandValue(h.Cards) > 21 {
+		h.Status = PlayerStatusBusted
+	}
+	return true
+}
+
+// double doubles the bet on the hand and deals it exactly one card. Free Bet doubles
+// on hard 9 to 11 are paid by the house. After doubling the hand stands, except in
+// Spanish 21 where the player may still rescue it.
+func (b *BlackJackInstance) double(p *Player, h *Hand) bool {
+	if h.Doubled || (len(h.Cards) != 2 && !b.rules.Variant.doubleAnyCards()) {
+		return false
+	}
+
+	if b.freeDouble(h) {
+		h.FreeBet += p.Bet
+	} else {
+		if !b.wallet.Debit(p, p.Bet) {
+			return false
+		}
+		h.Bet += p.Bet
+		h.DoubleBet = p.Bet
+		p.Wager.WagerAmount = p.staked()
+	}
+	h.Doubled = true
+
+	h.Cards = append(h.Cards, b.Deck.Draw())
+	switch {
+	case b.calculateHandValue(h.Cards) > 21:
+		h.Status = PlayerStatusBusted
+	case !b.rules.Variant.canSurrender():
+		h.Status = PlayerStatusStand
+	}
+	return true
+}
+
+// split splits a pair into two hands and deals each of them a second card. The new
+// hand gets the same bet, paid by the house for Free Bet splits. Split aces get one
+// card each and stand unless the variant lets them be played.
+func (b *BlackJackInstance) split(p *Player, h *Hand) bool {
+	if len(h.Cards) != 2 || cardValue(h.Cards[0]) != cardValue(h.Cards[1]) || len(p.Hands) >= MaxHands {
+		return false
+	}
+
+	second := &Hand{Cards: []carddeck.Card{h.Cards[1]}, FromSplit: true, SplitOff: true, Status: PlayerStatusPlaying}
+	if b.freeSplit(h) {
+		second.FreeBet = p.Bet
+	} else {
+		if !b.wallet.Debit(p, p.Bet) {
+			return false
+		}
+		second.Bet = p.Bet
+	}
+
+	// Fresh slices, earlier updates may still be reading the old ones
+	h.Cards = []carddeck.Card{h.Cards[0], b.Deck.Draw()}
+	h.FromSplit = true
+	second.Cards = append(second.Cards, b.Deck.Draw())
+
+	hands := make([]*Hand, 0, len(p.Hands)+1)
+	hands = append(hands, p.Hands[:p.Active+1]...)
+	hands = append(hands, second)
+	hands = append(hands, p.Hands[p.Active+1:]...)
+	p.Hands = hands
+	p.Wager.WagerAmount = p.staked()
+
+	if h.Cards[0].Value == carddeck.Ace && b.rules.Variant.splitAcesDrawOne() {
+		h.Status = PlayerStatusStand
+		second.Status = PlayerStatusStand
+	}
+	return true
+}
+
+// surrender gives up the hand. Without a double it is a late surrender of the first
+// two cards, half the bet comes back. On a doubled hand it is a double-down rescue,
+// the original bet is forfeit and the double comes back.
+func (b *BlackJackInstance) surrender(p *Player, h *Hand) bool {
+	if !b.rules.Variant.canSurrender() {
+		return false
+	}
+	if !h.Doubled && (len(h.Cards) != 2 || len(p.Hands) > 1) {
+		return false
+	}
+	h.Status = PlayerStatusSurrendered
+	return true
+}
+
+// isNatural reports whether the hand is a blackjack: 21 in its first two cards, not after a split.
+func (b *BlackJackInstance) isNatural(h *Hand) bool {
+	return len(h.Cards) == 2 && !h.FromSplit && b.calculateHandValue(h.Cards) == 21
+}
+
+// cardValue returns the blackjack value of a card, counting an ace as 11.
+func cardValue(c carddeck.Card) int {
+	switch c.Value {
+	case carddeck.Ace:
+		return 11
+	case carddeck.King, carddeck.Queen, carddeck.Jack:
+		return 10
+	default:
+		return c.Value.Number()
+	}
+}
@@ -0,0 +1,158 @@
+package blackjack
+
+// host.go
+// This file contains table admission and the host controls of private tables.
+// The player who creates a private table is its host. The host can protect the table
+// with a password, kick or ban players, lock the table to new joins, change the rules
+// between rounds and hand the host role to someone else. When the host leaves, the
+// role passes to the next connected player automatically. Players holding an invite
+// from the host skip the password and the lock.
+
+import (
+	"cardgames/backend/libraries/game"
+	"log"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Errors returned by Join when a player cannot take a seat.
+var (
+	ErrTableFull     = game.ErrTableFull
+	ErrTableLocked   = game.ErrTableLocked
+	ErrWrongPassword = game.ErrWrongPassword
+	ErrBanned        = game.ErrBanned
+	ErrNotAllowed    = game.ErrNotAllowed
+	ErrTableClosed   = game.ErrTableClosed
+)
+
+// JoinRequest describes a player asking for a seat at a table.
+type JoinRequest = game.JoinRequest
+
+// HostID returns the current host of the table, 0 if the table has no host.
+func (b *BlackJackInstance) HostID() uint {
+	var hostID uint
+	b.call(func() { hostID = b.hostID })
+	return hostID
+}
+
+// IsSeated reports whether the player already has a seat at the table.
+func (b *BlackJackInstance) IsSeated(playerID uint) bool {
+	var seated bool
+	b.call(func() { seated = b.findPlayerByID(playerID) != nil })
+	return seated
+}
+
+// HasPassword reports whether joining the table requires a password.
+func (b *BlackJackInstance) HasPassword() bool {
+	var hasPassword bool
+	b.call(func() { hasPassword = b.passwordHash != nil })
+	return hasPassword
+}
+
+// setPassword stores a hash of the table password.
+func (b *BlackJackInstance) setPassword(password string) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Println("Failed to hash table password:", err)
+		return
+	}
+	b.passwordHash = hash
+}
+
+// checkAdmission applies the host lock and table password to a new player.
+func (b *BlackJackInstance) checkAdmission(req JoinRequest) error {
+	if req.Invited {
+		return nil
+	}
+	if b.locked {
+		return ErrTableLocked
+	}
+	if b.passwordHash != nil && bcrypt.CompareHashAndPassword(b.passwordHash, []byte(req.Password)) != nil {
+		return ErrWrongPassword
+	}
+	return nil
+}
+
+// passHost gives the host role to the next connected player after the current host,
+// in seat order. The table is left without a host if nobody else is connected; the
+// next player to join becomes host.
+func (b *BlackJackInstance) passHost() {
+	if !b.hosted {
+		return
+	}
+
+	start := 0
+	for i, p := range b.Players {
+		if p.ID == b.hostID {
+			start = i + 1
+			break
+		}
+	}
+	for i := 0; i < len(b.Players); i++ {
+		p := b.Players[(start+i)%len(b.Players)]
+		if p.Connected && p.ID != b.hostID {
+			b.hostID = p.ID
+			return
+		}
+	}
+	b.hostID = 0
+}
+
+// handleHostAction carries out a host control action. Actions from anyone other
+// than the host are ignored.
+func (b *BlackJackInstance) handleHostAction(update IncomingUpdate) {
+	if !b.hosted || update.PlayerID != b.hostID {
+		return
+	}
+
+	switch update.Action {
+	case KickAction:
+		if update.TargetID == update.PlayerID {
+			return
+		}
+		b.kick(update.TargetID, "You were removed from the table by the host", "")
+	case BanAction:
+		if update.TargetID == update.PlayerID {
+			return
+		}
+		b.banned[update.TargetID] = true
+		b.kick(update.TargetID, "You were banned from the table by the host", "")
+	case UnbanAction:
+		delete(b.banned, update.TargetID)
+	case LockAction, UnlockAction:
+		b.locked = update.Action == LockAction
+	case TransferHostAction:
+		target := b.findPlayerByID(update.TargetID)
+		if target != nil && target.Connected {
+			b.hostID = target.ID
+		}
+	case SetRulesAction:
+		if update.Rules == nil || update.Rules.validateFor(b.accountChips()) != nil {
+			return
+		}
+		if b.gamePhase == Betting {
+			b.applyRules(*update.Rules)
+		} else {
+			// Wait for the round in progress to finish
+			rules := *update.Rules
+			b.pendingRules = &rules
+		}
+	}
+	b.broadcastUpdate()
+}
+
+// applyRules switches the table to new rules. It must only be called between rounds.
+// Bets already placed that fall outside the new limits are cleared.
+func (b *BlackJackInstance) applyRules(rules Rules) {
+	// A Spanish 21 shoe has no tens, switching to or from it needs a new shoe too
+	decksChanged := rules.Decks != b.rules.Decks || rules.Variant.deck().Size() != b.rules.Variant.deck().Size()
+	b.rules = rules
+	if decksChanged {
+		b.newShoe()
+	}
+	for _, p := range b.Players {
+		if !b.betAllowed(p.Bet) {
+			p.Bet = 0
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package blackjack
+
+// lifecycle.go
+// This file contains the lifecycle of a blackjack instance: shutting the game loop
+// down, the goroutines that forward player input to the loop, and idle detection so
+// the game instance manager knows when a table can be cleaned up.
+
+import (
+	"cardgames/backend/libraries/game"
+	"encoding/json"
+	"time"
+)
+
+// Close stops the game loop and every player forwarding goroutine, and closes the
+// players' Outgoing channels so their WebSocket handlers return. It is safe to call
+// more than once and from any goroutine.
+func (b *BlackJackInstance) Close() {
+	b.closeOnce.Do(func() {
+		close(b.done)
+	})
+}
+
+// Suspend closes the instance for a server restart. Unlike Close, chips staked on a
+// round in progress are not refunded, the last snapshot refunds them when it is restored.
+func (b *BlackJackInstance) Suspend() {
+	b.closeOnce.Do(func() {
+		b.suspended = true
+		close(b.done)
+	})
+}
+
+// Done returns a channel that is closed once the instance has been closed.
+func (b *BlackJackInstance) Done() <-chan struct{} {
+	return b.done
+}
+
+// Seats returns the number of seats taken and the size of the table.
+func (b *BlackJackInstance) Seats() (int, int) {
+	var taken int
+	b.call(func() { taken = len(b.Players) })
+	return taken, MaxPlayersPerInstance
+}
+
+// Describe summarizes the table for the lobby's table browser. Players who have not
+// bet this round count as spectators.
+func (b *BlackJackInstance) Describe() game.Summary {
+	var summary game.Summary
+	b.call(func() { summary = b.describe() })
+	return summary
+}
+
+// describe summarizes the table on the game loop, see Describe.
+func (b *BlackJackInstance) describe() game.Summary {
+	spectators := 0
+	ids := make([]uint, 0, len(b.Players))
+	for _, p := range b.Players {
+		if p.staked() == 0 {
+			spectators++
+		}
+		ids = append(ids, p.ID)
+	}
+	return game.Summary{
+		Phase:         string(b.gamePhase),
+		Rules:         b.rules.Summary(),
+		MinStake:      b.rules.MinBet,
+		MaxStake:      b.rules.MaxBet,
+		Spectators:    spectators,
+		TurnSeconds:   b.rules.ActionSeconds,
+		BetweenRounds: b.gamePhase == Betting,
+		PlayerIDs:     ids,
+	}
+}
+
+// IdleFor returns how long the table has had no connected players, or 0 if
+// someone is connected right now.
+func (b *BlackJackInstance) IdleFor() time.Duration {
+	var idleSince time.Time
+	if !b.call(func() { idleSince = b.idleSince }) {
+		// Closed tables are idle
+		return time.Since(b.idleSince)
+	}
+	if idleSince.IsZero() {
+		return 0
+	}
+	return time.Since(idleSince)
+}
+
+// updateIdle records when the last connected player left. Called by the game loop
+// after a player connects or disconnects.
+func (b *BlackJackInstance) updateIdle() {
+	for _, p := range b.Players {
+		if p.Connected {
+			b.idleSince = time.Time{}
+			return
+		}
+	}
+	if b.idleSince.IsZero() {
+		b.idleSince = time.Now()
+	}
+}
+
+// startForwarding starts the goroutine that moves a player's input onto the game
+// loop's incoming channel. It exits when the player is detached or the table closes.
+// Messages that are not valid updates are dropped, and every update is marked as
+// coming from the seated player whatever the client put in PlayerID.
+func (b *BlackJackInstance) startForwarding(p *Player) {
+	p.stop = make(chan struct{})
+	go func(playerID uint, incoming chan json.RawMessage, stop chan struct{}) {
+		for {
+			select {
+			case raw := <-incoming:
+				var update IncomingUpdate
+				if err := json.Unmarshal(raw, &update); err != nil {
+					continue
+				}
+				update.PlayerID = playerID
+				select {
+				case b.incoming <- update:
+				case <-stop:
+					return
+				case <-b.done:
+					return
+				}
+			case <-stop:
+				return
+			case <-b.done:
+				return
+			}
+		}
+	}(p.ID, p.Incoming, p.stop)
+}
+
+// detach stops a player's forwarding goroutine and closes their Outgoing channel.
+// Incoming is left open: the WebSocket reader may still be sending on it. Detaching
+// a player twice, e.g. one kicked mid-round when the round ends, does nothing.
+// Outgoing is nil afterwards, so sends to the player fall through to their default.
+func (b *BlackJackInstance) detach(p *Player) {
+	if p.stop == nil {
+		return
+	}
+	close(p.stop)
+	close(p.Outgoing)
+	p.stop = nil
+	p.Outgoing = nil
+}
+
+// shutdown detaches every player as the game loop stops. If the table
+// closes in the middle of a round, the chips players have on it are refunded,
+// unless the table was suspended.
+func (b *BlackJackInstance) shutdown() {
+	if b.unsettled && !b.suspended {
+		for _, p := range b.Players {
+			if staked := p.staked(); staked > 0 {
+				b.wallet.Credit(p, staked)
+			}
+		}
+		b.unsettled = false
+	}
+
+	for _, p := range b.Players {
+		b.detach(p)
+	}
+	b.Players = nil
+}
@@ -0,0 +1,83 @@
+package blackjack
+
+// mailbox.go
+// This file contains how the rest of the server talks to a blackjack instance.
+// All table state belongs to the game loop goroutine and nothing else touches it.
+// Joins, leaves, host and tournament controls and queries such as the seat count are
+// requests posted to the table's mailbox, and the loop runs them one at a time between
+// its own steps. Commands are posted and return right away, queries wait for the loop
+// to answer.
+
+// The mailbox never blocks the sender, so a request made while holding a lock the loop
+// itself may need (the tournament manager's, through its wallet) cannot deadlock.
+// Requests are run in the order they were posted.
+
+import "sync"
+
+// mailbox is an unbounded queue of requests for the game loop.
+type mailbox struct {
+	mu    sync.Mutex
+	queue []func()
+	ready chan struct{} // holds a value while the queue is not empty
+}
+
+func newMailbox() *mailbox {
+	return &mailbox{ready: make(chan struct{}, 1)}
+}
+
+// post queues a request and wakes the loop up.
+func (m *mailbox) post(f func()) {
+	m.mu.Lock()
+	m.queue = append(m.queue, f)
+	m.mu.Unlock()
+
+	select {
+	case m.ready <- struct{}{}:
+	default:
+		// Loop has already been woken up
+	}
+}
+
+// take empties the queue and returns the requests in it, oldest first.
+func (m *mailbox) take() []func() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	queue := m.queue
+	m.queue = nil
+	return queue
+}
+
+// post sends a command to the game loop without waiting for it to run.
+// Commands posted once the loop has stopped are dropped.
+func (b *BlackJackInstance) post(f func()) {
+	b.mailbox.post(f)
+}
+
+// call runs f on the game loop and waits for it. Returns false, without f having run,
+// if the loop has stopped. Must not be called from the game loop itself.
+func (b *BlackJackInstance) call(f func()) bool {
+	reply := make(chan struct{})
+	b.mailbox.post(func() {
+		f()
+		close(reply)
+	})
+	select {
+	case <-reply:
+		return true
+	case <-b.stopped:
+		// The loop may have run f just before stopping
+		select {
+		case <-reply:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// runRequests runs every request waiting in the mailbox. Called by the game loop.
+func (b *BlackJackInstance) runRequests() {
+	for _, f := range b.mailbox.take() {
+		f()
+	}
+}
@@ -0,0 +1,72 @@
+package blackjack
+
+// register.go
+// This file registers blackjack with the game registry, so the lobby can create
+// blackjack tables and the WebSocket handler can connect players to them.
+
+import (
+	"cardgames/backend/libraries/game"
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// GameName is the name blackjack is registered under.
+const GameName = "blackjack"
+
+func init() {
+	game.Register(game.Definition{
+		Name:  GameName,
+		Modes: []string{string(StandardMode), string(PracticeMode), string(TrainerMode), string(Spanish21), string(FreeBet)},
+		New:   newFromConfig,
+	})
+}
+
+// newFromConfig creates a blackjack table from the settings chosen in the lobby, or
+// restores one from a snapshot. The creator of a private table becomes its host.
+// The spanish21 and free_bet modes are real money tables playing that variant.
+func newFromConfig(db *gorm.DB, cfg game.Config) (game.Game, error) {
+	opts := Options{Mode: TableMode(cfg.Mode)}
+	variant := Variant(cfg.Mode)
+	if variant == Spanish21 || variant == FreeBet {
+		opts.Mode = StandardMode
+		rules := DefaultRulesFor(variant)
+		opts.Rules = &rules
+	}
+	accountChips := opts.Mode == "" || opts.Mode == StandardMode
+	if cfg.Private {
+		opts.HostID = cfg.HostID
+		opts.Password = cfg.Password
+	}
+	if len(cfg.Rules) > 0 && string(cfg.Rules) != "null" {
+		var rules Rules
+		if err := json.Unmarshal(cfg.Rules, &rules); err != nil {
+			return nil, fmt.Errorf("%w: %v", game.ErrInvalidConfig, err)
+		}
+		if opts.Rules != nil {
+			// The lobby mode picks the variant
+			rules.Variant = opts.Rules.Variant
+		}
+		if err := rules.validateFor(accountChips); err != nil {
+			return nil, fmt.Errorf("%w: %v", game.ErrInvalidConfig, err)
+		}
+		opts.Rules = &rules
+	}
+	opts.OnSnapshot = cfg.OnSnapshot
+
+	if len(cfg.Snapshot) == 0 {
+		return NewBlackJackInstanceWithOptions(db, opts), nil
+	}
+	var snap snapshot
+	if err := json.Unmarshal(cfg.Snapshot, &snap); err != nil {
+		return nil, fmt.Errorf("%w: %v", game.ErrInvalidConfig, err)
+	}
+	if err := snap.Rules.validateFor(accountChips); err != nil {
+		return nil, fmt.Errorf("%w: %v", game.ErrInvalidConfig, err)
+	}
+	b := newInstance(db, opts)
+	b.restore(snap)
+	go b.GameLoop()
+	return b, nil
+}
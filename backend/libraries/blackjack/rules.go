@@ -0,0 +1,157 @@
+package blackjack
+
+// rules.go
+// This file contains the configurable table rules of a blackjack instance.
+// Regular tables use DefaultRules, private tables can set their own when they are
+// created and the host can change them between rounds.
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidRules is returned when a Rules value fails validation.
+var ErrInvalidRules = errors.New("invalid table rules")
+
+// MaxAccountBet is the largest bet a table played for account chips accepts. Its rules
+// may not set a higher MaxBet, and a MaxBet of 0 is held to it too.
+const MaxAccountBet = 10000
+
+// payouts are the natural blackjack payouts a table may offer.
+var payouts = map[string]bool{"3:2": true, "6:5": true, "1:1": true}
+
+// Rules are the settings a blackjack table plays by.
+type Rules struct {
+	Decks            int     // decks in the shoe
+	MinBet           int     // smallest bet accepted
+	MaxBet           int     // largest bet accepted, 0 for no limit
+	BlackjackPayout  string  // natural blackjack payout: "3:2", "6:5" or "1:1"
+	DealerHitsSoft17 bool    // dealer hits a soft 17 instead of standing
+	BettingSeconds   int     // length of the betting phase
+	ActionSeconds    int     // time each player has to act on their turn
+	Variant          Variant // game variant, classic when empty
+}
+
+// DefaultRules returns the rules used by tables that do not set their own.
+func DefaultRules() Rules {
+	return Rules{
+		Decks:            4,
+		MinBet:           1,
+		MaxBet:           0,
+		BlackjackPayout:  "3:2",
+		DealerHitsSoft17: false,
+		BettingSeconds:   BettingTimeLimit,
+		ActionSeconds:    ActionTimeLimit,
+		Variant:          Classic,
+	}
+}
+
+// DefaultRulesFor returns the default rules of a variant. Spanish 21 and Free Bet
+// tables deal from six decks and the dealer hits soft 17.
+func DefaultRulesFor(v Variant) Rules {
+	r := DefaultRules()
+	if v == Spanish21 || v == FreeBet {
+		r.Decks = 6
+		r.DealerHitsSoft17 = true
+	}
+	r.Variant = v
+	return r
+}
+
+// Validate checks that the rules describe a playable table.
+func (r Rules) Validate() error {
+	if r.Decks < 1 || r.Decks > 8 {
+		return ErrInvalidRules
+	}
+	if r.MinBet < 1 || (r.MaxBet != 0 && r.MaxBet < r.MinBet) {
+		return ErrInvalidRules
+	}
+	if r.BettingSeconds < 3 || r.BettingSeconds > 60 || r.ActionSeconds < 3 || r.ActionSeconds > 60 {
+		return ErrInvalidRules
+	}
+	if !payouts[r.BlackjackPayout] {
+		return ErrInvalidRules
+	}
+	if !r.Variant.Valid() {
+		return ErrInvalidRules
+	}
+	return nil
+}
+
+// validateFor checks the rules like Validate. Tables played for account chips may
+// also not set a MaxBet above MaxAccountBet.
+func (r Rules) validateFor(accountChips bool) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	if accountChips && r.MaxBet > MaxAccountBet {
+		return ErrInvalidRules
+	}
+	return nil
+}
+
+// Summary describes the rules in a few words, e.g. "Spanish 21, 6 decks, blackjack pays 3:2, dealer hits soft 17".
+func (r Rules) Summary() string {
+	soft17 := "dealer stands on soft 17"
+	if r.DealerHitsSoft17 {
+		soft17 = "dealer hits soft 17"
+	}
+	summary := fmt.Sprintf("%d decks, blackjack pays %s, %s", r.Decks, r.BlackjackPayout, soft17)
+	switch r.Variant {
+	case Spanish21:
+		return "Spanish 21, " + summary
+	case FreeBet:
+		return "Free Bet, " + summary
+	}
+	return summary
+}
+
+// BetAllowed reports whether bet is within the table limits. A bet of 0 sits the round out.
+func (r Rules) BetAllowed(bet int) bool {
+	if bet == 0 {
+		return true
+	}
+	return bet >= r.MinBet && (r.MaxBet == 0 || bet <= r.MaxBet)
+}
+
+// accountChips reports whether the table is played for the players' account balances.
+func (b *BlackJackInstance) accountChips() bool {
+	_, ok := b.wallet.(*accountWallet)
+	return ok
+}
+
+// betAllowed reports whether bet is within the table limits, see Rules.BetAllowed.
+// Tables played for account chips never take more than MaxAccountBet.
+func (b *BlackJackInstance) betAllowed(bet int) bool {
+	return b.rules.BetAllowed(bet) && (!b.accountChips() || bet <= MaxAccountBet)
+}
+
+// blackjackWinnings returns what a natural blackjack wins on top of the returned bet.
+func (r Rules) blackjackWinnings(bet int) int {
+	num, den, ok := parsePayout(r.BlackjackPayout)
+	if !ok {
+		num, den = 3, 2
+	}
+	return bet * num / den
+}
+
+// reshuffleAt returns the number of cards left in the shoe that triggers a reshuffle (25%).
+func (r Rules) reshuffleAt() int {
+	return r.Decks * r.Variant.deck().Size() / 4
+}
+
+// parsePayout parses a payout written as "num:den".
+func parsePayout(payout string) (int, int, bool) {
+	parts := strings.Split(payout, ":")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	num, err1 := strconv.Atoi(parts[0])
+	den, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || num <= 0 || den <= 0 {
+		return 0, 0, false
+	}
+	return num, den, true
+}
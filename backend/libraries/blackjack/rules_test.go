@@ -0,0 +1,56 @@
+package blackjack
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRulesValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		change       func(r *Rules)
+		accountChips bool
+		valid        bool
+	}{
+		{"defaults", func(r *Rules) {}, true, true},
+		{"3:2", func(r *Rules) { r.BlackjackPayout = "3:2" }, true, true},
+		{"6:5", func(r *Rules) { r.BlackjackPayout = "6:5" }, true, true},
+		{"1:1", func(r *Rules) { r.BlackjackPayout = "1:1" }, true, true},
+		{"100:1", func(r *Rules) { r.BlackjackPayout = "100:1" }, false, false},
+		{"7:5", func(r *Rules) { r.BlackjackPayout = "7:5" }, false, false},
+		{"huge numerator", func(r *Rules) { r.BlackjackPayout = "9223372036854775807:1" }, false, false},
+		{"6:4", func(r *Rules) { r.BlackjackPayout = "6:4" }, false, false},
+		{"padded", func(r *Rules) { r.BlackjackPayout = " 3:2" }, false, false},
+		{"empty payout", func(r *Rules) { r.BlackjackPayout = "" }, false, false},
+		{"max bet at the account limit", func(r *Rules) { r.MaxBet = MaxAccountBet }, true, true},
+		{"max bet over the account limit", func(r *Rules) { r.MaxBet = MaxAccountBet + 1 }, true, false},
+		{"max bet over the account limit on play money", func(r *Rules) { r.MaxBet = MaxAccountBet + 1 }, false, true},
+		{"max bet under min bet", func(r *Rules) { r.MinBet, r.MaxBet = 10, 5 }, false, false},
+		{"unknown variant", func(r *Rules) { r.Variant = "pontoon" }, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := DefaultRules()
+			tt.change(&rules)
+			err := rules.validateFor(tt.accountChips)
+			if tt.valid && err != nil {
+				t.Errorf("validateFor = %v, want nil", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidRules) {
+				t.Errorf("validateFor = %v, want %v", err, ErrInvalidRules)
+			}
+		})
+	}
+}
+
+func TestBetAllowedOnAccountChips(t *testing.T) {
+	b := &BlackJackInstance{rules: DefaultRules(), wallet: NewAccountWallet(nil)}
+	if !b.betAllowed(MaxAccountBet) || b.betAllowed(MaxAccountBet+1) {
+		t.Errorf("account table without a MaxBet must take bets up to %d only", MaxAccountBet)
+	}
+	b.wallet = NewPlayMoneyWallet(PracticeStack)
+	if !b.betAllowed(MaxAccountBet + 1) {
+		t.Error("play money table without a MaxBet must take any bet")
+	}
+}
@@ -0,0 +1,151 @@
+package blackjack
+
+// snapshot.go
+// This file contains saving a blackjack table so it survives a server restart, and
+// restoring it. The table is saved when the bets are locked in, when a split or double
+// puts more chips on the table, as soon as the bets are paid out and when the round is over. The snapshot keeps the
+// rules, the host controls, the seats, the shoe and the trainer scores.
+
+// A restored table starts in the betting phase. Chips that were on the table in a round
+// cut short by the restart are paid back, and every seat is held for its player for
+// game.SeatHoldTime so they can reconnect and carry on.
+
+import (
+	carddeck "cardgames/backend/libraries/cardDeck"
+	"cardgames/backend/libraries/game"
+	"cardgames/backend/models"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// snapshot is the saved state of a table.
+type snapshot struct {
+	Rules        Rules
+	Hosted       bool
+	HostID       uint
+	PasswordHash []byte `json:",omitempty"`
+	Locked       bool
+	Banned       []uint `json:",omitempty"`
+	Seats        []seatSnapshot
+	Shoe         carddeck.Deck
+	ShoeTotal    int // Hi-Lo total of the shoe when it was shuffled, trainer tables only
+	RoundsPlayed int
+	Chat         []ChatMessage `json:",omitempty"`
+}
+
+// seatSnapshot is the saved state of a seated player.
+type seatSnapshot struct {
+	ID     uint
+	Staked int        `json:",omitempty"` // chips on the table in the round in progress
+	Stack  *int       `json:",omitempty"` // play-money stack at practice and trainer tables
+	Quiz   *QuizScore `json:",omitempty"` // trainer tables only
+}
+
+// saveSnapshot hands the table state to the OnSnapshot hook, if there is one.
+func (b *BlackJackInstance) saveSnapshot() {
+	if b.opts.OnSnapshot == nil {
+		return
+	}
+
+	snap := snapshot{
+		Rules:        b.rules,
+		Hosted:       b.hosted,
+		HostID:       b.hostID,
+		PasswordHash: b.passwordHash,
+		Locked:       b.locked,
+		Shoe:         b.Deck,
+		RoundsPlayed: b.RoundsPlayed,
+		Chat:         b.chat.history,
+	}
+	for id := range b.banned {
+		snap.Banned = append(snap.Banned, id)
+	}
+	if b.trainer != nil {
+		snap.ShoeTotal = b.trainer.shoeTotal
+	}
+	wallet, playMoney := b.wallet.(*playMoneyWallet)
+	for _, p := range b.Players {
+		seat := seatSnapshot{ID: p.ID}
+		if b.unsettled {
+			seat.Staked = p.staked()
+		}
+		if playMoney {
+			stack := wallet.Balance(p)
+			seat.Stack = &stack
+		}
+		if b.trainer != nil {
+			score := b.trainer.seat(p.ID).score
+			seat.Quiz = &score
+		}
+		snap.Seats = append(snap.Seats, seat)
+	}
+
+	state, err := json.Marshal(snap)
+	if err != nil {
+		log.Println("Failed to encode blackjack snapshot:", err)
+		return
+	}
+	b.opts.OnSnapshot(state)
+}
+
+// restore sets the table up from a snapshot. It is called before the game loop starts.
+// Players whose account is gone are left out.
+func (b *BlackJackInstance) restore(snap snapshot) {
+	b.rules = snap.Rules
+	b.hosted = snap.Hosted
+	b.hostID = snap.HostID
+	b.passwordHash = snap.PasswordHash
+	b.locked = snap.Locked
+	for _, id := range snap.Banned {
+		b.banned[id] = true
+	}
+	if len(snap.Shoe) > 0 {
+		b.Deck = snap.Shoe
+		if b.trainer != nil {
+			b.trainer.shoeTotal = snap.ShoeTotal
+		}
+	} else {
+		b.newShoe()
+	}
+	b.RoundsPlayed = snap.RoundsPlayed
+	b.chat.history = snap.Chat
+	if n := len(snap.Chat); n > 0 {
+		b.chat.seq = snap.Chat[n-1].ID
+	}
+
+	heldUntil := time.Now().Add(game.SeatHoldTime)
+	wallet, playMoney := b.wallet.(*playMoneyWallet)
+	for _, seat := range snap.Seats {
+		var account models.Account
+		if err := b.DB.Where("id = ?", seat.ID).First(&account).Error; err != nil {
+			log.Println("Not restoring seat of player", seat.ID, ":", err)
+			continue
+		}
+		p := &Player{
+			ID:        seat.ID,
+			Account:   &account,
+			Status:    PlayerStatusStandby,
+			Incoming:  make(chan json.RawMessage),
+			Outgoing:  make(chan any, 10),
+			Wager:     &models.Wager{AccountID: seat.ID},
+			stop:      make(chan struct{}),
+			heldUntil: heldUntil,
+		}
+		b.Players = append(b.Players, p)
+
+		if playMoney && seat.Stack != nil {
+			wallet.setStack(p.ID, *seat.Stack)
+		}
+		if seat.Staked > 0 {
+			// The round was cut short, give the chips back
+			b.wallet.Credit(p, seat.Staked)
+		}
+		if b.trainer != nil && seat.Quiz != nil {
+			b.trainer.seat(p.ID).score = *seat.Quiz
+		}
+	}
+
+	// Save right away so the chips paid back are not paid again after another restart
+	b.saveSnapshot()
+}
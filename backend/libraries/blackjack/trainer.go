@@ -0,0 +1,178 @@
+package blackjack
+
+// trainer.go
+// This file contains the card counting trainer used by trainer tables.
+// Trainer tables play with practice chips and add an overlay to every update with the
+// Hi-Lo running count, true count and what is left in the shoe. Every few rounds the
+// players are quizzed on the count and their answers are scored.
+
+import (
+	"fmt"
+	"math"
+
+	carddeck "cardgames/backend/libraries/cardDeck"
+)
+
+// quizEvery is the number of rounds between count quizzes.
+const quizEvery = 3
+
+// Quiz questions.
+const (
+	QuizRunningCount = "running_count"
+	QuizTrueCount    = "true_count" // answered as a whole number, rounded toward zero
+)
+
+// TrainerInfo is the trainer overlay sent to players at trainer tables.
+// The count fields are left out while the player has the overlay hidden or
+// has an unanswered quiz, so the answer is not on screen.
+type TrainerInfo struct {
+	RunningCount   *int                  `json:",omitempty"`
+	TrueCount      *float64              `json:",omitempty"`
+	CardsRemaining int                   // cards the player has not seen yet
+	DecksRemaining float64               // CardsRemaining expressed in decks
+	Remaining      map[carddeck.Rank]int `json:",omitempty"` // unseen cards by rank
+	Quiz           *Quiz                 `json:",omitempty"`
+	Score          QuizScore
+}
+
+// Quiz is an open question for a player about the current count.
+type Quiz struct {
+	ID       int
+	Question string
+}
+
+// QuizScore tracks a player's quiz results at the table.
+type QuizScore struct {
+	Asked      int
+	Correct    int
+	Streak     int
+	LastResult string
+}
+
+// trainerSeat is the trainer state of one player.
+type trainerSeat struct {
+	hideOverlay bool
+	quiz        *Quiz
+	expected    int
+	score       QuizScore
+}
+
+// trainerState holds the trainer data of a trainer table.
+type trainerState struct {
+	shoeTotal int // Hi-Lo total of the shoe when it was shuffled
+	quizSeq   int
+	seats     map[uint]*trainerSeat
+}
+
+func newTrainerState() *trainerState {
+	return &trainerState{seats: make(map[uint]*trainerSeat)}
+}
+
+// seat returns the trainer state for a player, creating it on first use.
+func (t *trainerState) seat(playerID uint) *trainerSeat {
+	s, ok := t.seats[playerID]
+	if !ok {
+		s = &trainerSeat{}
+		t.seats[playerID] = s
+	}
+	return s
+}
+
+// holeCardHidden reports whether the dealer's second card is still face down.
+func (b *BlackJackInstance) holeCardHidden() bool {
+	return b.gamePhase != DealerTurn && len(b.DealerHand) > 1
+}
+
+// runningCount returns the Hi-Lo count of every card players have seen since the shuffle.
+func (b *BlackJackInstance) runningCount() int {
+	count := b.trainer.shoeTotal - b.Deck.HiLoTotal()
+	if b.holeCardHidden() {
+		count -= carddeck.HiLo(b.DealerHand[1])
+	}
+	return count
+}
+
+// unseenCards returns the cards players have not seen: the rest of the shoe plus
+// the dealer's hole card while it is face down.
+func (b *BlackJackInstance) unseenCards() carddeck.Deck {
+	unseen := b.Deck
+	if b.holeCardHidden() {
+		unseen = append(carddeck.Deck{b.DealerHand[1]}, b.Deck...)
+	}
+	return unseen
+}
+
+// trueCount returns the running count divided by the decks that have not been seen.
+func (b *BlackJackInstance) trueCount() float64 {
+	decks := b.unseenCards().DecksRemaining()
+	if decks == 0 {
+		return 0
+	}
+	return float64(b.runningCount()) / decks
+}
+
+// trainerOverlay builds the overlay for one player.
+func (b *BlackJackInstance) trainerOverlay(p *Player) *TrainerInfo {
+	seat := b.trainer.seat(p.ID)
+	unseen := b.unseenCards()
+
+	info := &TrainerInfo{
+		CardsRemaining: len(unseen),
+		DecksRemaining: math.Round(unseen.DecksRemaining()*100) / 100,
+		Quiz:           seat.quiz,
+		Score:          seat.score,
+	}
+	if !seat.hideOverlay && seat.quiz == nil {
+		running := b.runningCount()
+		trueCount := math.Round(b.trueCount()*10) / 10
+		info.RunningCount = &running
+		info.TrueCount = &trueCount
+		info.Remaining = unseen.Composition()
+	}
+	return info
+}
+
+// askQuiz opens a new count question for every connected player. Questions that
+// were still open count as missed.
+func (b *BlackJackInstance) askQuiz() {
+	b.trainer.quizSeq++
+	quiz := &Quiz{ID: b.trainer.quizSeq, Question: QuizRunningCount}
+	expected := b.runningCount()
+	if b.trainer.quizSeq%2 == 0 {
+		quiz.Question = QuizTrueCount
+		expected = int(b.trueCount()) // truncates toward zero
+	}
+
+	for _, p := range b.Players {
+		if !p.Connected {
+			continue
+		}
+		seat := b.trainer.seat(p.ID)
+		if seat.quiz != nil {
+			seat.score.Streak = 0
+			seat.score.LastResult = "Missed the last question"
+		}
+		seat.quiz = quiz
+		seat.expected = expected
+		seat.score.Asked++
+	}
+}
+
+// answerQuiz scores a player's answer to their open quiz.
+func (b *BlackJackInstance) answerQuiz(playerID uint, answer int) bool {
+	seat := b.trainer.seat(playerID)
+	if seat.quiz == nil {
+		return false
+	}
+
+	if answer == seat.expected {
+		seat.score.Correct++
+		seat.score.Streak++
+		seat.score.LastResult = fmt.Sprintf("Correct! The answer was %+d", seat.expected)
+	} else {
+		seat.score.Streak = 0
+		seat.score.LastResult = fmt.Sprintf("Not quite, you said %+d but the answer was %+d", answer, seat.expected)
+	}
+	seat.quiz = nil
+	return true
+}
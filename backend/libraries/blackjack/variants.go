@@ -0,0 +1,172 @@
+package blackjack
+
+// variants.go
+// This file contains the blackjack variants a table can play and how each one settles a hand.
+// Classic is regular blackjack. Spanish 21 deals from 48-card decks without tens, a player
+// 21 always wins, five or more card 21s and 6-7-8 / 7-7-7 pay bonuses, and players may
+// surrender late or rescue a double. Free Bet blackjack gives free doubles on hard 9 to 11
+// and free splits of every pair but tens, and a dealer 22 pushes every hand still standing.
+
+import (
+	carddeck "cardgames/backend/libraries/cardDeck"
+)
+
+// Variant is the version of blackjack a table plays.
+type Variant string
+
+const (
+	Classic   Variant = "classic"
+	Spanish21 Variant = "spanish21"
+	FreeBet   Variant = "free_bet"
+)
+
+// Valid reports whether v is a known variant. The empty variant plays classic blackjack.
+func (v Variant) Valid() bool {
+	switch v {
+	case "", Classic, Spanish21, FreeBet:
+		return true
+	}
+	return false
+}
+
+// deck returns the deck the variant's shoe is built from.
+func (v Variant) deck() carddeck.DeckSpec {
+	if v == Spanish21 {
+		return carddeck.SpanishDeck
+	}
+	return carddeck.StandardDeck
+}
+
+// canSurrender reports whether the variant offers late surrender and double-down rescue.
+func (v Variant) canSurrender() bool {
+	return v == Spanish21
+}
+
+// splitAcesDrawOne reports whether split aces get a single card each and stand.
+// Spanish 21 lets players hit and double split aces.
+func (v Variant) splitAcesDrawOne() bool {
+	return v != Spanish21
+}
+
+// doubleAnyCards reports whether a hand may be doubled on more than two cards.
+func (v Variant) doubleAnyCards() bool {
+	return v == Spanish21
+}
+
+// freeDouble reports whether the house pays for doubling this hand: Free Bet doubles
+// are free on a two-card hard 9, 10 or 11.
+func (b *BlackJackInstance) freeDouble(h *Hand) bool {
+	if b.rules.Variant != FreeBet || len(h.Cards) != 2 || b.isSoftHand(h.Cards) {
+		return false
+	}
+	value := b.calculateHandValue(h.Cards)
+	return value >= 9 && value <= 11
+}
+
+// freeSplit reports whether the house pays for splitting this hand: Free Bet splits
+// are free on every pair but ten-value cards.
+func (b *BlackJackInstance) freeSplit(h *Hand) bool {
+	return b.rules.Variant == FreeBet && cardValue(h.Cards[0]) != 10
+}
+
+// spanishWinnings returns what a Spanish 21 player 21 wins on top of the returned bet.
+// Doubled hands are paid even money. Otherwise a 21 made with five cards pays 3:2,
+// six cards 2:1 and seven or more 3:1, and a three-card 6-7-8 or 7-7-7 pays 3:2
+// in mixed suits, 2:1 suited and 3:1 in spades.
+func spanishWinnings(h *Hand) int {
+	bet := h.Bet
+	if h.Doubled {
+		return bet
+	}
+	switch n := len(h.Cards); {
+	case n >= 7:
+		return bet * 3
+	case n == 6:
+		return bet * 2
+	case n == 5:
+		return bet * 3 / 2
+	case n == 3 && isSpanishTrips(h.Cards):
+		suit := h.Cards[0].Suit
+		suited := h.Cards[1].Suit == suit && h.Cards[2].Suit == suit
+		switch {
+		case suited && suit == carddeck.Spades:
+			return bet * 3
+		case suited:
+			return bet * 2
+		default:
+			return bet * 3 / 2
+		}
+	}
+	return bet
+}
+
+// isSpanishTrips reports whether three cards are 6-7-8 in any order or 7-7-7.
+func isSpanishTrips(cards []carddeck.Card) bool {
+	seen := map[carddeck.Rank]int{}
+	for _, c := range cards {
+		seen[c.Value]++
+	}
+	return seen[carddeck.Seven] == 3 ||
+		(seen[carddeck.Six] == 1 && seen[carddeck.Seven] == 1 && seen[carddeck.Eight] == 1)
+}
+
+// settleHand decides a finished hand against the dealer. It returns the hand's result
+// and the chips handed back to the player, stake included. Free chips the house put
+// up for a Free Bet hand are never handed back, the player only keeps what they win.
+//
+// The dealer peeks for blackjack before anyone acts, so surrender is late and hands are
+// not normally doubled or split against a dealer blackjack. Should one be, only the
+// original bet is lost: doubles and split-off hands are handed back, and surrendering
+// or rescuing the hand saves nothing.
+func (b *BlackJackInstance) settleHand(h *Hand) (PlayerStatus, int) {
+	variant := b.rules.Variant
+	dealerValue := b.calculateHandValue(b.DealerHand)
+	dealerBlackjack := len(b.DealerHand) == 2 && dealerValue == 21
+
+	if dealerBlackjack && !b.isNatural(h) {
+		if h.SplitOff {
+			return PlayerStatusPush, h.Bet
+		}
+		return PlayerStatusLost, h.DoubleBet
+	}
+
+	if h.Status == PlayerStatusSurrendered {
+		if h.Doubled {
+			// Double-down rescue: the original bet is forfeit, the double comes back
+			return PlayerStatusSurrendered, h.DoubleBet
+		}
+		return PlayerStatusSurrendered, h.Bet / 2
+	}
+	if h.Status == PlayerStatusBusted {
+		return PlayerStatusLost, 0
+	}
+
+	value := b.calculateHandValue(h.Cards)
+	if b.isNatural(h) {
+		// Both have blackjack - push, except in Spanish 21 where the player's blackjack wins
+		if dealerBlackjack && variant != Spanish21 {
+			return PlayerStatusPush, h.Bet
+		}
+		return PlayerStatusBlackjack, h.Bet + b.rules.blackjackWinnings(h.Bet)
+	}
+
+	// A Spanish 21 player 21 wins whatever the dealer has, with the bonus payouts
+	if variant == Spanish21 && value == 21 {
+		return PlayerStatusWon, h.Bet + spanishWinnings(h)
+	}
+	// A Free Bet dealer 22 pushes every hand still standing
+	if variant == FreeBet && dealerValue == 22 {
+		return PlayerStatusPush, h.Bet
+	}
+
+	// Winning hands are paid on the free chips too
+	win := h.Bet*2 + h.FreeBet
+	switch {
+	case dealerValue > 21, value > dealerValue:
+		return PlayerStatusWon, win
+	case value == dealerValue:
+		return PlayerStatusPush, h.Bet
+	default:
+		return PlayerStatusLost, 0
+	}
+}
@@ -0,0 +1,157 @@
+package blackjack
+
+import (
+	carddeck "cardgames/backend/libraries/cardDeck"
+	"cardgames/backend/models"
+	"strings"
+	"testing"
+)
+
+// cards parses a space separated list of card codes, e.g. "AH 10S 2C".
+func cards(t testing.TB, s string) []carddeck.Card {
+	t.Helper()
+	var hand []carddeck.Card
+	for _, code := range strings.Fields(s) {
+		c, err := carddeck.ParseCard(code)
+		if err != nil {
+			t.Fatalf("parsing %q: %v", code, err)
+		}
+		hand = append(hand, c)
+	}
+	return hand
+}
+
+// table returns an instance playing variant v with the dealer holding dealer.
+func table(t testing.TB, v Variant, dealer string) *BlackJackInstance {
+	t.Helper()
+	return &BlackJackInstance{
+		rules:      DefaultRulesFor(v),
+		wallet:     NewPlayMoneyWallet(PracticeStack),
+		DealerHand: cards(t, dealer),
+	}
+}
+
+func TestSettleHand(t *testing.T) {
+	tests := []struct {
+		name    string
+		variant Variant
+		dealer  string
+		hand    Hand
+		cards   string
+		status  PlayerStatus
+		paid    int
+	}{
+		{"win", Classic, "10H 8D", Hand{Bet: 10, Status: PlayerStatusStand}, "10C 9D", PlayerStatusWon, 20},
+		{"push", Classic, "10H 8D", Hand{Bet: 10, Status: PlayerStatusStand}, "10C 8S", PlayerStatusPush, 10},
+		{"blackjack", Classic, "10H 8D", Hand{Bet: 10, Status: PlayerStatusBlackjack}, "AC KD", PlayerStatusBlackjack, 25},
+		{"blackjacks push", Classic, "AH QD", Hand{Bet: 10, Status: PlayerStatusBlackjack}, "AC KD", PlayerStatusPush, 10},
+		{"split 21 is no blackjack", Classic, "10H 8D", Hand{Bet: 10, FromSplit: true, Status: PlayerStatusStand}, "AC KD", PlayerStatusWon, 20},
+		{"double against dealer blackjack loses the bet only", Classic, "AH QD", Hand{Bet: 20, DoubleBet: 10, Doubled: true, Status: PlayerStatusStand}, "5C 6D 10S", PlayerStatusLost, 10},
+		{"split against dealer blackjack loses the bet only", Classic, "AH QD", Hand{Bet: 10, FromSplit: true, SplitOff: true, Status: PlayerStatusStand}, "8C 3D", PlayerStatusPush, 10},
+
+		{"spanish 5-card 21", Spanish21, "10H 8D", Hand{Bet: 10, Status: PlayerStatusStand}, "2H 3D 4C 5S 7H", PlayerStatusWon, 25},
+		{"spanish 6-card 21", Spanish21, "10H 8D", Hand{Bet: 10, Status: PlayerStatusStand}, "2H 3D 4C 5S 3H 4D", PlayerStatusWon, 30},
+		{"spanish mixed 6-7-8", Spanish21, "10H 8D", Hand{Bet: 10, Status: PlayerStatusStand}, "6H 7D 8C", PlayerStatusWon, 25},
+		{"spanish suited 6-7-8", Spanish21, "10H 8D", Hand{Bet: 10, Status: PlayerStatusStand}, "8H 6H 7H", PlayerStatusWon, 30},
+		{"spanish spade 7-7-7", Spanish21, "10H 8D", Hand{Bet: 10, Status: PlayerStatusStand}, "7S 7S 7S", PlayerStatusWon, 40},
+		{"spanish doubled 21 pays even money", Spanish21, "10H 8D", Hand{Bet: 20, DoubleBet: 10, Doubled: true, Status: PlayerStatusStand}, "2H 3D 4C 5S 7H", PlayerStatusWon, 40},
+		{"spanish 21 beats dealer 21", Spanish21, "9H 5D 7C", Hand{Bet: 10, Status: PlayerStatusStand}, "9H 5D 7S", PlayerStatusWon, 20},
+		{"spanish blackjack beats dealer blackjack", Spanish21, "AH QD", Hand{Bet: 10, Status: PlayerStatusBlackjack}, "AC KD", PlayerStatusBlackjack, 25},
+		{"spanish 21 loses to dealer blackjack", Spanish21, "AH QD", Hand{Bet: 10, Status: PlayerStatusStand}, "7S 7S 7S", PlayerStatusLost, 0},
+		{"spanish surrender", Spanish21, "10H 8D", Hand{Bet: 10, Status: PlayerStatusSurrendered}, "10C 6D", PlayerStatusSurrendered, 5},
+		{"spanish rescue", Spanish21, "10H 8D", Hand{Bet: 20, DoubleBet: 10, Doubled: true, Status: PlayerStatusSurrendered}, "5C 6D 2S", PlayerStatusSurrendered, 10},
+		{"spanish surrender against dealer blackjack", Spanish21, "AH QD", Hand{Bet: 10, Status: PlayerStatusSurrendered}, "10C 6D", PlayerStatusLost, 0},
+		{"spanish rescue against dealer blackjack", Spanish21, "AH QD", Hand{Bet: 20, DoubleBet: 10, Doubled: true, Status: PlayerStatusSurrendered}, "5C 6D 2S", PlayerStatusLost, 10},
+
+		{"free bet dealer 22 pushes", FreeBet, "10H 6D 6C", Hand{Bet: 10, Status: PlayerStatusStand}, "10C 9D", PlayerStatusPush, 10},
+		{"free bet dealer 22 pushes a free double", FreeBet, "10H 6D 6C", Hand{Bet: 10, FreeBet: 10, Doubled: true, Status: PlayerStatusStand}, "5C 6D 9S", PlayerStatusPush, 10},
+		{"free bet dealer 22 does not save a bust", FreeBet, "10H 6D 6C", Hand{Bet: 10, Status: PlayerStatusBusted}, "10C 6D 8S", PlayerStatusLost, 0},
+		{"free bet blackjack beats dealer 22", FreeBet, "10H 6D 6C", Hand{Bet: 10, Status: PlayerStatusBlackjack}, "AC KD", PlayerStatusBlackjack, 25},
+		{"free bet dealer 23 busts", FreeBet, "10H 6D 7C", Hand{Bet: 10, Status: PlayerStatusStand}, "10C 9D", PlayerStatusWon, 20},
+		{"free bet free double wins on the free chips", FreeBet, "10H 8D", Hand{Bet: 10, FreeBet: 10, Doubled: true, Status: PlayerStatusStand}, "5C 6D 9S", PlayerStatusWon, 30},
+		{"free bet free split wins the free chips", FreeBet, "10H 8D", Hand{FreeBet: 10, FromSplit: true, SplitOff: true, Status: PlayerStatusStand}, "8C AD", PlayerStatusWon, 10},
+		{"free bet free double against dealer blackjack", FreeBet, "AH QD", Hand{Bet: 10, FreeBet: 10, Doubled: true, Status: PlayerStatusStand}, "5C 6D 9S", PlayerStatusLost, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := table(t, tt.variant, tt.dealer)
+			h := tt.hand
+			h.Cards = cards(t, tt.cards)
+			status, paid := b.settleHand(&h)
+			if status != tt.status || paid != tt.paid {
+				t.Errorf("settleHand = %s %d, want %s %d", status, paid, tt.status, tt.paid)
+			}
+		})
+	}
+}
+
+func TestFreeSplitThenFreeDouble(t *testing.T) {
+	b := table(t, FreeBet, "10H 8D")
+	b.Deck = carddeck.Deck(cards(t, "3C AS 10D"))
+	p := &Player{ID: 1, Bet: 10, Wager: &models.Wager{}}
+	p.Hands = []*Hand{{Cards: cards(t, "8H 8D"), Bet: 10, Status: PlayerStatusPlaying}}
+	b.wallet.Debit(p, p.Bet)
+
+	if !b.split(p, p.Hands[0]) {
+		t.Fatal("split refused")
+	}
+	first, second := p.Hands[0], p.Hands[1]
+	if second.Bet != 0 || second.FreeBet != 10 {
+		t.Errorf("split hand bet = %d free %d, want 0 free 10", second.Bet, second.FreeBet)
+	}
+	if !b.double(p, first) {
+		t.Fatal("double refused")
+	}
+	if first.Bet != 10 || first.FreeBet != 10 || first.DoubleBet != 0 {
+		t.Errorf("doubled hand bet = %d free %d double %d, want 10 free 10 double 0", first.Bet, first.FreeBet, first.DoubleBet)
+	}
+	if got := b.wallet.Balance(p); got != PracticeStack-10 {
+		t.Errorf("balance = %d, want %d, free moves are paid by the house", got, PracticeStack-10)
+	}
+	if p.Wager.WagerAmount != 10 {
+		t.Errorf("wager = %d, want 10", p.Wager.WagerAmount)
+	}
+
+	// 8-3-10 is 21 and 8-A is 19 against the dealer's 18
+	if status, paid := b.settleHand(first); status != PlayerStatusWon || paid != 30 {
+		t.Errorf("doubled hand = %s %d, want won 30", status, paid)
+	}
+	if status, paid := b.settleHand(second); status != PlayerStatusWon || paid != 10 {
+		t.Errorf("split hand = %s %d, want won 10", status, paid)
+	}
+
+	// Against a dealer 22 both hands push, only the player's own chips come back
+	b.DealerHand = cards(t, "10S 6D 6C")
+	if status, paid := b.settleHand(first); status != PlayerStatusPush || paid != 10 {
+		t.Errorf("doubled hand against 22 = %s %d, want push 10", status, paid)
+	}
+	if status, paid := b.settleHand(second); status != PlayerStatusPush || paid != 0 {
+		t.Errorf("split hand against 22 = %s %d, want push 0", status, paid)
+	}
+}
+
+func TestSpanishRescue(t *testing.T) {
+	b := table(t, Spanish21, "10H 8D")
+	b.Deck = carddeck.Deck(cards(t, "2S"))
+	p := &Player{ID: 1, Bet: 10, Wager: &models.Wager{}}
+	p.Hands = []*Hand{{Cards: cards(t, "5C 6D"), Bet: 10, Status: PlayerStatusPlaying}}
+	b.wallet.Debit(p, p.Bet)
+
+	if !b.double(p, p.Hands[0]) {
+		t.Fatal("double refused")
+	}
+	h := p.Hands[0]
+	if h.Status != PlayerStatusPlaying {
+		t.Fatalf("doubled hand status = %s, want playing, Spanish 21 may rescue it", h.Status)
+	}
+	if !b.surrender(p, h) {
+		t.Fatal("rescue refused")
+	}
+	if status, paid := b.settleHand(h); status != PlayerStatusSurrendered || paid != 10 {
+		t.Errorf("rescued hand = %s %d, want surrendered 10", status, paid)
+	}
+	if got := b.wallet.Balance(p); got != PracticeStack-20 {
+		t.Errorf("balance = %d, want %d", got, PracticeStack-20)
+	}
+}
@@ -0,0 +1,150 @@
+package blackjack
+
+// wallet.go
+// This file defines where a table's chips come from and where the results of a hand are written.
+// Regular tables play with the player's account balance, while other table types
+// (practice tables, tournaments) provide their own Wallet implementation.
+
+import (
+	"cardgames/backend/models"
+	"log"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// PracticeStack is the play-money stack a player gets (and refills to) at a practice table.
+const PracticeStack = 1000
+
+// Wallet abstracts the chip stack a player bets with at a table.
+// Implementations must be safe to call from the game loop and from the goroutines
+// that build state updates for newly connected players.
+type Wallet interface {
+	// Balance returns the chips the player currently has available to bet.
+	Balance(p *Player) int
+	// Debit removes chips from the player's stack. It returns false, taking nothing,
+	// if the stack does not cover amount.
+	Debit(p *Player, amount int) bool
+	// Credit adds chips to the player's stack.
+	Credit(p *Player, amount int)
+	// RecordWager stores the outcome of the player's wager once it has been settled.
+	RecordWager(p *Player)
+}
+
+// accountWallet is the default wallet. It plays with models.Account.Balance and
+// records every settled hand as a models.Wager row. The balance is changed in the
+// database in place, other games may be moving the same player's chips, and the
+// player's Account is only updated to match afterwards.
+type accountWallet struct {
+	db *gorm.DB
+}
+
+// NewAccountWallet returns a Wallet backed by the players' account balances.
+func NewAccountWallet(db *gorm.DB) Wallet {
+	return &accountWallet{db: db}
+}
+
+func (w *accountWallet) Balance(p *Player) int {
+	return p.Account.Balance
+}
+
+func (w *accountWallet) Debit(p *Player, amount int) bool {
+	// Only take the chips if the balance still covers them
+	res := w.db.Model(&models.Account{}).
+		Where("id = ? AND balance >= ?", p.ID, amount).
+		Update("balance", gorm.Expr("balance - ?", amount))
+	if res.Error != nil || res.RowsAffected == 0 {
+		return false
+	}
+	p.Account.Balance -= amount
+	return true
+}
+
+func (w *accountWallet) Credit(p *Player, amount int) {
+	err := w.db.Model(&models.Account{}).
+		Where("id = ?", p.ID).
+		Update("balance", gorm.Expr("balance + ?", amount)).Error
+	if err != nil {
+		log.Println("Failed to credit", amount, "chips to player", p.ID, ":", err)
+		return
+	}
+	p.Account.Balance += amount
+}
+
+func (w *accountWallet) RecordWager(p *Player) {
+	w.db.Save(p.Wager)
+}
+
+// Refiller is implemented by wallets whose stacks can be topped back up by the player.
+type Refiller interface {
+	// Refill resets the player's stack and reports whether anything changed.
+	Refill(p *Player) bool
+}
+
+// playMoneyWallet gives every player a refillable play-money stack for as long as
+// the table exists. It never touches the database, so practice hands have no effect
+// on account balances, wager history or leaderboards.
+type playMoneyWallet struct {
+	mu     sync.Mutex
+	stack  int
+	stacks map[uint]int
+}
+
+// NewPlayMoneyWallet returns a Wallet that starts every player with stack play-money chips.
+func NewPlayMoneyWallet(stack int) Wallet {
+	return &playMoneyWallet{stack: stack, stacks: make(map[uint]int)}
+}
+
+func (w *playMoneyWallet) Balance(p *Player) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.balance(p.ID)
+}
+
+func (w *playMoneyWallet) Debit(p *Player, amount int) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.balance(p.ID) < amount {
+		return false
+	}
+	w.stacks[p.ID] = w.balance(p.ID) - amount
+	return true
+}
+
+func (w *playMoneyWallet) Credit(p *Player, amount int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stacks[p.ID] = w.balance(p.ID) + amount
+}
+
+func (w *playMoneyWallet) RecordWager(p *Player) {}
+
+// Refill tops the player's stack back up to the starting amount. Players who are
+// already at or above it are left alone.
+func (w *playMoneyWallet) Refill(p *Player) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.balance(p.ID) >= w.stack {
+		return false
+	}
+	w.stacks[p.ID] = w.stack
+	return true
+}
+
+// setStack sets the player's stack, used when a table is restored.
+func (w *playMoneyWallet) setStack(playerID uint, stack int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stacks[playerID] = stack
+}
+
+// balance returns the player's stack, handing out a fresh one on first use.
+// Must be called with w.mu held.
+func (w *playMoneyWallet) balance(playerID uint) int {
+	bal, ok := w.stacks[playerID]
+	if !ok {
+		bal = w.stack
+		w.stacks[playerID] = bal
+	}
+	return bal
+}
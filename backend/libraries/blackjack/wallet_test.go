@@ -0,0 +1,34 @@
+package blackjack
+
+import (
+	"cardgames/backend/models"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestAccountWalletKeepsOtherChanges(t *testing.T) {
+	db := testDB(t, 1)
+	db.Model(&models.Account{}).Where("id = ?", 1).Update("balance", 100)
+
+	var account models.Account
+	db.First(&account, 1)
+	p := &Player{ID: 1, Account: &account}
+	w := NewAccountWallet(db)
+
+	// Another game takes chips from the same account while the player is seated
+	db.Model(&models.Account{}).Where("id = ?", 1).Update("balance", gorm.Expr("balance - ?", 80))
+
+	if w.Debit(p, 50) {
+		t.Error("Debit of 50 from a balance of 20 succeeded")
+	}
+	if !w.Debit(p, 20) {
+		t.Error("Debit of 20 from a balance of 20 failed")
+	}
+	w.Credit(p, 30)
+
+	db.First(&account, 1)
+	if account.Balance != 30 {
+		t.Errorf("balance = %d, want 30", account.Balance)
+	}
+}
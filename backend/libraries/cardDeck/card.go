@@ -0,0 +1,228 @@
+package carddeck
+
+// card.go
+// This file contains the typed card model: ranks and suits, the hidden card sent in
+// place of a face down card, jokers, and parsing and formatting of cards as short
+// codes ("AH", "10S"), suit symbols ("A♥") and Unicode playing card characters.
+// Ranks and suits are strings underneath so cards encode to the same JSON as before,
+// e.g. {"Suit":"H","Value":"A"}.
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Suit is the suit of a card.
+type Suit string
+
+const (
+	Hearts   Suit = "H"
+	Diamonds Suit = "D"
+	Clubs    Suit = "C"
+	Spades   Suit = "S"
+
+	NoSuit     Suit = ""  // jokers have no suit
+	HiddenSuit Suit = "0" // suit of the hidden card
+)
+
+// Suits lists the four suits in the order decks are built.
+var Suits = []Suit{Hearts, Diamonds, Clubs, Spades}
+
+// suitSymbols are the symbols of the suits.
+var suitSymbols = map[Suit]string{Hearts: "♥", Diamonds: "♦", Clubs: "♣", Spades: "♠"}
+
+// Valid reports whether s is one of the four suits.
+func (s Suit) Valid() bool {
+	_, ok := suitSymbols[s]
+	return ok
+}
+
+// Red reports whether s is hearts or diamonds.
+func (s Suit) Red() bool {
+	return s == Hearts || s == Diamonds
+}
+
+// Symbol returns the suit symbol, e.g. "♥".
+func (s Suit) Symbol() string {
+	return suitSymbols[s]
+}
+
+// Rank is the rank of a card. The field holding it is still called Value.
+type Rank string
+
+const (
+	Two   Rank = "2"
+	Three Rank = "3"
+	Four  Rank = "4"
+	Five  Rank = "5"
+	Six   Rank = "6"
+	Seven Rank = "7"
+	Eight Rank = "8"
+	Nine  Rank = "9"
+	Ten   Rank = "10"
+	Jack  Rank = "J"
+	Queen Rank = "Q"
+	King  Rank = "K"
+	Ace   Rank = "A"
+
+	JokerRank  Rank = "JK" // rank of a joker
+	HiddenRank Rank = "0"  // rank of the hidden card
+)
+
+// Ranks lists the thirteen ranks from two up to ace, the order decks are built in.
+var Ranks = []Rank{Two, Three, Four, Five, Six, Seven, Eight, Nine, Ten, Jack, Queen, King, Ace}
+
+// rankNumbers are the ranks counted from 1 for an ace up to 13 for a king.
+var rankNumbers = map[Rank]int{
+	Ace: 1, Two: 2, Three: 3, Four: 4, Five: 5, Six: 6, Seven: 7,
+	Eight: 8, Nine: 9, Ten: 10, Jack: 11, Queen: 12, King: 13,
+}
+
+// Valid reports whether r is one of the thirteen ranks.
+func (r Rank) Valid() bool {
+	_, ok := rankNumbers[r]
+	return ok
+}
+
+// Number returns the rank counted from 1 for an ace up to 13 for a king, or 0 for a
+// joker or the hidden card.
+func (r Rank) Number() int {
+	return rankNumbers[r]
+}
+
+// High returns the rank counted from 2 up to 14 for an ace, for games where aces are
+// high, or 0 for a joker or the hidden card.
+func (r Rank) High() int {
+	if r == Ace {
+		return 14
+	}
+	return rankNumbers[r]
+}
+
+// IsFace reports whether r is a jack, queen or king.
+func (r Rank) IsFace() bool {
+	return r == Jack || r == Queen || r == King
+}
+
+// Hidden stands in for a card the player is not allowed to see.
+var Hidden = Card{Suit: HiddenSuit, Value: HiddenRank}
+
+// Joker is a joker. Decks built with jokers hold several copies of it.
+var Joker = Card{Suit: NoSuit, Value: JokerRank}
+
+// IsHidden reports whether c is the hidden card.
+func (c Card) IsHidden() bool {
+	return c == Hidden
+}
+
+// IsJoker reports whether c is a joker.
+func (c Card) IsJoker() bool {
+	return c.Value == JokerRank
+}
+
+// Valid reports whether c is a regular playing card, a joker or the hidden card.
+func (c Card) Valid() bool {
+	return (c.Value.Valid() && c.Suit.Valid()) || c.IsJoker() || c.IsHidden()
+}
+
+// String returns the short code of the card, e.g. "AH" or "10S". Jokers are "JK" and
+// the hidden card "??".
+func (c Card) String() string {
+	switch {
+	case c.IsHidden():
+		return "??"
+	case c.IsJoker():
+		return string(JokerRank)
+	}
+	return string(c.Value) + string(c.Suit)
+}
+
+// Symbol returns the card with its suit symbol, e.g. "A♥".
+func (c Card) Symbol() string {
+	if !c.Suit.Valid() {
+		return c.String()
+	}
+	return string(c.Value) + c.Suit.Symbol()
+}
+
+// Unicode playing card code points.
+const (
+	unicodeBack  = 0x1F0A0 // back of a card, used for the hidden card
+	unicodeJoker = 0x1F0CF
+)
+
+// unicodeSuitBase is the code point before the ace of each suit in the Unicode
+// playing cards block.
+var unicodeSuitBase = map[Suit]rune{Spades: 0x1F0A0, Hearts: 0x1F0B0, Diamonds: 0x1F0C0, Clubs: 0x1F0D0}
+
+// Unicode returns the Unicode playing card character for the card, e.g. "🂱" for the
+// ace of hearts.
+func (c Card) Unicode() string {
+	switch {
+	case c.IsHidden():
+		return string(rune(unicodeBack))
+	case c.IsJoker():
+		return string(rune(unicodeJoker))
+	case !c.Valid():
+		return ""
+	}
+	n := c.Value.Number()
+	if n >= 12 {
+		n++ // the block has a knight between the jack and the queen
+	}
+	return string(unicodeSuitBase[c.Suit] + rune(n))
+}
+
+// ParseCard parses a card written as a short code ("AH", "10S", "TS" for a ten), with a
+// suit symbol ("A♥") or as a Unicode playing card character. "JK" and the Unicode joker
+// parse as a joker, and "??" as the hidden card.
+func ParseCard(s string) (Card, error) {
+	s = strings.TrimSpace(s)
+	switch strings.ToUpper(s) {
+	case "JK", string(rune(unicodeJoker)):
+		return Joker, nil
+	case "??", string(rune(unicodeBack)):
+		return Hidden, nil
+	}
+
+	if r := []rune(s); len(r) == 1 {
+		return parseUnicode(r[0])
+	}
+
+	for suit, symbol := range suitSymbols {
+		if strings.HasSuffix(s, symbol) {
+			s = strings.TrimSuffix(s, symbol) + string(suit)
+		}
+	}
+	s = strings.ToUpper(s)
+	if len(s) < 2 {
+		return Card{}, fmt.Errorf("invalid card %q", s)
+	}
+	rank, suit := Rank(s[:len(s)-1]), Suit(s[len(s)-1:])
+	if rank == "T" || rank == "1" {
+		rank = Ten
+	}
+	if !rank.Valid() || !suit.Valid() {
+		return Card{}, fmt.Errorf("invalid card %q", s)
+	}
+	return Card{Suit: suit, Value: rank}, nil
+}
+
+// parseUnicode parses a single Unicode playing card character.
+func parseUnicode(r rune) (Card, error) {
+	for suit, base := range unicodeSuitBase {
+		n := int(r - base)
+		if n < 1 || n > 14 || n == 12 {
+			continue
+		}
+		if n > 12 {
+			n-- // skip the knight
+		}
+		for rank, number := range rankNumbers {
+			if number == n {
+				return Card{Suit: suit, Value: rank}, nil
+			}
+		}
+	}
+	return Card{}, fmt.Errorf("invalid card %q", string(r))
+}
@@ -0,0 +1,40 @@
+package carddeck
+
+// count.go
+// This file contains helpers for inspecting a shoe: Hi-Lo card counting values,
+// the number of decks left and a breakdown of the cards that remain.
+
+// HiLo returns the Hi-Lo counting value of a card: +1 for 2-6, 0 for 7-9 and -1 for tens and aces.
+func HiLo(c Card) int {
+	switch c.Value {
+	case Two, Three, Four, Five, Six:
+		return 1
+	case Ten, Jack, Queen, King, Ace:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// HiLoTotal returns the sum of the Hi-Lo values of every card in the deck.
+func (d Deck) HiLoTotal() int {
+	total := 0
+	for _, c := range d {
+		total += HiLo(c)
+	}
+	return total
+}
+
+// DecksRemaining returns how many 52-card decks are left in the shoe.
+func (d Deck) DecksRemaining() float64 {
+	return float64(len(d)) / 52
+}
+
+// Composition returns how many cards of each rank are left in the deck.
+func (d Deck) Composition() map[Rank]int {
+	counts := make(map[Rank]int)
+	for _, c := range d {
+		counts[c.Value]++
+	}
+	return counts
+}
@@ -16,8 +16,8 @@ import (
 
 // Card represents a single playing card.
 type Card struct {
-	Suit  string
-	Value string
+	Suit  Suit
+	Value Rank
 }
 
 // Deck represents a deck of cards.
@@ -30,16 +30,50 @@ func New() Deck {
 
 // NewDeck creates a deck with the specified number of standard 52-card decks.
 func NewDeck(numDecks int) Deck {
-	suits := []string{"H", "D", "C", "S"}
-	values := []string{"2", "3", "4", "5", "6", "7", "8", "9", "10", "J", "Q", "K", "A"}
+	return StandardDeck.Build(numDecks)
+}
+
+// DeckSpec describes the cards in one deck of a variant: every rank in every suit,
+// plus a number of jokers.
+type DeckSpec struct {
+	Ranks  []Rank
+	Suits  []Suit
+	Jokers int
+}
+
+// Deck specs for the variant decks.
+var (
+	// StandardDeck is the 52-card deck.
+	StandardDeck = DeckSpec{Ranks: Ranks, Suits: Suits}
+	// SpanishDeck is the 48-card deck used by Spanish 21: a standard deck without the tens.
+	SpanishDeck = DeckSpec{Ranks: []Rank{Two, Three, Four, Five, Six, Seven, Eight, Nine, Jack, Queen, King, Ace}, Suits: Suits}
+	// PiquetDeck is the 32-card deck of sevens up to aces.
+	PiquetDeck = DeckSpec{Ranks: []Rank{Seven, Eight, Nine, Ten, Jack, Queen, King, Ace}, Suits: Suits}
+)
 
-	deck := make(Deck, 0, len(suits)*len(values)*numDecks)
+// WithJokers returns a copy of the spec with n jokers in each deck.
+func (s DeckSpec) WithJokers(n int) DeckSpec {
+	s.Jokers = n
+	return s
+}
+
+// Size returns the number of cards in one deck of the spec.
+func (s DeckSpec) Size() int {
+	return len(s.Ranks)*len(s.Suits) + s.Jokers
+}
+
+// Build creates a deck holding numDecks decks of the spec, unshuffled.
+func (s DeckSpec) Build(numDecks int) Deck {
+	deck := make(Deck, 0, s.Size()*numDecks)
 	for i := 0; i < numDecks; i++ {
-		for _, suit := range suits {
-			for _, value := range values {
-				deck = append(deck, Card{Suit: suit, Value: value})
+		for _, suit := range s.Suits {
+			for _, rank := range s.Ranks {
+				deck = append(deck, Card{Suit: suit, Value: rank})
 			}
 		}
+		for j := 0; j < s.Jokers; j++ {
+			deck = append(deck, Joker)
+		}
 	}
 	return deck
 }
@@ -0,0 +1,299 @@
+package carddeck
+
+// poker.go
+// This file contains a poker hand evaluator that finds the best 5 card hand in 5 to 7 cards.
+// It works on bit masks of the ranks held in each suit instead of trying every 5 card
+// combination, so scoring a hand does not allocate and takes well under a microsecond.
+
+// HandCategory is the class of a poker hand, from high card up to straight flush.
+type HandCategory uint8
+
+const (
+	HighCard HandCategory = iota
+	OnePair
+	TwoPair
+	ThreeOfAKind
+	Straight
+	Flush
+	FullHouse
+	FourOfAKind
+	StraightFlush
+)
+
+// categoryNames are the display names of the hand categories.
+var categoryNames = [...]string{
+	HighCard:      "High Card",
+	OnePair:       "Pair",
+	TwoPair:       "Two Pair",
+	ThreeOfAKind:  "Three of a Kind",
+	Straight:      "Straight",
+	Flush:         "Flush",
+	FullHouse:     "Full House",
+	FourOfAKind:   "Four of a Kind",
+	StraightFlush: "Straight Flush",
+}
+
+func (c HandCategory) String() string {
+	if int(c) < len(categoryNames) {
+		return categoryNames[c]
+	}
+	return "Unknown"
+}
+
+// HandScore is the strength of a poker hand. Scores compare directly: a higher score
+// always beats a lower one and equal scores tie. The category is kept in the high bits
+// and the ranks that break ties below it, most significant first, 4 bits each.
+type HandScore uint32
+
+// Category returns the class of the hand.
+func (s HandScore) Category() HandCategory {
+	return HandCategory(s >> 20)
+}
+
+// PokerHand is the best 5 card hand found in a set of cards.
+type PokerHand struct {
+	Category HandCategory
+	Score    HandScore
+	Cards    []Card // the 5 cards that make the hand, most important first
+}
+
+// rankIndex returns the rank of a card from 0 for a two up to 12 for an ace, or -1 if
+// the card is not a regular playing card.
+func rankIndex(c Card) int {
+	if c.Value == "" || c.Value == JokerRank {
+		return -1
+	}
+	switch v := c.Value[0]; {
+	case v >= '2' && v <= '9':
+		return int(v - '2')
+	case v == '1':
+		return 8 // "10"
+	case v == 'J':
+		return 9
+	case v == 'Q':
+		return 10
+	case v == 'K':
+		return 11
+	case v == 'A':
+		return 12
+	}
+	return -1
+}
+
+// suitIndex returns the suit of a card from 0 to 3, or -1 if it has no regular suit.
+func suitIndex(c Card) int {
+	if c.Suit == "" {
+		return -1
+	}
+	switch c.Suit[0] {
+	case 'H':
+		return 0
+	case 'D':
+		return 1
+	case 'C':
+		return 2
+	case 'S':
+		return 3
+	}
+	return -1
+}
+
+// handShape is what the evaluator works out before picking the actual cards:
+// the score, the rank of each of the 5 cards and the suit they must share, if any.
+type handShape struct {
+	score HandScore
+	ranks [5]int8
+	suit  int8 // -1 unless the hand is a flush or straight flush
+}
+
+// PokerScore returns the score of the best 5 card hand in cards. It does not allocate,
+// use it when only the comparison matters, e.g. in simulations.
+func PokerScore(cards []Card) HandScore {
+	return evaluatePoker(cards).score
+}
+
+// EvaluatePoker returns the best 5 card hand in cards, which should hold 5 to 7 cards.
+// Cards that are not regular playing cards are ignored.
+func EvaluatePoker(cards []Card) PokerHand {
+	shape := evaluatePoker(cards)
+	hand := PokerHand{Category: shape.score.Category(), Score: shape.score, Cards: make([]Card, 0, 5)}
+
+	used := make([]bool, len(cards))
+	for _, r := range shape.ranks {
+		if r < 0 {
+			break
+		}
+		for i, c := range cards {
+			if !used[i] && rankIndex(c) == int(r) && (shape.suit < 0 || suitIndex(c) == int(shape.suit)) {
+				used[i] = true
+				hand.Cards = append(hand.Cards, c)
+				break
+			}
+		}
+	}
+	return hand
+}
+
+// evaluatePoker works out the category, score and card ranks of the best hand.
+func evaluatePoker(cards []Card) handShape {
+	var suitMask [4]uint16
+	var suitCount [4]uint8
+	var counts [13]uint8
+	var all uint16
+	for _, c := range cards {
+		r, s := rankIndex(c), suitIndex(c)
+		if r < 0 || s < 0 {
+			continue
+		}
+		suitMask[s] |= 1 << r
+		suitCount[s]++
+		counts[r]++
+		all |= 1 << r
+	}
+
+	shape := handShape{suit: -1, ranks: [5]int8{-1, -1, -1, -1, -1}}
+
+	flushSuit := -1
+	for s := 0; s < 4; s++ {
+		if suitCount[s] >= 5 {
+			flushSuit = s
+		}
+	}
+	if flushSuit >= 0 {
+		if high := straightHigh(suitMask[flushSuit]); high >= 0 {
+			shape.suit = int8(flushSuit)
+			shape.setStraight(StraightFlush, high)
+			return shape
+		}
+	}
+
+	// Group ranks by how many of each are held, highest rank first
+	quad, trips1, trips2, pair1, pair2, pair3 := -1, -1, -1, -1, -1, -1
+	for r := 12; r >= 0; r-- {
+		switch counts[r] {
+		case 4:
+			quad = r
+		case 3:
+			if trips1 < 0 {
+				trips1 = r
+			} else if trips2 < 0 {
+				trips2 = r
+			}
+		case 2:
+			if pair1 < 0 {
+				pair1 = r
+			} else if pair2 < 0 {
+				pair2 = r
+			} else if pair3 < 0 {
+				pair3 = r
+			}
+		}
+	}
+
+	switch {
+	case quad >= 0:
+		shape.set(FourOfAKind, []int{quad, quad, quad, quad}, kickers(all, 1, quad, -1))
+	case trips1 >= 0 && (trips2 >= 0 || pair1 >= 0):
+		pair := max(trips2, pair1)
+		shape.set(FullHouse, []int{trips1, trips1, trips1, pair, pair}, noKickers)
+	case flushSuit >= 0:
+		shape.suit = int8(flushSuit)
+		shape.set(Flush, nil, kickers(suitMask[flushSuit], 5, -1, -1))
+	case straightHigh(all) >= 0:
+		shape.setStraight(Straight, straightHigh(all))
+	case trips1 >= 0:
+		shape.set(ThreeOfAKind, []int{trips1, trips1, trips1}, kickers(all, 2, trips1, -1))
+	case pair2 >= 0:
+		// With three pairs the third one can only play as the kicker
+		kicker := kickers(all, 1, pair1, pair2)
+		if pair3 > kicker[0] {
+			kicker[0] = pair3
+		}
+		shape.set(TwoPair, []int{pair1, pair1, pair2, pair2}, kicker)
+	case pair1 >= 0:
+		shape.set(OnePair, []int{pair1, pair1}, kickers(all, 3, pair1, -1))
+	default:
+		shape.set(HighCard, nil, kickers(all, 5, -1, -1))
+	}
+	return shape
+}
+
+// noKickers is passed to set for hands that are made by their 5 cards alone.
+var noKickers = [5]int{-1, -1, -1, -1, -1}
+
+// set fills in the shape from the cards that make the category and the kickers.
+// The score only counts each rank of a group once, then each kicker.
+func (h *handShape) set(category HandCategory, made []int, kickers [5]int) {
+	score := HandScore(category) << 20
+	shift := 16
+	n := 0
+	for i, r := range made {
+		if i == 0 || r != made[i-1] {
+			score |= HandScore(r+2) << shift
+			shift -= 4
+		}
+		h.ranks[n] = int8(r)
+		n++
+	}
+	for _, r := range kickers {
+		if n == 5 || r < 0 {
+			break
+		}
+		score |= HandScore(r+2) << shift
+		shift -= 4
+		h.ranks[n] = int8(r)
+		n++
+	}
+	h.score = score
+}
+
+// setStraight fills in the shape for a straight or straight flush topped by high.
+func (h *handShape) setStraight(category HandCategory, high int) {
+	h.score = HandScore(category)<<20 | HandScore(high+2)<<16
+	for i := 0; i < 5; i++ {
+		r := high - i
+		if r < 0 {
+			r = 12 // the ace plays low in the wheel
+		}
+		h.ranks[i] = int8(r)
+	}
+}
+
+// straightHigh returns the top rank of the highest straight in mask, or -1 if there is none.
+// The ace also counts below the two, so A-2-3-4-5 is a straight with a five high.
+func straightHigh(mask uint16) int {
+	ext := uint32(mask)<<1 | uint32(mask>>12)&1 // bit 0 is the low ace, bit r+1 is rank r
+	run := ext & (ext >> 1) & (ext >> 2) & (ext >> 3) & (ext >> 4)
+	if run == 0 {
+		return -1
+	}
+	top := 0
+	for b := 9; b >= 0; b-- {
+		if run&(1<<b) != 0 {
+			top = b
+			break
+		}
+	}
+	// Bits top..top+4 hold the straight, bit top+4 is rank top+3
+	return top + 3
+}
+
+// kickers returns the n highest ranks in mask, leaving out skip1 and skip2 (-1 for none).
+// Unused places are -1.
+func kickers(mask uint16, n int, skip1, skip2 int) [5]int {
+	if skip1 >= 0 {
+		mask &^= 1 << skip1
+	}
+	if skip2 >= 0 {
+		mask &^= 1 << skip2
+	}
+	out := [5]int{-1, -1, -1, -1, -1}
+	found := 0
+	for r := 12; r >= 0 && found < n; r-- {
+		if mask&(1<<r) != 0 {
+			out[found] = r
+			found++
+		}
+	}
+	return out
+}
@@ -0,0 +1,146 @@
+package carddeck
+
+import (
+	"strings"
+	"testing"
+)
+
+// cards parses a space separated list of card codes, e.g. "AH 10S 2C".
+func cards(t testing.TB, s string) []Card {
+	t.Helper()
+	var hand []Card
+	for _, code := range strings.Fields(s) {
+		c, err := ParseCard(code)
+		if err != nil {
+			t.Fatalf("parsing %q: %v", code, err)
+		}
+		hand = append(hand, c)
+	}
+	return hand
+}
+
+// codes returns the card codes of hand, space separated.
+func codes(hand []Card) string {
+	parts := make([]string, len(hand))
+	for i, c := range hand {
+		parts[i] = c.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestEvaluatePoker(t *testing.T) {
+	tests := []struct {
+		name     string
+		cards    string
+		category HandCategory
+		best     string // the 5 cards of the hand, most important first
+	}{
+		{"high card", "AH JD 9C 7S 4H 3D 2C", HighCard, "AH JD 9C 7S 4H"},
+		{"pair", "KH KD 9C 7S 4H 3D 2C", OnePair, "KH KD 9C 7S 4H"},
+		{"three pairs play the best two and a kicker", "KH KD QC QS 2H 2D 3C", TwoPair, "KH KD QC QS 3C"},
+		{"third pair is the kicker", "KH KD QC QS 7H 7D 3C", TwoPair, "KH KD QC QS 7H"},
+		{"trips", "9H 9D 9C AS KH 4D 2C", ThreeOfAKind, "9H 9D 9C AS KH"},
+		{"two trips make a full house", "KH KD KC 7S 7H 7D 2C", FullHouse, "KH KD KC 7S 7H"},
+		{"wheel", "AH 2D 3C 4S 5H 9D KC", Straight, "5H 4S 3C 2D AH"},
+		{"six high beats the wheel ace", "AH 2D 3C 4S 5H 6D KC", Straight, "6D 5H 4S 3C 2D"},
+		{"broadway", "AH KD QC JS 10H 2D 3C", Straight, "AH KD QC JS 10H"},
+		{"flush over straight", "9H 8H 7D 6H 5S 2H KH", Flush, "KH 9H 8H 6H 2H"},
+		{"best five of a six card flush", "AH KH 9H 7H 4H 2H QC", Flush, "AH KH 9H 7H 4H"},
+		{"full house over flush", "AH KH 9H 7H 4H AD AC 9S", FullHouse, "AH AD AC 9H 9S"},
+		{"quads with best kicker", "8H 8D 8C 8S 2H KD 3C", FourOfAKind, "8H 8D 8C 8S KD"},
+		{"steel wheel", "AS 2S 3S 4S 5S KH KD", StraightFlush, "5S 4S 3S 2S AS"},
+		{"royal flush", "AS KS QS JS 10S 9S 2D", StraightFlush, "AS KS QS JS 10S"},
+		{"straight flush over a higher plain straight", "5H 6H 7H 8H 9H 10D", StraightFlush, "9H 8H 7H 6H 5H"},
+		{"five cards", "2H 3D 4C 5S 7H", HighCard, "7H 5S 4C 3D 2H"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hand := EvaluatePoker(cards(t, tt.cards))
+			if hand.Category != tt.category {
+				t.Errorf("category = %v, want %v", hand.Category, tt.category)
+			}
+			if hand.Score.Category() != tt.category {
+				t.Errorf("score category = %v, want %v", hand.Score.Category(), tt.category)
+			}
+			if got := codes(hand.Cards); got != tt.best {
+				t.Errorf("cards = %s, want %s", got, tt.best)
+			}
+			if got := PokerScore(cards(t, tt.cards)); got != hand.Score {
+				t.Errorf("PokerScore = %d, EvaluatePoker score = %d", got, hand.Score)
+			}
+		})
+	}
+}
+
+func TestPokerScoreOrder(t *testing.T) {
+	tests := []struct {
+		name          string
+		better, worse string
+	}{
+		{"six high straight beats the wheel", "6D 5H 4S 3C 2D", "AH 2D 3C 4S 5H"},
+		{"six high straight flush beats the steel wheel", "6S 5S 4S 3S 2S", "AS 2S 3S 4S 5S"},
+		{"steel wheel beats quads", "AS 2S 3S 4S 5S", "KH KD KC KS AH"},
+		{"flush beats straight", "KH 9H 8H 6H 2H", "AH KD QC JS 10H"},
+		{"higher pair", "QH QD 5C 4S 2H", "JH JD AC KS QH"},
+		{"pair kicker", "KH KD AC 4S 2H", "KS KC QC JS 10H"},
+		{"last kicker", "KH KD AC 9S 3H", "KS KC AD 9D 2H"},
+		{"two pair kicker", "AH AD 8C 8S KH", "AS AC 8D 8H QH"},
+		{"higher second pair", "AH AD 9C 9S 2H", "AS AC 8D 8H KH"},
+		{"full house by trips", "3H 3D 3C 2S 2H", "2D 2C 2S AS AH"},
+		{"flush by the last card", "AH KH 9H 7H 4H", "AD KD 9D 7D 3D"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			better, worse := PokerScore(cards(t, tt.better)), PokerScore(cards(t, tt.worse))
+			if better <= worse {
+				t.Errorf("%s scored %d, not above %s at %d", tt.better, better, tt.worse, worse)
+			}
+		})
+	}
+}
+
+func TestPokerScoreTies(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{"suits do not matter", "AH KD 9C 7S 4H", "AS KC 9D 7H 4D"},
+		{"board plays", "AH KH QH JD 9S 2C 3D", "AH KH QH JD 9S 4C 5D"},
+		{"kickers below the best five do not count", "KH KD AC QS JH 3D 2C", "KS KC AD QD JC 4H 3H"},
+		{"straights of the same height", "9H 8D 7C 6S 5H", "9S 8C 7D 6H 5D"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := PokerScore(cards(t, tt.a)), PokerScore(cards(t, tt.b))
+			if a != b {
+				t.Errorf("%s scored %d, %s scored %d, want a tie", tt.a, a, tt.b, b)
+			}
+		})
+	}
+}
+
+func TestPokerScoreIgnoresOtherCards(t *testing.T) {
+	hand := EvaluatePoker([]Card{Joker, Hidden, {Suit: Hearts, Value: Ace}, {Suit: Hearts, Value: King},
+		{Suit: Hearts, Value: Queen}, {Suit: Hearts, Value: Jack}, {Suit: Hearts, Value: Ten}})
+	if hand.Category != StraightFlush || len(hand.Cards) != 5 {
+		t.Errorf("got %v with %d cards, want a straight flush of 5 cards", hand.Category, len(hand.Cards))
+	}
+}
+
+func BenchmarkPokerScore(b *testing.B) {
+	hands := [][]Card{
+		cards(b, "AH KD 9C 7S 4H 3D 2C"),
+		cards(b, "KH KD QC QS 7H 7D 3C"),
+		cards(b, "9H 8H 7D 6H 5S 2H KH"),
+		cards(b, "AS 2S 3S 4S 5S KH KD"),
+		cards(b, "KH KD KC 7S 7H 7D 2C"),
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		PokerScore(hands[i%len(hands)])
+	}
+}
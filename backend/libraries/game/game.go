@@ -0,0 +1,131 @@
+// Package game defines the interface every card game played over WebSocket implements,
+// so the game instance manager, lobby and WebSocket handler can run any game without
+// knowing its rules. Games register a constructor by name in the registry.
+// This file contains the Game interface and the seat a player gets when they join.
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Errors returned by Join when a player cannot take a seat.
+var (
+	ErrTableFull     = errors.New("table is full")
+	ErrTableLocked   = errors.New("table is locked by the host")
+	ErrWrongPassword = errors.New("wrong table password")
+	ErrBanned        = errors.New("banned from this table")
+	ErrNotAllowed    = errors.New("not allowed to join this table")
+	ErrTableClosed   = errors.New("table has been closed")
+)
+
+// JoinRequest describes a player asking for a seat at a table.
+type JoinRequest struct {
+	PlayerID uint
+	Password string // checked against the table password, if it has one
+	Invited  bool   // player holds a valid invite from the host, skips the password and lock
+}
+
+// Seat is a player's connection to a game.
+// Actions carries the player's messages as raw JSON; the game decodes them and always
+// treats them as coming from PlayerID. State carries the updates for the player and is
+// closed when the seat is taken over by a new connection, the player is removed or the
+// game is closed.
+type Seat struct {
+	PlayerID uint
+	Actions  chan<- json.RawMessage
+	State    <-chan any
+}
+
+// Game is a running table of some card game.
+type Game interface {
+	// Join seats a player, or reconnects them if they already have a seat.
+	Join(req JoinRequest) (*Seat, error)
+	// Leave marks the connection holding seat as gone. The game decides when to free the
+	// seat. A seat that a newer connection of the player has taken over is ignored.
+	Leave(seat *Seat)
+	// IsSeated reports whether the player already has a seat.
+	IsSeated(playerID uint) bool
+	// Seats returns the number of seats taken and the size of the table.
+	Seats() (taken, max int)
+	// IdleFor returns how long the table has had no connected players.
+	IdleFor() time.Duration
+	// KeepWhenEmpty reports whether the table must not be cleaned up when idle.
+	KeepWhenEmpty() bool
+	// Close stops the game and closes every seat's State channel.
+	Close()
+	// Done is closed once the game has been closed.
+	Done() <-chan struct{}
+}
+
+// Greeter is implemented by games that send a newly connected player anything beyond
+// the regular state updates, such as the current state or chat history.
+type Greeter interface {
+	Greet(playerID uint)
+}
+
+// Hosted is implemented by games whose private tables have a host.
+type Hosted interface {
+	HostID() uint
+}
+
+// Summary describes a table for the lobby's table browser.
+type Summary struct {
+	Phase      string // current phase of the game, e.g. "betting"
+	Rules      string // short summary of the table rules, e.g. "6 decks, blackjack pays 3:2"
+	MinStake   int    // smallest bet or buy-in, 0 for games not played for chips
+	MaxStake   int    // largest bet or buy-in, 0 for no limit
+	Spectators int    // seated players sitting out the current round
+
+	TurnSeconds   int    // time a player has to act, or to bet in games without turns
+	Bots          int    // seats played by bots
+	BetweenRounds bool   // no round is being played, a player joining now is in the next one from the start
+	PlayerIDs     []uint `json:"-"` // seated players, for the matchmaker, not shown in the lobby
+}
+
+// Describer is implemented by games that can describe their tables in the table browser.
+// Tables of other games are still listed, with an empty Summary.
+type Describer interface {
+	Describe() Summary
+}
+
+// Inspection is what an admin sees of a table.
+type Inspection struct {
+	Phase        string
+	PlayerIDs    []uint
+	ShoeDepth    int  // cards left to deal, 0 for games without a shoe
+	RoundsPlayed int  // rounds or hands played since the table opened
+	Paused       bool // no new rounds are being started
+	State        any  `json:",omitempty"` // the full game state, hidden cards included
+}
+
+// Inspector is implemented by games that admins can look inside.
+type Inspector interface {
+	Inspect() Inspection
+}
+
+// Pausable is implemented by games that can stop starting new rounds. The round in
+// progress is played out, the next one starts once the game is resumed.
+type Pausable interface {
+	SetPaused(paused bool)
+	Paused() bool
+}
+
+// Suspender is implemented by games that can be stopped for a server restart. Suspend
+// closes the game like Close, except that the chips on the table are left to the game's
+// last snapshot instead of being paid back: restoring the snapshot pays them back or
+// plays on with them.
+type Suspender interface {
+	Suspend()
+}
+
+// IsClosed reports whether g has been closed.
+func IsClosed(g Game) bool {
+	select {
+	case <-g.Done():
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,104 @@
+// Package game defines the interface every card game played over WebSocket implements.
+// This file contains the registry games add themselves to, so the lobby can create
+// tables of any game by name.
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidConfig is returned by a Factory when the table settings are not valid for the game.
+var ErrInvalidConfig = errors.New("invalid table settings")
+
+// Config holds the settings a table is created with.
+type Config struct {
+	Mode     string          // one of the game's Modes
+	Private  bool            // table is private, reached by ID or invite only
+	HostID   uint            // creator of a private table, 0 for no host
+	Password string          // optional password for private tables
+	Rules    json.RawMessage // optional game specific rules, decoded by the game
+	FastPace bool            // play with a turn clock of at most FastPaceSeconds, games already that fast ignore it
+
+	// Snapshot is the saved state of a table to restore, as passed to OnSnapshot.
+	// The table comes back with its rules, host, seats and shoe, the seats are held for
+	// the players for SeatHoldTime. Mode still picks the table mode, the other settings
+	// above are ignored.
+	Snapshot json.RawMessage
+	// OnSnapshot is called by the game with its state whenever it is worth saving: at
+	// round boundaries and when chips move on or off the table. Nil when the table is
+	// not saved. The game is busy while it runs, so it must not call back into the game.
+	OnSnapshot func(state json.RawMessage)
+}
+
+// SeatHoldTime is how long a restored table keeps the seats of players who have not
+// reconnected yet.
+const SeatHoldTime = 5 * time.Minute
+
+// FastPaceSeconds is the longest turn clock of a fast-paced table.
+const FastPaceSeconds = 15
+
+// Factory creates a new table of a game.
+type Factory func(db *gorm.DB, cfg Config) (Game, error)
+
+// Definition describes a registered game.
+type Definition struct {
+	Name  string   // name used in the lobby and WebSocket route, e.g. "blackjack"
+	Modes []string // modes the game can be played in, the first one is the default
+	New   Factory
+}
+
+// Mode returns the mode a table is created in when mode is requested, and false if
+// the game has no such mode. An empty mode selects the default.
+func (d Definition) Mode(mode string) (string, bool) {
+	if mode == "" && len(d.Modes) > 0 {
+		return d.Modes[0], true
+	}
+	for _, m := range d.Modes {
+		if m == mode {
+			return m, true
+		}
+	}
+	return "", false
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Definition)
+)
+
+// Register adds a game to the registry. Games call it from an init function.
+// Registering the same name twice panics.
+func Register(def Definition) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[def.Name]; ok {
+		panic("game: " + def.Name + " registered twice")
+	}
+	registry[def.Name] = def
+}
+
+// Lookup returns the registered game called name.
+func Lookup(name string) (Definition, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	def, ok := registry[name]
+	return def, ok
+}
+
+// Names returns the names of all registered games in alphabetical order.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,115 @@
+// Package gameinstancemanager provides management functionality for game instances.
+// This file contains what the admin API sees of the tables: every instance, public
+// and private, with its phase, players, shoe depth, uptime and rounds played, the full
+// state of one table, and pausing, resuming and force-closing a table. The state is
+// read through the games' Inspector interface. Each game reads it the way it guards
+// it: blackjack asks its game loop through the table's mailbox, the other games take
+// their table lock.
+package gameinstancemanager
+
+import (
+	"cardgames/backend/libraries/game"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrNotPausable is returned by SetPaused for games that cannot be paused.
+var ErrNotPausable = errors.New("game cannot be paused")
+
+// AdminTable is a table as the admin API lists it.
+type AdminTable struct {
+	ID         string
+	Game       string
+	Mode       string
+	Private    bool
+	Seated     int
+	MaxPlayers int
+	Created    time.Time
+	Uptime     string // time since the table was created, e.g. "1h2m3s"
+	game.Inspection
+}
+
+// AdminTables returns every open table, public and private, ordered by ID.
+// The full game state is left out, AdminTable has it for a single table.
+func (gim *GameInstanceManager) AdminTables() []AdminTable {
+	gim.mu.RLock()
+	tables := make([]*Table, 0, len(gim.PublicGames)+len(gim.PrivateGames))
+	for _, t := range gim.PublicGames {
+		tables = append(tables, t)
+	}
+	for _, t := range gim.PrivateGames {
+		tables = append(tables, t)
+	}
+	gim.mu.RUnlock()
+
+	// Inspect the tables without holding the manager lock, each takes its table's lock
+	list := make([]AdminTable, 0, len(tables))
+	for _, t := range tables {
+		if game.IsClosed(t.Game) {
+			continue
+		}
+		at := inspect(t)
+		at.State = nil
+		list = append(list, at)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// AdminTable returns a table with its full game state, hidden cards included.
+// Returns ErrTableNotFound when there is no open table with the given ID.
+func (gim *GameInstanceManager) AdminTable(id string) (AdminTable, error) {
+	t, ok := gim.GetTable(id)
+	if !ok || game.IsClosed(t.Game) {
+		return AdminTable{}, ErrTableNotFound
+	}
+	return inspect(t), nil
+}
+
+// SetPaused pauses or resumes a table. A paused table plays out the round in
+// progress and starts no new one until it is resumed.
+// Returns ErrTableNotFound for unknown tables and ErrNotPausable for games without pausing.
+func (gim *GameInstanceManager) SetPaused(id string, paused bool) error {
+	t, ok := gim.GetTable(id)
+	if !ok || game.IsClosed(t.Game) {
+		return ErrTableNotFound
+	}
+	p, ok := t.Game.(game.Pausable)
+	if !ok {
+		return ErrNotPausable
+	}
+	p.SetPaused(paused)
+	return nil
+}
+
+// ForceClose closes a table right away and disconnects its players. Chips on the
+// table in a round that has not been settled are refunded by the game as it shuts down.
+// Returns ErrTableNotFound when there is no open table with the given ID.
+func (gim *GameInstanceManager) ForceClose(id string) error {
+	t, ok := gim.GetTable(id)
+	if !ok || game.IsClosed(t.Game) {
+		return ErrTableNotFound
+	}
+	gim.RemoveGame(id)
+	return nil
+}
+
+// inspect returns the admin view of a table.
+func inspect(t *Table) AdminTable {
+	at := AdminTable{
+		ID:      t.ID,
+		Game:    t.GameName,
+		Mode:    t.Mode,
+		Private: t.Private,
+		Created: t.Created,
+		Uptime:  time.Since(t.Created).Round(time.Second).String(),
+	}
+	at.Seated, at.MaxPlayers = t.Game.Seats()
+	if in, ok := t.Game.(game.Inspector); ok {
+		at.Inspection = in.Inspect()
+	} else if p, ok := t.Game.(game.Pausable); ok {
+		at.Paused = p.Paused()
+	}
+	return at
+}
@@ -0,0 +1,138 @@
+// Package gameinstancemanager provides management functionality for game instances.
+// This file contains the table browser: listing the public tables with what is
+// going on at each of them, filtered and sorted for the lobby, and finding a
+// listed table a player picked.
+package gameinstancemanager
+
+import (
+	"cardgames/backend/libraries/game"
+	"errors"
+	"sort"
+)
+
+// Errors returned by PublicTable.
+var (
+	ErrTableNotFound = errors.New("table not found")
+	ErrTableFull     = errors.New("table is full")
+)
+
+// TableInfo is a public table as listed in the table browser.
+type TableInfo struct {
+	ID         string
+	Game       string
+	Mode       string
+	Seated     int
+	MaxPlayers int
+	game.Summary
+}
+
+// Sort orders for ListPublicTables.
+const (
+	SortPlayers = "players" // most seated players first, the default
+	SortOpen    = "open"    // most free seats first
+	SortStakes  = "stakes"  // lowest minimum stake first
+	SortID      = "id"
+)
+
+// TableFilter selects and orders the tables listed by ListPublicTables.
+// Zero values do not filter.
+type TableFilter struct {
+	Game     string // registered game name
+	Mode     string
+	Phase    string
+	OpenOnly bool   // only tables with a free seat
+	MaxStake int    // only tables whose minimum stake is at most this
+	Sort     string // one of the Sort orders, SortPlayers when empty
+	Desc     bool   // reverse the order
+}
+
+// ValidSort reports whether s is a sort order ListPublicTables knows.
+func ValidSort(s string) bool {
+	switch s {
+	case "", SortPlayers, SortOpen, SortStakes, SortID:
+		return true
+	}
+	return false
+}
+
+// ListPublicTables returns the public tables that match f, in the order it asks for.
+// Ties are broken by table ID so the order is stable between calls.
+func (gim *GameInstanceManager) ListPublicTables(f TableFilter) []TableInfo {
+	gim.mu.RLock()
+	tables := make([]*Table, 0, len(gim.PublicGames))
+	for _, t := range gim.PublicGames {
+		if game.IsClosed(t.Game) || (f.Game != "" && t.GameName != f.Game) || (f.Mode != "" && t.Mode != f.Mode) {
+			continue
+		}
+		tables = append(tables, t)
+	}
+	gim.mu.RUnlock()
+
+	// Describe the tables without holding the manager lock, each takes its table's lock
+	infos := make([]TableInfo, 0, len(tables))
+	for _, t := range tables {
+		info := describe(t)
+		if f.Phase != "" && info.Phase != f.Phase {
+			continue
+		}
+		if f.OpenOnly && info.Seated >= info.MaxPlayers {
+			continue
+		}
+		if f.MaxStake > 0 && info.MinStake > f.MaxStake {
+			continue
+		}
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		a, b := infos[i], infos[j]
+		if f.Desc {
+			a, b = b, a
+		}
+		switch f.Sort {
+		case SortOpen:
+			if a.MaxPlayers-a.Seated != b.MaxPlayers-b.Seated {
+				return a.MaxPlayers-a.Seated > b.MaxPlayers-b.Seated
+			}
+		case SortStakes:
+			if a.MinStake != b.MinStake {
+				return a.MinStake < b.MinStake
+			}
+		case SortID:
+		default:
+			if a.Seated != b.Seated {
+				return a.Seated > b.Seated
+			}
+		}
+		return a.ID < b.ID
+	})
+	return infos
+}
+
+// PublicTable returns the public table with the given ID for a player who picked it
+// in the table browser. Returns ErrTableNotFound for unknown, closed or private
+// tables and ErrTableFull when there is no free seat, unless the player is already
+// seated there and is coming back.
+func (gim *GameInstanceManager) PublicTable(id string, playerID uint) (*Table, error) {
+	t, ok := gim.publicTable(id)
+	if !ok || game.IsClosed(t.Game) {
+		return nil, ErrTableNotFound
+	}
+	if t.Game.IsSeated(playerID) {
+		return t, nil
+	}
+	if taken, max := t.Game.Seats(); taken >= max {
+		return nil, ErrTableFull
+	}
+	return t, nil
+}
+
+// describe returns the browser listing of a table.
+func describe(t *Table) TableInfo {
+	info := TableInfo{ID: t.ID, Game: t.GameName, Mode: t.Mode}
+	info.Seated, info.MaxPlayers = t.Game.Seats()
+	if d, ok := t.Game.(game.Describer); ok {
+		info.Summary = d.Describe()
+	}
+	return info
+}
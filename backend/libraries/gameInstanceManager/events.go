@@ -0,0 +1,193 @@
+// Package gameinstancemanager provides management functionality for game instances.
+// This file contains the lobby events the manager publishes about its public tables:
+// tables being created and closed, seats filling up and emptying, and players sitting
+// down. Subscribers get the events on a buffered channel. Publishing never blocks, a
+// subscriber that falls a full buffer behind is dropped and its channel closed, so a
+// slow client can never hold up the manager or the games.
+package gameinstancemanager
+
+import (
+	"cardgames/backend/libraries/game"
+	"sync"
+	"time"
+)
+
+// EventType is the kind of a lobby event.
+type EventType string
+
+const (
+	TableCreated     EventType = "table_created"
+	TableClosed      EventType = "table_closed"
+	OccupancyChanged EventType = "occupancy"
+	PlayerSeated     EventType = "player_seated"
+)
+
+// Event is a change to the public tables.
+type Event struct {
+	Type       EventType
+	TableID    string
+	Game       string
+	Mode       string
+	Seated     int
+	MaxPlayers int
+	PlayerID   uint `json:",omitempty"` // the player who sat down, PlayerSeated only
+}
+
+// subscriberBuffer is how many events a subscriber can fall behind before it is dropped.
+const subscriberBuffer = 64
+
+// occupancyInterval is how often seat counts are checked for changes the games make
+// on their own, such as players removed at the end of a round.
+const occupancyInterval = 3 * time.Second
+
+// eventHub fans events out to the subscribers and remembers the last seat count
+// published for each public table. It has its own lock, it never takes gim.mu.
+type eventHub struct {
+	mu     sync.Mutex
+	subs   map[chan Event]struct{}
+	seated map[string]int // last published seat count of each open public table
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{
+		subs:   make(map[chan Event]struct{}),
+		seated: make(map[string]int),
+	}
+}
+
+// publish sends e to every subscriber without blocking. Must be called with h.mu held.
+func (h *eventHub) publish(e Event) {
+	for ch := range h.subs {
+		select {
+		case ch <- e:
+		default:
+			// Subscriber is too far behind, drop it. It can reconnect and reload the tables
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// closeAll drops every subscriber.
+func (h *eventHub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+// Subscribe returns a channel of lobby events and a function that cancels the
+// subscription. The channel is closed when the subscription is cancelled, when the
+// subscriber falls too far behind or when the manager stops.
+func (gim *GameInstanceManager) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+	h := gim.events
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+
+	cancel := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subs[ch]; ok {
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel
+}
+
+// tableEvent returns an event about t with its current seat count.
+// The seats are read from the game, so gim.mu must not be held.
+func tableEvent(typ EventType, t *Table) Event {
+	taken, max := t.Game.Seats()
+	return Event{Type: typ, TableID: t.ID, Game: t.GameName, Mode: t.Mode, Seated: taken, MaxPlayers: max}
+}
+
+// tableOpened publishes a new public table.
+func (gim *GameInstanceManager) tableOpened(t *Table) {
+	e := tableEvent(TableCreated, t)
+	h := gim.events
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seated[t.ID] = e.Seated
+	h.publish(e)
+}
+
+// tableClosed publishes a public table going away, once.
+func (gim *GameInstanceManager) tableClosed(t *Table) {
+	h := gim.events
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.seated[t.ID]; !ok {
+		return
+	}
+	delete(h.seated, t.ID)
+	h.publish(Event{Type: TableClosed, TableID: t.ID, Game: t.GameName, Mode: t.Mode})
+}
+
+// refreshOccupancy publishes the seat count of a public table if it changed.
+func (gim *GameInstanceManager) refreshOccupancy(t *Table) {
+	e := tableEvent(OccupancyChanged, t)
+	h := gim.events
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if last, ok := h.seated[t.ID]; !ok || last == e.Seated {
+		return
+	}
+	h.seated[t.ID] = e.Seated
+	h.publish(e)
+}
+
+// PlayerJoined tells the manager a player took a seat at a table. The WebSocket
+// handler calls it after a successful Join so the lobby hears about it right away.
+func (gim *GameInstanceManager) PlayerJoined(tableID string, playerID uint) {
+	t, ok := gim.publicTable(tableID)
+	if !ok {
+		return
+	}
+	gim.refreshOccupancy(t)
+
+	e := tableEvent(PlayerSeated, t)
+	e.PlayerID = playerID
+	h := gim.events
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.publish(e)
+}
+
+// PlayerLeft tells the manager a player's connection to a table went away.
+func (gim *GameInstanceManager) PlayerLeft(tableID string) {
+	if t, ok := gim.publicTable(tableID); ok {
+		gim.refreshOccupancy(t)
+	}
+}
+
+// checkOccupancy publishes the seat changes and closures of the public tables that
+// happened inside the games since the last check.
+func (gim *GameInstanceManager) checkOccupancy() {
+	gim.mu.RLock()
+	tables := make([]*Table, 0, len(gim.PublicGames))
+	for _, t := range gim.PublicGames {
+		tables = append(tables, t)
+	}
+	gim.mu.RUnlock()
+
+	for _, t := range tables {
+		if game.IsClosed(t.Game) {
+			gim.tableClosed(t)
+			continue
+		}
+		gim.refreshOccupancy(t)
+	}
+}
+
+// publicTable returns the public table with the given ID.
+func (gim *GameInstanceManager) publicTable(id string) (*Table, bool) {
+	gim.mu.RLock()
+	defer gim.mu.RUnlock()
+	t, ok := gim.PublicGames[id]
+	return t, ok
+}
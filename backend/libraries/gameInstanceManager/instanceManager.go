@@ -1,14 +1,15 @@
 // Package gameinstancemanager provides management functionality for game instances.
 // It handles the creation, retrieval, and lifecycle management of both public and
-// private blackjack game instances, ensuring thread-safe operations and automatic
-// cleanup of empty games.
+// private game instances of any game in the game registry, ensuring thread-safe
+// operations and automatic cleanup of idle games.
 //
 // Author: Benjamin Stonestreet
 // Date: 2025-11-06
 package gameinstancemanager
 
 import (
-	"cardgames/backend/libraries/blackjack"
+	"cardgames/backend/libraries/game"
+	"fmt"
 	"math/rand"
 	"sync"
 	"time"
@@ -32,162 +33,269 @@ func generateID(length int) string {
 	return string(b)
 }
 
-// GameInstanceManager manages the lifecycle of blackjack game instances.
+// Table is a game instance held by the manager, along with which game it is.
+type Table struct {
+	ID        string
+	GameName  string // registered game name, e.g. "blackjack"
+	Mode      string
+	Private   bool
+	Game      game.Game
+	Created   time.Time
+	Scheduled bool // opened by the event scheduler, which closes it, so it is not cleaned up when idle
+	saved     bool // whether the table's snapshots are written, guarded by gim.snapMu
+}
+
+// GameInstanceManager manages the lifecycle of game instances.
 // It maintains separate maps for public and private games and provides
 // thread-safe access through a read-write mutex.
 type GameInstanceManager struct {
 	mu           sync.RWMutex
-	PublicGames  map[string]*blackjack.BlackJackInstance
-	PrivateGames map[string]*blackjack.BlackJackInstance
+	PublicGames  map[string]*Table
+	PrivateGames map[string]*Table
 	DB           *gorm.DB
+	events       *eventHub
 	stop         chan struct{}
+	snapMu       sync.Mutex // serializes writing and deleting table snapshots
 }
 
+// idleTimeout is how long a game can go without connected players before it is cleaned up.
+const idleTimeout = 5 * time.Minute
+
 // NewGameInstanceManager creates and initializes a new GameInstanceManager.
-// It sets up the game maps, database connection, and starts the background
-// cleanup routine for removing empty games.
+// It sets up the game maps, database connection, restores the tables saved before
+// the last shutdown and starts the background cleanup routine for removing idle games.
 func NewGameInstanceManager(db *gorm.DB) *GameInstanceManager {
 	gim := &GameInstanceManager{
-		PublicGames:  make(map[string]*blackjack.BlackJackInstance),
-		PrivateGames: make(map[string]*blackjack.BlackJackInstance),
+		PublicGames:  make(map[string]*Table),
+		PrivateGames: make(map[string]*Table),
 		DB:           db,
+		events:       newEventHub(),
 		stop:         make(chan struct{}),
 	}
+	if db != nil {
+		gim.restoreTables()
+	}
 	gim.Start()
 	return gim
 }
 
-// clearEmptyGames removes all game instances that have no players.
-// This is called periodically by the background cleanup routine to free
-// up resources from abandoned games.
+// clearEmptyGames closes and removes all game instances that have had no connected
+// players for idleTimeout, along with any that were already closed.
+// This is called periodically by the background cleanup routine to stop the game
+// loops of abandoned games and free their resources.
+// The games are asked without holding the manager lock: a game may answer from its
+// own loop, which can be busy calling back into the manager.
 func (gim *GameInstanceManager) clearEmptyGames() {
-	gim.mu.Lock()
-	defer gim.mu.Unlock()
-	for id, game := range gim.PublicGames {
-		if len(game.Players) == 0 {
-			delete(gim.PublicGames, id)
-		}
+	gim.mu.RLock()
+	tables := make([]*Table, 0, len(gim.PublicGames)+len(gim.PrivateGames))
+	for _, t := range gim.PublicGames {
+		tables = append(tables, t)
 	}
-	for id, game := range gim.PrivateGames {
-		if len(game.Players) == 0 {
-			delete(gim.PrivateGames, id)
+	for _, t := range gim.PrivateGames {
+		tables = append(tables, t)
+	}
+	gim.mu.RUnlock()
+
+	for _, t := range tables {
+		if game.IsClosed(t.Game) || (!t.Scheduled && (!t.Private || !t.Game.KeepWhenEmpty()) && t.Game.IdleFor() >= idleTimeout) {
+			gim.RemoveGame(t.ID)
 		}
 	}
 }
 
 // Start begins the background cleanup routine that periodically removes
-// empty game instances. The cleanup runs every 5 minutes.
+// idle game instances. The cleanup runs every 5 minutes. The same routine checks
+// the public tables for seat changes to publish as lobby events.
 func (gim *GameInstanceManager) Start() {
-	ticker := time.NewTicker(5 * time.Minute) // Clear empty games every 5 minutes
+	ticker := time.NewTicker(5 * time.Minute) // Clear idle games every 5 minutes
+	occupancy := time.NewTicker(occupancyInterval)
 	go func() {
 		for {
 			select {
 			case <-ticker.C:
 				gim.clearEmptyGames()
+			case <-occupancy.C:
+				gim.checkOccupancy()
 			case <-gim.stop:
 				ticker.Stop()
+				occupancy.Stop()
 				return
 			}
 		}
 	}()
 }
 
-// Stop halts the background cleanup routine by closing the stop channel.
+// Stop halts the background cleanup routine by closing the stop channel and
+// closes every game instance, disconnecting their players.
 // This should be called when shutting down the game instance manager.
+// Saved tables are suspended rather than closed: the chips on them are left to their
+// snapshots, which are kept so the tables are restored on the next boot. Other tables
+// pay their chips back and have their snapshots deleted.
 func (gim *GameInstanceManager) Stop() {
 	close(gim.stop)
-}
+	gim.events.closeAll()
 
-// CreatePublicGame creates a new public blackjack game instance.
-// It generates a unique 5-character ID and adds the game to the public games map.
-// Returns the game ID and any error encountered.
-func (gim *GameInstanceManager) CreatePublicGame() (string, error) {
 	gim.mu.Lock()
-	defer gim.mu.Unlock()
-	var id string
-	for {
-		id = generateID(5)
-		if _, ok := gim.PublicGames[id]; !ok {
-			break
+	var closed []*Table
+	for id, t := range gim.PublicGames {
+		delete(gim.PublicGames, id)
+		closed = append(closed, t)
+	}
+	for id, t := range gim.PrivateGames {
+		delete(gim.PrivateGames, id)
+		closed = append(closed, t)
+	}
+	gim.mu.Unlock()
+
+	for _, t := range closed {
+		if !gim.suspend(t) {
+			t.Game.Close()
+			gim.stopSaving(t)
 		}
 	}
-	newGame := blackjack.NewBlackJackInstance(gim.DB)
-	gim.PublicGames[id] = newGame
-	return id, nil
 }
 
-// CreatePrivateGame creates a new private blackjack game instance.
-// It generates a unique 5-character ID and adds the game to the private games map.
-// Returns the game ID and any error encountered.
-func (gim *GameInstanceManager) CreatePrivateGame() (string, error) {
-	gim.mu.Lock()
-	defer gim.mu.Unlock()
-	var id string
+// newID returns a random 5-character ID not used by any game.
+// Must be called with gim.mu held.
+func (gim *GameInstanceManager) newID() string {
 	for {
-		id = generateID(5)
-		if _, ok := gim.PrivateGames[id]; !ok {
-			break
+		id := generateID(5)
+		_, public := gim.PublicGames[id]
+		_, private := gim.PrivateGames[id]
+		if !public && !private {
+			return id
 		}
 	}
-	newGame := blackjack.NewBlackJackInstance(gim.DB)
-	gim.PrivateGames[id] = newGame
-	return id, nil
 }
 
-// getPublicGame retrieves a public game instance by its ID.
-// Returns the game instance and a boolean indicating whether the game was found.
-func (gim *GameInstanceManager) getPublicGame(id string) (*blackjack.BlackJackInstance, bool) {
-	gim.mu.RLock()
-	defer gim.mu.RUnlock()
-	game, ok := gim.PublicGames[id]
-	return game, ok
+// CreatePublicGame creates a new public instance of the registered game name, played in mode.
+// It generates a unique 5-character ID and adds the game to the public games map.
+// Returns the game ID and any error encountered.
+func (gim *GameInstanceManager) CreatePublicGame(name string, mode string) (string, error) {
+	return gim.create(name, game.Config{Mode: mode}, false)
 }
 
-// getPrivateGame retrieves a private game instance by its ID.
-// Returns the game instance and a boolean indicating whether the game was found.
-func (gim *GameInstanceManager) getPrivateGame(id string) (*blackjack.BlackJackInstance, bool) {
-	gim.mu.RLock()
-	defer gim.mu.RUnlock()
-	game, ok := gim.PrivateGames[id]
-	return game, ok
+// CreatePrivateGame creates a new private instance of the registered game name customized
+// by cfg, which carries the mode, host, password and rules chosen by the creator.
+// It generates a unique 5-character ID and adds the game to the private games map.
+// Returns the game ID and any error encountered.
+func (gim *GameInstanceManager) CreatePrivateGame(name string, cfg game.Config) (string, error) {
+	cfg.Private = true
+	return gim.create(name, cfg, false)
 }
 
-// GetGame retrieves a game instance by its ID, searching both private and public games.
-// It first checks private games, then public games.
-// Returns the game instance if found, or nil if no game exists with the given ID.
-func (gim *GameInstanceManager) GetGame(id string) *blackjack.BlackJackInstance {
+// CreateScheduledGame creates a public instance of the registered game name customized
+// by cfg for a scheduled event. The table stays open while it is idle, the scheduler
+// removes it when the event ends.
+// Returns the game ID and any error encountered.
+func (gim *GameInstanceManager) CreateScheduledGame(name string, cfg game.Config) (string, error) {
+	cfg.Private = false
+	return gim.create(name, cfg, true)
+}
 
-	game, ok := gim.getPrivateGame(id)
-	if ok {
-		return game
+// create builds a game from the registry and adds it to the public or private games map.
+func (gim *GameInstanceManager) create(name string, cfg game.Config, scheduled bool) (string, error) {
+	def, ok := game.Lookup(name)
+	if !ok {
+		return "", fmt.Errorf("%w: unknown game %q", game.ErrInvalidConfig, name)
+	}
+	mode, ok := def.Mode(cfg.Mode)
+	if !ok {
+		return "", fmt.Errorf("%w: unknown mode %q", game.ErrInvalidConfig, cfg.Mode)
+	}
+	cfg.Mode = mode
+
+	t := &Table{GameName: name, Mode: mode, Private: cfg.Private, Scheduled: scheduled}
+	cfg.OnSnapshot = gim.snapshotter(t)
+	g, err := def.New(gim.DB, cfg)
+	if err != nil {
+		return "", err
 	}
 
-	game, ok = gim.getPublicGame(id)
-	if ok {
-		return game
+	gim.mu.Lock()
+	id := gim.newID()
+	t.ID, t.Game, t.Created = id, g, time.Now()
+	if cfg.Private {
+		gim.PrivateGames[id] = t
+	} else {
+		gim.PublicGames[id] = t
 	}
+	gim.mu.Unlock()
 
-	return nil
+	gim.startSaving(t)
+	if !cfg.Private {
+		gim.tableOpened(t)
+	}
+	return id, nil
 }
 
-// FindAvailablePublicGame searches for a public game that has available player slots.
-// If an available game is found, its ID is returned. If no games have available slots,
-// a new public game is created and its ID is returned.
-func (gim *GameInstanceManager) FindAvailablePublicGame() (string, error) {
+// AddPrivateGame adds a game instance created outside the registry to the private games map.
+// It is used by features that run their own tables, such as tournaments.
+// Returns the new game ID.
+func (gim *GameInstanceManager) AddPrivateGame(name string, mode string, g game.Game) string {
+	gim.mu.Lock()
+	defer gim.mu.Unlock()
+	id := gim.newID()
+	gim.PrivateGames[id] = &Table{ID: id, GameName: name, Mode: mode, Private: true, Game: g, Created: time.Now()}
+	return id
+}
+
+// RemoveGame closes a game instance and deletes it from the manager, whether public or private.
+func (gim *GameInstanceManager) RemoveGame(id string) {
+	gim.mu.Lock()
+	public, isPublic := gim.PublicGames[id]
+	if isPublic {
+		public.Game.Close()
+		delete(gim.PublicGames, id)
+	}
+	private, isPrivate := gim.PrivateGames[id]
+	if isPrivate {
+		private.Game.Close()
+		delete(gim.PrivateGames, id)
+	}
+	gim.mu.Unlock()
+
+	if isPublic {
+		gim.stopSaving(public)
+	}
+	if isPrivate {
+		gim.stopSaving(private)
+	}
+	if isPublic {
+		gim.tableClosed(public)
+	}
+}
+
+// GetTable retrieves a table by its ID, searching both private and public games.
+// Returns the table and a boolean indicating whether the game was found.
+func (gim *GameInstanceManager) GetTable(id string) (*Table, bool) {
 	gim.mu.RLock()
-	// Try to find an available game
-	for id, game := range gim.PublicGames {
-		if len(game.Players) < blackjack.MaxPlayersPerInstance {
-			gim.mu.RUnlock()
-			return id, nil
-		}
+	defer gim.mu.RUnlock()
+	if t, ok := gim.PrivateGames[id]; ok {
+		return t, true
 	}
-	gim.mu.RUnlock()
+	t, ok := gim.PublicGames[id]
+	return t, ok
+}
 
-	// No available game found, create a new one
-	id, err := gim.CreatePublicGame()
-	if err != nil {
-		return "", err
+// GetPrivateGame retrieves a private game instance by its ID.
+// Returns the game instance and a boolean indicating whether the game was found.
+func (gim *GameInstanceManager) GetPrivateGame(id string) (game.Game, bool) {
+	gim.mu.RLock()
+	defer gim.mu.RUnlock()
+	t, ok := gim.PrivateGames[id]
+	if !ok {
+		return nil, false
 	}
-	return id, nil
+	return t.Game, true
+}
+
+// GetGame retrieves a game instance by its ID, searching both private and public games.
+// Returns the game instance if found, or nil if no game exists with the given ID.
+func (gim *GameInstanceManager) GetGame(id string) game.Game {
+	t, ok := gim.GetTable(id)
+	if !ok {
+		return nil
+	}
+	return t.Game
 }
@@ -0,0 +1,237 @@
+// Package gameinstancemanager provides management functionality for game instances.
+// This file contains the matchmaker that seats players at public tables. It leaves out
+// the tables that do not fit what the player asked for, then prefers tables where
+// their friends sit, emptier tables so players spread evenly, tables about to start a
+// new round and tables of players with a similar skill. When nothing fits it opens a
+// new table. Every match says why the table was picked.
+package gameinstancemanager
+
+import (
+	"cardgames/backend/libraries/game"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// MatchRequest is what a player asks the matchmaker for.
+type MatchRequest struct {
+	Game           string
+	Mode           string
+	PlayerID       uint
+	Stake          int                         // stake the player wants to play, tables whose limits exclude it are left out, 0 for any
+	Friends        []uint                      // the player's friends
+	SitWithFriends bool                        // prefer tables where friends are seated
+	FastPace       bool                        // only tables with a turn clock of at most game.FastPaceSeconds
+	NoBots         bool                        // only tables without bots
+	Skill          func(playerID uint) float64 // optional skill rating, tables of players with a similar rating are preferred
+}
+
+// Match is the table the matchmaker picked.
+type Match struct {
+	TableID string
+	Created bool      // no table fitted and a new one was opened
+	Table   TableInfo // the table before the player sat down
+	Reason  string    // why the table was picked, e.g. "joined table X (3/7, $10-$500): new round about to start"
+}
+
+// Weights of the matchmaker's preferences. A friend at the table outweighs the rest.
+const (
+	friendWeight      = 100.0
+	fillWeight        = 10.0 // per fraction of seats taken, so emptier tables fill first
+	betweenRoundBonus = 3.0
+	skillWeight       = 5.0 // per point of skill rating difference
+)
+
+// candidate is a table that fits a match request, with its score.
+type candidate struct {
+	info     TableInfo
+	friends  int
+	skillGap float64 // -1 when not rated
+	score    float64
+}
+
+// Match finds the public table that suits req best and returns it, or opens a new
+// table when none fits. A player already seated at a table of the game and mode is
+// sent back to it. The player is not seated here, they join by connecting to the table.
+func (gim *GameInstanceManager) Match(req MatchRequest) (Match, error) {
+	def, ok := game.Lookup(req.Game)
+	if !ok {
+		return Match{}, fmt.Errorf("%w: unknown game %q", game.ErrInvalidConfig, req.Game)
+	}
+	mode, ok := def.Mode(req.Mode)
+	if !ok {
+		return Match{}, fmt.Errorf("%w: unknown mode %q", game.ErrInvalidConfig, req.Mode)
+	}
+
+	tables := gim.ListPublicTables(TableFilter{Game: def.Name, Mode: mode})
+	for _, info := range tables {
+		if containsID(info.PlayerIDs, req.PlayerID) {
+			return Match{TableID: info.ID, Table: info, Reason: fmt.Sprintf("rejoined table %s (%s)", info.ID, occupancy(info))}, nil
+		}
+	}
+
+	friends := make(map[uint]bool, len(req.Friends))
+	if req.SitWithFriends {
+		for _, id := range req.Friends {
+			friends[id] = true
+		}
+	}
+	var playerSkill float64
+	if req.Skill != nil {
+		playerSkill = req.Skill(req.PlayerID)
+	}
+
+	var best *candidate
+	var candidates []candidate
+	for _, info := range tables {
+		if !req.fits(info) {
+			continue
+		}
+		c := candidate{info: info, skillGap: -1}
+		for _, id := range info.PlayerIDs {
+			if friends[id] {
+				c.friends++
+			}
+		}
+		if req.Skill != nil && len(info.PlayerIDs) > 0 {
+			total := 0.0
+			for _, id := range info.PlayerIDs {
+				total += req.Skill(id)
+			}
+			c.skillGap = math.Abs(total/float64(len(info.PlayerIDs)) - playerSkill)
+		}
+
+		c.score = float64(c.friends)*friendWeight - fillWeight*float64(info.Seated)/float64(info.MaxPlayers)
+		if info.BetweenRounds {
+			c.score += betweenRoundBonus
+		}
+		if c.skillGap >= 0 {
+			c.score -= skillWeight * c.skillGap
+		}
+		candidates = append(candidates, c)
+	}
+	for i := range candidates {
+		c := &candidates[i]
+		if best == nil || c.score > best.score || (c.score == best.score && c.info.ID < best.info.ID) {
+			best = c
+		}
+	}
+
+	if best == nil {
+		id, err := gim.create(def.Name, game.Config{Mode: mode, FastPace: req.FastPace}, false)
+		if err != nil {
+			return Match{}, err
+		}
+		t, _ := gim.publicTable(id)
+		info := describe(t)
+		reason := fmt.Sprintf("opened new table %s (%s): no open table matched", id, occupancy(info))
+		if wanted := req.wanted(); wanted != "" {
+			reason += " " + wanted
+		}
+		return Match{TableID: id, Created: true, Table: info, Reason: reason}, nil
+	}
+
+	return Match{TableID: best.info.ID, Table: best.info, Reason: best.reason(candidates)}, nil
+}
+
+// fits reports whether a table meets the hard requirements of the request.
+func (req MatchRequest) fits(info TableInfo) bool {
+	if info.Seated >= info.MaxPlayers {
+		return false
+	}
+	if req.Stake > 0 && (info.MinStake > req.Stake || (info.MaxStake != 0 && req.Stake > info.MaxStake)) {
+		return false
+	}
+	if req.FastPace && info.TurnSeconds > game.FastPaceSeconds {
+		return false
+	}
+	if req.NoBots && info.Bots > 0 {
+		return false
+	}
+	return true
+}
+
+// wanted describes the requirements of the request, e.g. "for $10 stake, fast pace".
+func (req MatchRequest) wanted() string {
+	var parts []string
+	if req.Stake > 0 {
+		parts = append(parts, fmt.Sprintf("$%d stake", req.Stake))
+	}
+	if req.FastPace {
+		parts = append(parts, "fast pace")
+	}
+	if req.NoBots {
+		parts = append(parts, "no bots")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "for " + strings.Join(parts, ", ")
+}
+
+// reason explains why c was picked over the other candidates.
+func (c *candidate) reason(all []candidate) string {
+	var why []string
+	switch {
+	case c.friends == 1:
+		why = append(why, "a friend is seated")
+	case c.friends > 1:
+		why = append(why, fmt.Sprintf("%d friends are seated", c.friends))
+	}
+	if c.info.BetweenRounds {
+		why = append(why, "new round about to start")
+	}
+	// Only claimed when another table is actually fuller or further off
+	emptiest, fuller := true, false
+	closest, further := c.skillGap >= 0, false
+	for _, other := range all {
+		mine, theirs := c.info.Seated*other.info.MaxPlayers, other.info.Seated*c.info.MaxPlayers
+		if theirs < mine {
+			emptiest = false
+		}
+		if theirs > mine {
+			fuller = true
+		}
+		if other.skillGap >= 0 && other.skillGap < c.skillGap {
+			closest = false
+		}
+		if other.skillGap > c.skillGap {
+			further = true
+		}
+	}
+	if emptiest && fuller {
+		why = append(why, "fewest players of the open tables")
+	}
+	if closest && further {
+		why = append(why, "closest skill match")
+	}
+
+	reason := fmt.Sprintf("joined table %s (%s)", c.info.ID, occupancy(c.info))
+	if len(why) > 0 {
+		reason += ": " + strings.Join(why, ", ")
+	}
+	return reason
+}
+
+// occupancy describes a table's seats and stakes, e.g. "3/7, $10-$500".
+func occupancy(info TableInfo) string {
+	seats := fmt.Sprintf("%d/%d", info.Seated, info.MaxPlayers)
+	switch {
+	case info.MinStake == 0 && info.MaxStake == 0:
+		return seats + ", no stakes"
+	case info.MaxStake == 0:
+		return fmt.Sprintf("%s, $%d+", seats, info.MinStake)
+	default:
+		return fmt.Sprintf("%s, $%d-$%d", seats, info.MinStake, info.MaxStake)
+	}
+}
+
+// containsID reports whether id is in ids.
+func containsID(ids []uint, id uint) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+// Package gameinstancemanager provides management functionality for game instances.
+// This file contains table persistence. Games hand the manager a snapshot of their
+// state at round boundaries, which is saved to the database under the table ID. On
+// boot every saved table is recreated from its snapshot with the same ID, and players
+// who reconnect get their seats back. A table's snapshot is deleted when the table is
+// removed, but kept when the manager is stopped for a restart. Tables added from
+// outside the registry, such as tournament tables, are not saved.
+package gameinstancemanager
+
+import (
+	"cardgames/backend/libraries/game"
+	"cardgames/backend/models"
+	"encoding/json"
+	"log"
+)
+
+// snapshotter returns the OnSnapshot callback that saves t, or nil when the manager
+// has no database.
+func (gim *GameInstanceManager) snapshotter(t *Table) func(json.RawMessage) {
+	if gim.DB == nil {
+		return nil
+	}
+	return func(state json.RawMessage) { gim.saveSnapshot(t, state) }
+}
+
+// saveSnapshot saves the state of t, unless it is not being saved yet or any more.
+// Games save from their own goroutine, possibly while holding their own lock, so the
+// manager lock is never taken here: gim.snapMu alone keeps a table being removed from
+// having its snapshot written back after it was deleted.
+func (gim *GameInstanceManager) saveSnapshot(t *Table, state json.RawMessage) {
+	gim.snapMu.Lock()
+	defer gim.snapMu.Unlock()
+	if !t.saved {
+		return
+	}
+
+	snap := models.TableSnapshot{
+		ID:        t.ID,
+		Game:      t.GameName,
+		Mode:      t.Mode,
+		Private:   t.Private,
+		Scheduled: t.Scheduled,
+		State:     state,
+		CreatedAt: t.Created,
+	}
+	if err := gim.DB.Save(&snap).Error; err != nil {
+		log.Println("Failed to save snapshot of table", t.ID, ":", err)
+	}
+}
+
+// startSaving starts writing the snapshots of a table that was just added.
+func (gim *GameInstanceManager) startSaving(t *Table) {
+	gim.snapMu.Lock()
+	defer gim.snapMu.Unlock()
+	t.saved = true
+}
+
+// stopSaving stops writing the snapshots of a removed table and deletes its saved state.
+func (gim *GameInstanceManager) stopSaving(t *Table) {
+	gim.snapMu.Lock()
+	defer gim.snapMu.Unlock()
+	t.saved = false
+	gim.deleteSnapshot(t.ID)
+}
+
+// suspend closes a saved table for a restart, leaving its chips and its snapshot to be
+// restored on boot. It reports false, and leaves t open, when t is not saved or its game
+// cannot be suspended.
+func (gim *GameInstanceManager) suspend(t *Table) bool {
+	gim.snapMu.Lock()
+	defer gim.snapMu.Unlock()
+	s, ok := t.Game.(game.Suspender)
+	if !ok || !t.saved || gim.DB == nil {
+		return false
+	}
+	s.Suspend()
+	return true
+}
+
+// deleteSnapshot deletes the saved state of a table.
+func (gim *GameInstanceManager) deleteSnapshot(id string) {
+	if gim.DB == nil {
+		return
+	}
+	if err := gim.DB.Delete(&models.TableSnapshot{}, "id = ?", id).Error; err != nil {
+		log.Println("Failed to delete snapshot of table", id, ":", err)
+	}
+}
+
+// restoreTables recreates the saved tables. Snapshots that can no longer be restored,
+// such as those of a game that was removed, are deleted.
+func (gim *GameInstanceManager) restoreTables() {
+	var snaps []models.TableSnapshot
+	if err := gim.DB.Find(&snaps).Error; err != nil {
+		log.Println("Failed to load table snapshots:", err)
+		return
+	}
+
+	restored := 0
+	for _, snap := range snaps {
+		def, ok := game.Lookup(snap.Game)
+		if !ok {
+			log.Println("Dropping snapshot of table", snap.ID, ": unknown game", snap.Game)
+			gim.deleteSnapshot(snap.ID)
+			continue
+		}
+		mode, ok := def.Mode(snap.Mode)
+		if !ok {
+			log.Println("Dropping snapshot of table", snap.ID, ": unknown mode", snap.Mode)
+			gim.deleteSnapshot(snap.ID)
+			continue
+		}
+
+		// Restored tables keep their ID, so they are saved from the start
+		t := &Table{ID: snap.ID, GameName: def.Name, Mode: mode, Private: snap.Private, Scheduled: snap.Scheduled, Created: snap.CreatedAt, saved: true}
+		g, err := def.New(gim.DB, game.Config{
+			Mode:       mode,
+			Private:    snap.Private,
+			Snapshot:   snap.State,
+			OnSnapshot: gim.snapshotter(t),
+		})
+		if err != nil {
+			log.Println("Dropping snapshot of table", snap.ID, ":", err)
+			gim.deleteSnapshot(snap.ID)
+			continue
+		}
+
+		gim.mu.Lock()
+		t.Game = g
+		if t.Private {
+			gim.PrivateGames[t.ID] = t
+		} else {
+			gim.PublicGames[t.ID] = t
+		}
+		gim.mu.Unlock()
+
+		if !t.Private {
+			gim.tableOpened(t)
+		}
+		restored++
+	}
+	if restored > 0 {
+		log.Println("Restored", restored, "tables")
+	}
+}
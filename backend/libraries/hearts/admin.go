@@ -0,0 +1,104 @@
+package hearts
+
+// admin.go
+// This file contains what admins see of a Hearts table, every seat's hand included,
+// and pausing the table between hands.
+
+import (
+	carddeck "cardgames/backend/libraries/cardDeck"
+	"cardgames/backend/libraries/game"
+)
+
+// AdminState is the full state of a Hearts table for admins.
+type AdminState struct {
+	Rules        Rules
+	Seats        []AdminSeat
+	HandNumber   int
+	ActiveSeat   int
+	Trick        []PlayedCard
+	TricksPlayed int
+	HeartsBroken bool
+	HostID       uint
+}
+
+// AdminSeat is a seat as admins see it.
+type AdminSeat struct {
+	SeatInfo
+	Hand     []carddeck.Card
+	Pass     []carddeck.Card
+	Received []carddeck.Card
+}
+
+// Inspect returns the table state for admins.
+func (t *Table) Inspect() game.Inspection {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state := AdminState{
+		Rules:        t.rules,
+		HandNumber:   t.handNumber,
+		ActiveSeat:   t.active,
+		Trick:        append([]PlayedCard(nil), t.trick...),
+		TricksPlayed: t.tricksPlayed,
+		HeartsBroken: t.heartsBroken,
+		HostID:       t.hostID,
+	}
+	var ids []uint
+	for _, s := range t.seats {
+		info := SeatInfo{
+			Seat:       s.Index,
+			Bot:        s.isBot(),
+			CardCount:  len(s.Hand),
+			HasPassed:  s.Pass != nil,
+			Tricks:     s.Tricks,
+			HandPoints: s.HandPoints,
+			Score:      s.Score,
+		}
+		if s.Player != nil {
+			info.PlayerID = s.Player.ID
+			info.Username = s.Player.Account.Username
+			ids = append(ids, s.Player.ID)
+		}
+		state.Seats = append(state.Seats, AdminSeat{
+			SeatInfo: info,
+			Hand:     append([]carddeck.Card(nil), s.Hand...),
+			Pass:     append([]carddeck.Card(nil), s.Pass...),
+			Received: append([]carddeck.Card(nil), s.Received...),
+		})
+	}
+
+	return game.Inspection{
+		Phase:        string(t.phase),
+		PlayerIDs:    ids,
+		RoundsPlayed: t.handsPlayed,
+		Paused:       t.paused,
+		State:        state,
+	}
+}
+
+// SetPaused stops (or resumes) dealing new games and hands. The hand in progress is
+// played out.
+func (t *Table) SetPaused(paused bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.paused == paused {
+		return
+	}
+	t.paused = paused
+	if paused {
+		return
+	}
+	switch {
+	case t.phase == Waiting && t.humans() == NumSeats:
+		t.schedule(startDelay)
+	case t.phase == HandOver:
+		t.schedule(handOverDelay)
+	}
+}
+
+// Paused reports whether the table is paused.
+func (t *Table) Paused() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.paused
+}
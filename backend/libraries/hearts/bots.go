@@ -0,0 +1,99 @@
+package hearts
+
+// bots.go
+// This file contains the bots that play seats without a player, and moves for players
+// who run out of time. The bots play a simple, safe game: they get rid of the
+// dangerous high spades and hearts, duck under the winning card when they can and
+// throw their points on tricks they cannot win.
+
+import (
+	carddeck "cardgames/backend/libraries/cardDeck"
+	"sort"
+)
+
+// danger rates how risky a card is to keep: the queen of spades most, then the spades
+// that can catch it, then hearts, then everything else by rank.
+func danger(c carddeck.Card) int {
+	switch {
+	case c == queenOfSpades:
+		return 100
+	case c.Suit == carddeck.Spades && cardRank(c) > 12:
+		return 90
+	case c.Suit == carddeck.Hearts:
+		return 20 + cardRank(c)
+	}
+	return cardRank(c)
+}
+
+// botPass chooses the three most dangerous cards in hand to pass.
+func botPass(hand []carddeck.Card) []carddeck.Card {
+	cards := append([]carddeck.Card(nil), hand...)
+	sort.SliceStable(cards, func(i, j int) bool { return danger(cards[i]) > danger(cards[j]) })
+	return cards[:3]
+}
+
+// botPlay chooses a card to play to trick from the valid plays in hand.
+func botPlay(hand []carddeck.Card, trick []PlayedCard, firstTrick, heartsBroken bool) carddeck.Card {
+	plays := validPlays(hand, trick, firstTrick, heartsBroken)
+
+	if len(trick) == 0 {
+		// Lead the lowest card, keeping the queen of spades back
+		return lowest(plays, func(c carddeck.Card) bool { return c != queenOfSpades })
+	}
+
+	led := trick[0].Card.Suit
+	if plays[0].Suit != led {
+		// Void in the suit led: throw the most dangerous card
+		best := plays[0]
+		for _, c := range plays[1:] {
+			if danger(c) > danger(best) {
+				best = c
+			}
+		}
+		return best
+	}
+
+	// Duck under the winning card with the highest card that still loses
+	winning := cardRank(trick[trickWinner(trick)].Card)
+	var duck *carddeck.Card
+	for i, c := range plays {
+		if cardRank(c) < winning && (duck == nil || cardRank(c) > cardRank(*duck)) {
+			duck = &plays[i]
+		}
+	}
+	if duck != nil {
+		return *duck
+	}
+
+	// Cannot duck. Last to play on a trick without points, take it with the highest
+	// card, otherwise hope someone after plays higher.
+	if len(trick) == NumSeats-1 && trickPoints(trick) == 0 {
+		return highest(plays, func(c carddeck.Card) bool { return c != queenOfSpades })
+	}
+	return lowest(plays, func(carddeck.Card) bool { return true })
+}
+
+// lowest returns the lowest ranked card in cards, preferring cards that match prefer.
+func lowest(cards []carddeck.Card, prefer func(c carddeck.Card) bool) carddeck.Card {
+	return pick(cards, prefer, func(a, b carddeck.Card) bool { return cardRank(a) < cardRank(b) })
+}
+
+// highest returns the highest ranked card in cards, preferring cards that match prefer.
+func highest(cards []carddeck.Card, prefer func(c carddeck.Card) bool) carddeck.Card {
+	return pick(cards, prefer, func(a, b carddeck.Card) bool { return cardRank(a) > cardRank(b) })
+}
+
+// pick returns the best card in cards by better, among those that match prefer if any do.
+func pick(cards []carddeck.Card, prefer func(c carddeck.Card) bool, better func(a, b carddeck.Card) bool) carddeck.Card {
+	pool := filter(cards, prefer)
+	if len(pool) == 0 {
+		pool = cards
+	}
+	best := pool[0]
+	for _, c := range pool[1:] {
+		if better(c, best) {
+			best = c
+		}
+	}
+	return best
+}
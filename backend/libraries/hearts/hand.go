@@ -0,0 +1,263 @@
+package hearts
+
+// hand.go
+// This file contains the play of a game of Hearts: dealing, passing, the tricks and
+// scoring each hand, until a player reaches the end score.
+
+// The pass direction rotates left, right, across and hold, one hand each. After the
+// pass the player holding the two of clubs leads the first trick, and the winner of
+// each trick leads the next. Bots make their moves after a short pause, players have
+// the turn time before a bot moves for them.
+
+import (
+	carddeck "cardgames/backend/libraries/cardDeck"
+)
+
+// tricksPerHand is the number of tricks in a hand, one per card dealt to each seat.
+const tricksPerHand = 13
+
+// startGame resets the scores and deals the first hand. Seats without a player are
+// played by bots.
+func (t *Table) startGame() {
+	for _, s := range t.seats {
+		s.Score = 0
+	}
+	t.handNumber = 0
+	t.winners = nil
+	t.startHand()
+}
+
+// startHand deals a new hand and opens the passing, or goes straight to the first trick
+// on a hold hand.
+func (t *Table) startHand() {
+	t.handNumber++
+	t.trick = nil
+	t.lastTrick = nil
+	t.tricksPlayed = 0
+	t.heartsBroken = false
+	t.moonSeat = -1
+	t.active = -1
+
+	deck := carddeck.New()
+	deck.Shuffle()
+	for _, s := range t.seats {
+		s.Hand = make([]carddeck.Card, 0, tricksPerHand)
+		s.Pass = nil
+		s.Received = nil
+		s.Tricks = 0
+		s.HandPoints = 0
+	}
+	for i := 0; i < tricksPerHand*NumSeats; i++ {
+		s := t.seats[i%NumSeats]
+		s.Hand = append(s.Hand, deck.Draw())
+	}
+	for _, s := range t.seats {
+		sortHand(s.Hand)
+	}
+
+	if t.passDirection() == PassHold {
+		t.beginPlay()
+		return
+	}
+
+	t.phase = Passing
+	for _, s := range t.seats {
+		if s.isBot() {
+			s.Pass = botPass(s.Hand)
+		}
+	}
+	t.schedule(t.turnTime())
+	t.broadcast()
+}
+
+// passDirection returns where cards are passed in the current hand.
+func (t *Table) passDirection() PassDirection {
+	if t.handNumber == 0 {
+		return ""
+	}
+	return passDirections[(t.handNumber-1)%len(passDirections)]
+}
+
+// passTarget returns the seat that receives the cards passed by seat. Seats are
+// numbered clockwise, so the seat to the left is the next one.
+func passTarget(seat int, dir PassDirection) int {
+	switch dir {
+	case PassLeft:
+		return (seat + 1) % NumSeats
+	case PassAcross:
+		return (seat + 2) % NumSeats
+	case PassRight:
+		return (seat + 3) % NumSeats
+	}
+	return seat
+}
+
+// choosePass records the three cards a seat passes. It returns a notice for the player
+// if the cards are not allowed, in which case nothing changes.
+func (t *Table) choosePass(s *Seat, cards []carddeck.Card) string {
+	if t.phase != Passing {
+		return "Cards are not being passed now"
+	}
+	if s.Pass != nil {
+		return "You have already passed"
+	}
+	if len(cards) != 3 {
+		return "You must pass three cards"
+	}
+	hand := s.Hand
+	for _, c := range cards {
+		if !contains(hand, c) {
+			return "You can only pass cards in your hand"
+		}
+		hand = remove(hand, c)
+	}
+	s.Pass = append([]carddeck.Card(nil), cards...)
+	return ""
+}
+
+// maybeExchange passes the chosen cards once every seat has chosen, then starts the
+// first trick.
+func (t *Table) maybeExchange() {
+	if t.phase != Passing {
+		return
+	}
+	for _, s := range t.seats {
+		if s.Pass == nil {
+			return
+		}
+	}
+
+	dir := t.passDirection()
+	for _, s := range t.seats {
+		for _, c := range s.Pass {
+			s.Hand = remove(s.Hand, c)
+		}
+		t.seats[passTarget(s.Index, dir)].Received = s.Pass
+	}
+	for _, s := range t.seats {
+		s.Hand = append(s.Hand, s.Received...)
+		sortHand(s.Hand)
+	}
+	t.beginPlay()
+}
+
+// beginPlay gives the first lead to the seat holding the two of clubs.
+func (t *Table) beginPlay() {
+	t.phase = Playing
+	for _, s := range t.seats {
+		if contains(s.Hand, twoOfClubs) {
+			t.active = s.Index
+		}
+	}
+	t.scheduleTurn()
+	t.broadcast()
+}
+
+// scheduleTurn arms the timer for the seat whose turn it is: a short pause for a bot,
+// the turn time for a player.
+func (t *Table) scheduleTurn() {
+	if t.seats[t.active].isBot() {
+		t.schedule(botDelay)
+	} else {
+		t.schedule(t.turnTime())
+	}
+}
+
+// resume restarts the timer after the game was paused, or gives a player who took a
+// seat over from a bot the full time for its move.
+func (t *Table) resume() {
+	switch t.phase {
+	case Passing:
+		t.schedule(t.turnTime())
+	case Playing:
+		if len(t.trick) == NumSeats {
+			t.schedule(trickDelay)
+		} else {
+			t.scheduleTurn()
+		}
+	case HandOver:
+		t.schedule(handOverDelay)
+	}
+}
+
+// play puts a card from the seat's hand on the trick and passes the turn on. The card
+// must already have been checked against validPlays.
+func (t *Table) play(s *Seat, c carddeck.Card) {
+	s.Hand = remove(s.Hand, c)
+	t.trick = append(t.trick, PlayedCard{Seat: s.Index, Card: c})
+	if c.Suit == carddeck.Hearts {
+		t.heartsBroken = true
+	}
+
+	if len(t.trick) == NumSeats {
+		// Leave the full trick up for a moment before it is taken
+		t.schedule(trickDelay)
+	} else {
+		t.active = (t.active + 1) % NumSeats
+		t.scheduleTurn()
+	}
+	t.broadcast()
+}
+
+// collectTrick gives the trick to the seat that won it, who leads the next one. The
+// hand is scored after the last trick.
+func (t *Table) collectTrick() {
+	winner := t.seats[t.trick[trickWinner(t.trick)].Seat]
+	winner.Tricks++
+	winner.HandPoints += trickPoints(t.trick)
+	t.lastTrick = t.trick
+	t.trick = nil
+	t.tricksPlayed++
+
+	if t.tricksPlayed == tricksPerHand {
+		t.endHand()
+		return
+	}
+	t.active = winner.Index
+	t.scheduleTurn()
+	t.broadcast()
+}
+
+// endHand adds the hand's points to the scores. The game is over once a seat reaches
+// the end score, and the seats with the lowest score win.
+func (t *Table) endHand() {
+	t.active = -1
+
+	var taken [NumSeats]int
+	for i, s := range t.seats {
+		taken[i] = s.HandPoints
+		if s.HandPoints == moonPoints {
+			t.moonSeat = i
+		}
+	}
+	scores := handScores(taken)
+	t.handsPlayed++
+	over := false
+	for i, s := range t.seats {
+		s.Score += scores[i]
+		if s.Score >= t.rules.EndScore {
+			over = true
+		}
+	}
+
+	if !over {
+		t.phase = HandOver
+		t.schedule(handOverDelay)
+		t.saveSnapshot()
+		t.broadcast()
+		return
+	}
+
+	t.phase = GameOver
+	best := t.seats[0].Score
+	for _, s := range t.seats {
+		best = min(best, s.Score)
+	}
+	for _, s := range t.seats {
+		if s.Score == best {
+			t.winners = append(t.winners, s.Index)
+		}
+	}
+	t.saveSnapshot()
+	t.broadcast()
+}
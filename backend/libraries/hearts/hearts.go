@@ -0,0 +1,626 @@
+package hearts
+
+// hearts.go
+// This file contains the main logic for a four player Hearts table: seating, bots, the
+// game loop and the state sent to players.
+
+// Unlike the casino games every player has a hand only they may see, and play moves
+// around the table one turn at a time. Each player gets their own view of the table:
+// their cards, and only the number of cards everyone else holds.
+
+// A game starts once all four seats are taken, or earlier if a seated player asks for
+// bots to fill the empty seats. A player who leaves during a game hands their seat to a
+// bot, and the bot hands it back if they, or a new player, join before the game ends.
+// The play of a hand (passing, tricks and scoring) is in hand.go.
+
+// All table state is guarded by the table mutex. The game loop takes it for every
+// player action and timer event, and Join and Leave take it from the WebSocket handlers.
+
+import (
+	carddeck "cardgames/backend/libraries/cardDeck"
+	"cardgames/backend/libraries/game"
+	"cardgames/backend/models"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// ------------------------------------------------------------------
+// Constants and Types
+// ------------------------------------------------------------------
+const (
+	NumSeats      = 4
+	TurnTimeLimit = 30                     // seconds
+	startDelay    = 2 * time.Second        // pause before the first hand once the table is full
+	botDelay      = 800 * time.Millisecond // pause before a bot plays, so players can follow
+	trickDelay    = 1500 * time.Millisecond
+	handOverDelay = 6 * time.Second // time the hand scores stay up before the next deal
+)
+
+// Phase represents the current phase of the table.
+type Phase string
+
+const (
+	Waiting  Phase = "waiting"   // waiting for players before the first hand
+	Passing  Phase = "passing"   // players are choosing the cards to pass
+	Playing  Phase = "playing"   // tricks are being played
+	HandOver Phase = "hand_over" // the hand is scored, the next one is about to be dealt
+	GameOver Phase = "game_over" // a player reached the end score
+)
+
+// PassDirection is where the passed cards go in a hand. It rotates each hand.
+type PassDirection string
+
+const (
+	PassLeft   PassDirection = "left"
+	PassRight  PassDirection = "right"
+	PassAcross PassDirection = "across"
+	PassHold   PassDirection = "hold" // no cards are passed
+)
+
+// passDirections is the order the pass direction rotates through.
+var passDirections = [...]PassDirection{PassLeft, PassRight, PassAcross, PassHold}
+
+// Action represents the type of action a player can take.
+type Action string
+
+const (
+	PassAction  Action = "pass"  // pass the three Cards
+	PlayAction  Action = "play"  // play Card to the trick
+	StartAction Action = "start" // fill the empty seats with bots and start, or start a new game once one is over
+	LeaveAction Action = "leave"
+)
+
+// IncomingUpdate is a message from a player to the table.
+type IncomingUpdate struct {
+	PlayerID uint
+	Action   Action
+	Cards    []carddeck.Card // Optional, only used for PassAction
+	Card     carddeck.Card   // Optional, only used for PlayAction
+}
+
+// OutgoingUpdate is a message from the table to a player.
+type OutgoingUpdate struct {
+	Phase         Phase
+	YourID        uint
+	YourSeat      int
+	YourHand      []carddeck.Card
+	MoonSeat      int             // seat that shot the moon last hand, -1 if nobody did
+	ValidPlays    []carddeck.Card `json:",omitempty"` // cards this player may play, set on their turn
+	Passed        []carddeck.Card `json:",omitempty"` // cards this player chose to pass this hand
+	Received      []carddeck.Card `json:",omitempty"` // cards passed to this player this hand
+	PassDirection PassDirection
+	HandNumber    int
+	ActiveSeat    int // seat whose turn it is, -1 when nobody's
+	Trick         []PlayedCard
+	LastTrick     []PlayedCard `json:",omitempty"`
+	HeartsBroken  bool
+	Seats         []SeatInfo
+	Winners       []int  `json:",omitempty"` // seats with the lowest score, set when the game is over
+	Notice        string `json:",omitempty"` // message for this player only
+	Rules         Rules
+}
+
+// SeatInfo contains public information about a seat.
+type SeatInfo struct {
+	Seat       int
+	PlayerID   uint // 0 for an empty seat or a bot
+	Username   string
+	Bot        bool
+	CardCount  int
+	HasPassed  bool
+	Tricks     int // tricks taken this hand
+	HandPoints int // points taken this hand
+	Score      int
+}
+
+//------------------------------------------------------------------
+// Structs
+//------------------------------------------------------------------
+
+// Player is a person connected to a seat.
+type Player struct {
+	ID       uint
+	Account  *models.Account
+	Incoming chan json.RawMessage
+	Outgoing chan any
+	stop     chan struct{}
+}
+
+// Seat is one of the four places at the table. It keeps its cards and score whether a
+// player or a bot is playing it.
+type Seat struct {
+	Index      int
+	Player     *Player // nil when the seat is empty, or played by a bot once a game has started
+	LastID     uint    // last player to sit here, who gets the seat back from the bot on rejoining
+	Hand       []carddeck.Card
+	Pass       []carddeck.Card // cards chosen to pass this hand
+	Received   []carddeck.Card // cards passed to this seat this hand
+	Tricks     int
+	HandPoints int
+	Score      int
+}
+
+// isBot reports whether a bot is playing the seat.
+func (s *Seat) isBot() bool {
+	return s.Player == nil
+}
+
+// Options customizes a Hearts table.
+type Options struct {
+	Rules      *Rules                      // table rules, defaults to DefaultRules
+	HostID     uint                        // creator of a private table
+	Password   string                      // optional password required to join
+	OnSnapshot func(state json.RawMessage) // optional hook the table state is saved through, see snapshot.go
+}
+
+// Table is a Hearts table.
+type Table struct {
+	DB           *gorm.DB
+	seats        [NumSeats]*Seat
+	phase        Phase
+	handNumber   int  // hands dealt this game, the first is 1
+	handsPlayed  int  // hands scored since the table opened
+	paused       bool // no new game or hand is dealt
+	active       int  // seat whose turn it is, -1 when nobody's
+	trick        []PlayedCard
+	lastTrick    []PlayedCard
+	tricksPlayed int
+	heartsBroken bool
+	moonSeat     int
+	winners      []int
+	rules        Rules
+	hostID       uint
+	passwordHash []byte
+	onSnapshot   func(state json.RawMessage)
+	heldUntil    time.Time // seats of a restored table are kept for their last player until then
+	incoming     chan IncomingUpdate
+	timer        *time.Timer
+	idleSince    time.Time
+	done         chan struct{}
+	closeOnce    sync.Once
+	mu           sync.Mutex
+}
+
+// NewTable creates a Hearts table customized by opts and starts its game loop.
+func NewTable(db *gorm.DB, opts Options) *Table {
+	t := newTable(db, opts)
+	go t.gameLoop()
+	return t
+}
+
+// newTable creates a Hearts table customized by opts without starting its game loop.
+func newTable(db *gorm.DB, opts Options) *Table {
+	t := &Table{
+		DB:         db,
+		phase:      Waiting,
+		active:     -1,
+		moonSeat:   -1,
+		rules:      DefaultRules(),
+		hostID:     opts.HostID,
+		onSnapshot: opts.OnSnapshot,
+		incoming:   make(chan IncomingUpdate),
+		timer:      time.NewTimer(time.Hour),
+		idleSince:  time.Now(),
+		done:       make(chan struct{}),
+	}
+	t.timer.Stop()
+	if opts.Rules != nil {
+		t.rules = *opts.Rules
+	}
+	for i := range t.seats {
+		t.seats[i] = &Seat{Index: i}
+	}
+	if opts.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(opts.Password), bcrypt.DefaultCost)
+		if err != nil {
+			log.Println("Failed to hash table password:", err)
+		} else {
+			t.passwordHash = hash
+		}
+	}
+
+	return t
+}
+
+//------------------------------------------------------------------
+// Seating
+//------------------------------------------------------------------
+
+// Join seats a player, or reconnects them if they already have a seat. During a game a
+// new player takes over a seat from a bot, preferring the one they sat in before.
+func (t *Table) Join(req game.JoinRequest) (*game.Seat, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	select {
+	case <-t.done:
+		return nil, game.ErrTableClosed
+	default:
+	}
+
+	if p := t.findPlayer(req.PlayerID); p != nil {
+		// Reconnect: close the old connection's channels and hand out new ones
+		t.detach(p)
+		p.Incoming = make(chan json.RawMessage)
+		p.Outgoing = make(chan any, 10)
+		t.updateIdle()
+		t.startForwarding(p)
+		return seatOf(p), nil
+	}
+
+	if !req.Invited && t.passwordHash != nil && bcrypt.CompareHashAndPassword(t.passwordHash, []byte(req.Password)) != nil {
+		return nil, game.ErrWrongPassword
+	}
+
+	seat := t.openSeat(req.PlayerID)
+	if seat == nil {
+		return nil, game.ErrTableFull
+	}
+
+	var account models.Account
+	if err := t.DB.First(&account, req.PlayerID).Error; err != nil {
+		return nil, game.ErrNotAllowed
+	}
+
+	p := &Player{
+		ID:       req.PlayerID,
+		Account:  &account,
+		Incoming: make(chan json.RawMessage),
+		Outgoing: make(chan any, 10),
+	}
+	seat.Player = p
+	seat.LastID = p.ID
+	t.updateIdle()
+	t.startForwarding(p)
+
+	if t.phase == Waiting && t.humans() == NumSeats {
+		t.schedule(startDelay)
+	} else if t.inGame() {
+		t.resume()
+	}
+	t.broadcast()
+
+	return seatOf(p), nil
+}
+
+// openSeat returns the seat a new player should take, or nil if every seat has a player.
+// The seats of a restored table are only given to their last player while they are held.
+func (t *Table) openSeat(playerID uint) *Seat {
+	for _, s := range t.seats {
+		if s.Player == nil && s.LastID == playerID {
+			return s
+		}
+	}
+	held := time.Now().Before(t.heldUntil)
+	for _, s := range t.seats {
+		if s.Player == nil && (s.LastID == 0 || !held) {
+			return s
+		}
+	}
+	return nil
+}
+
+// seatOf returns the seat handed to a player's connection.
+func seatOf(p *Player) *game.Seat {
+	return &game.Seat{PlayerID: p.ID, Actions: p.Incoming, State: p.Outgoing}
+}
+
+// Leave gives the player's seat up. During a game a bot takes the seat over, keeping
+// its cards and score. A seat the player has since reconnected on is left alone.
+func (t *Table) Leave(seat *game.Seat) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if s := t.seatOf(seat.PlayerID); s != nil && s.Player.Outgoing == seat.State {
+		t.vacate(s)
+		t.broadcast()
+	}
+}
+
+// vacate takes the player off a seat and, if it is their move, lets the bot make it.
+// The game is paused while nobody is left to watch it.
+func (t *Table) vacate(s *Seat) {
+	t.detach(s.Player)
+	s.Player = nil
+	t.updateIdle()
+
+	if t.inGame() && t.humans() == 0 {
+		t.timer.Stop()
+		return
+	}
+	switch t.phase {
+	case Waiting:
+		t.timer.Stop() // the table is no longer full
+	case Passing:
+		if s.Pass == nil {
+			s.Pass = botPass(s.Hand)
+			t.maybeExchange()
+		}
+	case Playing:
+		if t.active == s.Index && len(t.trick) < NumSeats {
+			t.schedule(botDelay)
+		}
+	}
+}
+
+// IsSeated reports whether the player already has a seat at the table.
+func (t *Table) IsSeated(playerID uint) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.findPlayer(playerID) != nil
+}
+
+// Seats returns the number of seats taken by players and the size of the table.
+// Seats played by bots count as free, a new player can take them over.
+func (t *Table) Seats() (int, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.humans(), NumSeats
+}
+
+// Describe summarizes the table for the lobby's table browser. Hearts is not played
+// for chips and every player plays, so there are no stakes or spectators. Bots only
+// play the empty seats once a game is under way.
+func (t *Table) Describe() game.Summary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ids := make([]uint, 0, NumSeats)
+	for _, s := range t.seats {
+		if s.Player != nil {
+			ids = append(ids, s.Player.ID)
+		}
+	}
+	bots := 0
+	if t.inGame() {
+		bots = NumSeats - len(ids)
+	}
+	return game.Summary{
+		Phase:         string(t.phase),
+		Rules:         fmt.Sprintf("game to %d points", t.rules.EndScore),
+		TurnSeconds:   t.rules.TurnSeconds,
+		Bots:          bots,
+		BetweenRounds: !t.inGame(),
+		PlayerIDs:     ids,
+	}
+}
+
+// HostID returns the player who created the table, 0 for public tables.
+func (t *Table) HostID() uint {
+	return t.hostID
+}
+
+// KeepWhenEmpty reports whether the table must not be cleaned up when idle. Hearts is
+// played for points only, so nothing is lost when a table is closed.
+func (t *Table) KeepWhenEmpty() bool {
+	return false
+}
+
+// Greet sends a newly connected player the table state.
+func (t *Table) Greet(playerID uint) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if s := t.seatOf(playerID); s != nil {
+		t.send(s, "")
+	}
+}
+
+// seatOf returns the seat of the player with the given ID, or nil.
+func (t *Table) seatOf(playerID uint) *Seat {
+	for _, s := range t.seats {
+		if s.Player != nil && s.Player.ID == playerID {
+			return s
+		}
+	}
+	return nil
+}
+
+// findPlayer returns the seated player with the given ID, or nil.
+func (t *Table) findPlayer(playerID uint) *Player {
+	if s := t.seatOf(playerID); s != nil {
+		return s.Player
+	}
+	return nil
+}
+
+// humans returns the number of seats taken by players.
+func (t *Table) humans() int {
+	n := 0
+	for _, s := range t.seats {
+		if s.Player != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// inGame reports whether a game is being played.
+func (t *Table) inGame() bool {
+	return t.phase != Waiting && t.phase != GameOver
+}
+
+//------------------------------------------------------------------
+// Game Loop
+//------------------------------------------------------------------
+
+// gameLoop handles player actions and timer events until the table is closed.
+func (t *Table) gameLoop() {
+	defer t.shutdown()
+
+	for {
+		select {
+		case <-t.done:
+			return
+		case update := <-t.incoming:
+			t.mu.Lock()
+			t.processUpdate(update)
+			t.mu.Unlock()
+		case <-t.timer.C:
+			t.mu.Lock()
+			t.onTimer()
+			t.mu.Unlock()
+		}
+	}
+}
+
+// schedule arms the table timer. Must be called with t.mu held.
+func (t *Table) schedule(d time.Duration) {
+	t.timer.Reset(d)
+}
+
+// turnTime returns the time a player has to pass or play.
+func (t *Table) turnTime() time.Duration {
+	return time.Duration(t.rules.TurnSeconds) * time.Second
+}
+
+// onTimer moves the table along when the timer fires: it starts the game or the next
+// hand, collects a finished trick, or has a bot move for a bot seat or a player who
+// ran out of time.
+func (t *Table) onTimer() {
+	switch t.phase {
+	case Waiting:
+		if t.humans() == NumSeats && !t.paused {
+			t.startGame()
+		}
+	case HandOver:
+		if t.paused {
+			return
+		}
+		t.startHand()
+	case Passing:
+		for _, s := range t.seats {
+			if s.Pass == nil {
+				s.Pass = botPass(s.Hand)
+				if !s.isBot() {
+					t.send(s, "You ran out of time, cards were passed for you")
+				}
+			}
+		}
+		t.maybeExchange()
+	case Playing:
+		if len(t.trick) == NumSeats {
+			t.collectTrick()
+			return
+		}
+		s := t.seats[t.active]
+		if !s.isBot() {
+			t.send(s, "You ran out of time, a card was played for you")
+		}
+		t.play(s, botPlay(s.Hand, t.trick, t.tricksPlayed == 0, t.heartsBroken))
+	}
+}
+
+// processUpdate handles one message from a player.
+func (t *Table) processUpdate(update IncomingUpdate) {
+	s := t.seatOf(update.PlayerID)
+	if s == nil {
+		return
+	}
+
+	switch update.Action {
+	case PassAction:
+		if notice := t.choosePass(s, update.Cards); notice != "" {
+			t.send(s, notice)
+			return
+		}
+		t.maybeExchange()
+	case PlayAction:
+		if t.phase != Playing || t.active != s.Index || len(t.trick) == NumSeats {
+			t.send(s, "It is not your turn")
+			return
+		}
+		if !contains(validPlays(s.Hand, t.trick, t.tricksPlayed == 0, t.heartsBroken), update.Card) {
+			t.send(s, "You cannot play that card")
+			return
+		}
+		t.play(s, update.Card)
+		return
+	case StartAction:
+		if t.inGame() {
+			return
+		}
+		if t.paused {
+			t.send(s, "The table is paused")
+			return
+		}
+		t.timer.Stop()
+		t.startGame()
+		return
+	case LeaveAction:
+		t.vacate(s)
+	default:
+		return
+	}
+	t.broadcast()
+}
+
+//------------------------------------------------------------------
+// Updates
+//------------------------------------------------------------------
+
+// broadcast sends every seated player their view of the table.
+func (t *Table) broadcast() {
+	for _, s := range t.seats {
+		if s.Player != nil {
+			t.send(s, "")
+		}
+	}
+}
+
+// send delivers a seated player's view of the table without blocking the game loop.
+// Other seats' cards are never included, only how many they hold.
+func (t *Table) send(s *Seat, notice string) {
+	p := s.Player
+	update := OutgoingUpdate{
+		Phase:         t.phase,
+		YourID:        p.ID,
+		YourSeat:      s.Index,
+		YourHand:      s.Hand,
+		Passed:        s.Pass,
+		Received:      s.Received,
+		PassDirection: t.passDirection(),
+		HandNumber:    t.handNumber,
+		MoonSeat:      t.moonSeat,
+		ActiveSeat:    t.active,
+		Trick:         t.trick,
+		LastTrick:     t.lastTrick,
+		HeartsBroken:  t.heartsBroken,
+		Winners:       t.winners,
+		Notice:        notice,
+		Rules:         t.rules,
+	}
+	if t.phase == Playing && t.active == s.Index && len(t.trick) < NumSeats {
+		update.ValidPlays = validPlays(s.Hand, t.trick, t.tricksPlayed == 0, t.heartsBroken)
+	}
+
+	for _, other := range t.seats {
+		info := SeatInfo{
+			Seat:       other.Index,
+			Bot:        other.isBot() && t.phase != Waiting,
+			CardCount:  len(other.Hand),
+			HasPassed:  other.Pass != nil,
+			Tricks:     other.Tricks,
+			HandPoints: other.HandPoints,
+			Score:      other.Score,
+		}
+		if other.Player != nil {
+			info.PlayerID = other.Player.ID
+			info.Username = other.Player.Account.Username
+		} else if info.Bot {
+			info.Username = fmt.Sprintf("Bot %d", other.Index+1)
+		}
+		update.Seats = append(update.Seats, info)
+	}
+
+	select {
+	case p.Outgoing <- update:
+	default:
+		log.Println("Failed to send update to player", p.ID)
+	}
+}
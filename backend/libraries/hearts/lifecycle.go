@@ -0,0 +1,103 @@
+package hearts
+
+// lifecycle.go
+// This file contains the lifecycle of a Hearts table: closing it, the goroutines that
+// forward player input to the game loop, and idle detection for the game instance manager.
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Close stops the game loop and closes the players' Outgoing channels. It is safe to
+// call more than once.
+func (t *Table) Close() {
+	t.closeOnce.Do(func() {
+		close(t.done)
+	})
+}
+
+// Suspend closes the table for a server restart. Hearts plays for no chips, so there
+// is nothing to leave to the snapshot and it is the same as Close.
+func (t *Table) Suspend() {
+	t.Close()
+}
+
+// Done returns a channel that is closed once the table has been closed.
+func (t *Table) Done() <-chan struct{} {
+	return t.done
+}
+
+// IdleFor returns how long the table has had no players, or 0 if someone is seated
+// right now.
+func (t *Table) IdleFor() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.idleSince.IsZero() {
+		return 0
+	}
+	return time.Since(t.idleSince)
+}
+
+// updateIdle records when the last player left. Must be called with t.mu held.
+func (t *Table) updateIdle() {
+	if t.humans() > 0 {
+		t.idleSince = time.Time{}
+		return
+	}
+	if t.idleSince.IsZero() {
+		t.idleSince = time.Now()
+	}
+}
+
+// startForwarding starts the goroutine that moves a player's input onto the game loop.
+// Messages that are not valid updates are dropped, and every update is marked as
+// coming from the seated player whatever the client put in PlayerID.
+func (t *Table) startForwarding(p *Player) {
+	p.stop = make(chan struct{})
+	go func(playerID uint, incoming chan json.RawMessage, stop chan struct{}) {
+		for {
+			select {
+			case raw := <-incoming:
+				var update IncomingUpdate
+				if err := json.Unmarshal(raw, &update); err != nil {
+					continue
+				}
+				update.PlayerID = playerID
+				select {
+				case t.incoming <- update:
+				case <-stop:
+					return
+				case <-t.done:
+					return
+				}
+			case <-stop:
+				return
+			case <-t.done:
+				return
+			}
+		}
+	}(p.ID, p.Incoming, p.stop)
+}
+
+// detach stops a player's forwarding goroutine and closes their Outgoing channel.
+// Incoming is left open: the WebSocket reader may still be sending on it.
+func (t *Table) detach(p *Player) {
+	close(p.stop)
+	close(p.Outgoing)
+}
+
+// shutdown detaches every player once the game loop has stopped.
+func (t *Table) shutdown() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.timer.Stop()
+	for _, s := range t.seats {
+		if s.Player != nil {
+			t.detach(s.Player)
+			s.Player = nil
+		}
+	}
+}
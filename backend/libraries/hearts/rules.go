@@ -0,0 +1,188 @@
+package hearts
+
+// rules.go
+// This file contains the rules of Hearts that do not depend on the table: card ranks,
+// which cards a player may play, who wins a trick, the points in a trick and shooting
+// the moon. It also holds the table settings, which private tables can change.
+
+import (
+	carddeck "cardgames/backend/libraries/cardDeck"
+	"errors"
+	"sort"
+)
+
+// ErrInvalidRules is returned when a Rules value fails validation.
+var ErrInvalidRules = errors.New("invalid table rules")
+
+// Rules are the settings a Hearts table plays by.
+type Rules struct {
+	EndScore    int // the game ends after the hand in which a player reaches this score
+	TurnSeconds int // time each player has to pass or play before a bot does it for them
+}
+
+// DefaultRules returns the rules used by tables that do not set their own.
+func DefaultRules() Rules {
+	return Rules{
+		EndScore:    100,
+		TurnSeconds: TurnTimeLimit,
+	}
+}
+
+// Validate checks that the rules describe a playable table.
+func (r Rules) Validate() error {
+	if r.EndScore < 25 || r.EndScore > 500 {
+		return ErrInvalidRules
+	}
+	if r.TurnSeconds < 5 || r.TurnSeconds > 120 {
+		return ErrInvalidRules
+	}
+	return nil
+}
+
+// Cards with a special meaning in Hearts.
+var (
+	twoOfClubs    = carddeck.Card{Suit: carddeck.Clubs, Value: carddeck.Two}
+	queenOfSpades = carddeck.Card{Suit: carddeck.Spades, Value: carddeck.Queen}
+)
+
+// moonPoints is the number of points in a deck, taking all of them shoots the moon.
+const moonPoints = 26
+
+// cardRank returns the rank of a card from 2 up to 14 for an ace.
+func cardRank(c carddeck.Card) int {
+	return c.Value.High()
+}
+
+// suitOrder is the order suits are sorted in a hand.
+var suitOrder = map[carddeck.Suit]int{carddeck.Clubs: 0, carddeck.Diamonds: 1, carddeck.Spades: 2, carddeck.Hearts: 3}
+
+// sortHand sorts a hand by suit, then by rank.
+func sortHand(hand []carddeck.Card) {
+	sort.Slice(hand, func(i, j int) bool {
+		if hand[i].Suit != hand[j].Suit {
+			return suitOrder[hand[i].Suit] < suitOrder[hand[j].Suit]
+		}
+		return cardRank(hand[i]) < cardRank(hand[j])
+	})
+}
+
+// cardPoints returns the penalty points a card is worth: 1 for a heart, 13 for the queen of spades.
+func cardPoints(c carddeck.Card) int {
+	switch {
+	case c.Suit == carddeck.Hearts:
+		return 1
+	case c == queenOfSpades:
+		return 13
+	}
+	return 0
+}
+
+// PlayedCard is a card played to a trick and the seat that played it.
+type PlayedCard struct {
+	Seat int
+	Card carddeck.Card
+}
+
+// trickPoints returns the penalty points in a trick.
+func trickPoints(trick []PlayedCard) int {
+	points := 0
+	for _, pc := range trick {
+		points += cardPoints(pc.Card)
+	}
+	return points
+}
+
+// trickWinner returns the index in trick of the card that takes it: the highest card
+// of the suit that was led.
+func trickWinner(trick []PlayedCard) int {
+	best := 0
+	for i, pc := range trick {
+		if pc.Card.Suit == trick[0].Card.Suit && cardRank(pc.Card) > cardRank(trick[best].Card) {
+			best = i
+		}
+	}
+	return best
+}
+
+// validPlays returns the cards in hand that may be played to trick.
+//
+// The first trick must be led with the two of clubs, and nobody may throw points on it
+// unless they hold nothing else. Players must follow the suit led if they can. Hearts
+// cannot be led until a heart has been played, unless the hand holds only hearts.
+func validPlays(hand []carddeck.Card, trick []PlayedCard, firstTrick, heartsBroken bool) []carddeck.Card {
+	if len(trick) == 0 {
+		if firstTrick {
+			for _, c := range hand {
+				if c == twoOfClubs {
+					return []carddeck.Card{c}
+				}
+			}
+		}
+		if !heartsBroken {
+			if plays := filter(hand, func(c carddeck.Card) bool { return c.Suit != carddeck.Hearts }); len(plays) > 0 {
+				return plays
+			}
+		}
+		return hand
+	}
+
+	led := trick[0].Card.Suit
+	if plays := filter(hand, func(c carddeck.Card) bool { return c.Suit == led }); len(plays) > 0 {
+		return plays
+	}
+	if firstTrick {
+		if plays := filter(hand, func(c carddeck.Card) bool { return cardPoints(c) == 0 }); len(plays) > 0 {
+			return plays
+		}
+	}
+	return hand
+}
+
+// filter returns the cards in hand that match keep.
+func filter(hand []carddeck.Card, keep func(c carddeck.Card) bool) []carddeck.Card {
+	var out []carddeck.Card
+	for _, c := range hand {
+		if keep(c) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// contains reports whether cards holds c.
+func contains(cards []carddeck.Card, c carddeck.Card) bool {
+	for _, have := range cards {
+		if have == c {
+			return true
+		}
+	}
+	return false
+}
+
+// remove returns hand without the first copy of c.
+func remove(hand []carddeck.Card, c carddeck.Card) []carddeck.Card {
+	for i, have := range hand {
+		if have == c {
+			return append(hand[:i:i], hand[i+1:]...)
+		}
+	}
+	return hand
+}
+
+// handScores turns the points each seat took in a hand into the points added to their
+// score. A seat that took every point shoots the moon: it scores nothing and every
+// other seat scores 26.
+func handScores(taken [NumSeats]int) [NumSeats]int {
+	for seat, points := range taken {
+		if points == moonPoints {
+			var scores [NumSeats]int
+			for other := range scores {
+				if other != seat {
+					scores[other] = moonPoints
+				}
+			}
+			return scores
+		}
+	}
+	return taken
+}
@@ -0,0 +1,88 @@
+package hearts
+
+// snapshot.go
+// This file contains saving a Hearts table so it survives a server restart, and
+// restoring it. The table is saved after every hand, with the scores and who sat where.
+
+// A game cut short by the restart carries on from the last hand that was scored: the
+// table comes back between hands with bots in every seat, waiting for someone to join.
+// Each seat is kept for the player who last sat in it for game.SeatHoldTime, so nobody
+// else can take it over from the bot before they reconnect.
+
+import (
+	"cardgames/backend/libraries/game"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// snapshot is the saved state of a table.
+type snapshot struct {
+	Rules        Rules
+	HostID       uint
+	PasswordHash []byte `json:",omitempty"`
+	InGame       bool
+	HandNumber   int
+	HandsPlayed  int
+	MoonSeat     int
+	Winners      []int `json:",omitempty"` // set when the game is over
+	Seats        [NumSeats]seatSnapshot
+}
+
+// seatSnapshot is the saved state of a seat.
+type seatSnapshot struct {
+	LastID uint
+	Score  int
+}
+
+// saveSnapshot hands the table state to the OnSnapshot hook, if there is one.
+// Must be called with t.mu held.
+func (t *Table) saveSnapshot() {
+	if t.onSnapshot == nil {
+		return
+	}
+
+	snap := snapshot{
+		Rules:        t.rules,
+		HostID:       t.hostID,
+		PasswordHash: t.passwordHash,
+		InGame:       t.inGame(),
+		HandNumber:   t.handNumber,
+		HandsPlayed:  t.handsPlayed,
+		MoonSeat:     t.moonSeat,
+		Winners:      t.winners,
+	}
+	for i, s := range t.seats {
+		snap.Seats[i] = seatSnapshot{LastID: s.LastID, Score: s.Score}
+	}
+
+	state, err := json.Marshal(snap)
+	if err != nil {
+		log.Println("Failed to encode Hearts snapshot:", err)
+		return
+	}
+	t.onSnapshot(state)
+}
+
+// restore sets the table up from a snapshot. It is called before the game loop starts.
+func (t *Table) restore(snap snapshot) {
+	t.rules = snap.Rules
+	t.hostID = snap.HostID
+	t.passwordHash = snap.PasswordHash
+	t.handNumber = snap.HandNumber
+	t.handsPlayed = snap.HandsPlayed
+	t.moonSeat = snap.MoonSeat
+	t.winners = snap.Winners
+	for i, s := range t.seats {
+		s.LastID = snap.Seats[i].LastID
+		s.Score = snap.Seats[i].Score
+	}
+	switch {
+	case snap.InGame:
+		// The next hand is dealt once a player is back
+		t.phase = HandOver
+	case snap.Winners != nil:
+		t.phase = GameOver
+	}
+	t.heldUntil = time.Now().Add(game.SeatHoldTime)
+}
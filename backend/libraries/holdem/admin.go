@@ -0,0 +1,109 @@
+package holdem
+
+// admin.go
+// This file contains what admins see of a Hold'em table, every player's hole cards and
+// the deck included, and pausing the table between hands.
+
+import (
+	carddeck "cardgames/backend/libraries/cardDeck"
+	"cardgames/backend/libraries/game"
+)
+
+// AdminState is the full state of a Hold'em table for admins.
+type AdminState struct {
+	Rules      Rules
+	Seats      []*AdminPlayer // indexed by seat number, nil for an empty seat
+	Board      []carddeck.Card
+	Deck       []carddeck.Card // cards left to deal, in dealing order
+	ButtonSeat int
+	ActiveSeat int
+	CurrentBet int
+	MinRaise   int
+	Pots       []Pot
+	HostID     uint
+}
+
+// AdminPlayer is a seated player as admins see them.
+type AdminPlayer struct {
+	ID         uint
+	Username   string
+	Stack      int
+	Bet        int
+	Committed  int
+	Hole       []carddeck.Card
+	InHand     bool
+	Folded     bool
+	AllIn      bool
+	SittingOut bool
+	Leaving    bool
+	Connected  bool
+}
+
+// Inspect returns the table state for admins.
+func (t *Table) Inspect() game.Inspection {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state := AdminState{
+		Rules:      t.rules,
+		Seats:      make([]*AdminPlayer, len(t.seats)),
+		Board:      append([]carddeck.Card(nil), t.board...),
+		Deck:       append([]carddeck.Card(nil), t.deck...),
+		ButtonSeat: t.button,
+		ActiveSeat: t.active,
+		CurrentBet: t.currentBet,
+		MinRaise:   t.minRaise,
+		Pots:       append([]Pot(nil), t.pots...),
+		HostID:     t.hostID,
+	}
+	var ids []uint
+	for i, p := range t.seats {
+		if p == nil {
+			continue
+		}
+		state.Seats[i] = &AdminPlayer{
+			ID:         p.ID,
+			Username:   p.Account.Username,
+			Stack:      p.Stack,
+			Bet:        p.Bet,
+			Committed:  p.Committed,
+			Hole:       append([]carddeck.Card(nil), p.Hole...),
+			InHand:     p.InHand,
+			Folded:     p.Folded,
+			AllIn:      p.AllIn,
+			SittingOut: p.SittingOut,
+			Leaving:    p.Leaving,
+			Connected:  p.Connected,
+		}
+		ids = append(ids, p.ID)
+	}
+
+	return game.Inspection{
+		Phase:        string(t.phase),
+		PlayerIDs:    ids,
+		ShoeDepth:    len(t.deck),
+		RoundsPlayed: t.HandsPlayed,
+		Paused:       t.paused,
+		State:        state,
+	}
+}
+
+// SetPaused stops (or resumes) dealing new hands. The hand in progress is played out.
+func (t *Table) SetPaused(paused bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.paused == paused {
+		return
+	}
+	t.paused = paused
+	if !paused && (t.phase == Waiting || t.phase == Showdown) {
+		t.schedule(startDelay)
+	}
+}
+
+// Paused reports whether the table is paused.
+func (t *Table) Paused() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.paused
+}
@@ -0,0 +1,406 @@
+package holdem
+
+// hand.go
+// This file contains the play of a single hand: moving the button, posting the blinds,
+// dealing, the betting rounds, and settling the pots at showdown.
+
+// A betting round is over once every player who can still bet has acted since the last
+// full raise and matched the current bet. An all-in raise smaller than a full raise
+// does not reopen the betting for players who have already acted: they can only call
+// or fold.
+
+import (
+	carddeck "cardgames/backend/libraries/cardDeck"
+	"cardgames/backend/models"
+	"time"
+)
+
+// startHand finishes off the previous hand and deals a new one if at least two players
+// are ready, otherwise the table waits.
+func (t *Table) startHand() {
+	t.clearHand()
+	t.saveSnapshot()
+
+	ready := t.readyPlayers()
+	if len(ready) < 2 {
+		t.phase = Waiting
+		t.broadcast()
+		return
+	}
+
+	t.hand = ready
+	for _, p := range t.hand {
+		p.InHand = true
+	}
+	t.button = t.nextSeat(t.button, func(p *Player) bool { return p.InHand })
+
+	t.deck = carddeck.New()
+	t.deck.Shuffle()
+	t.phase = PreFlop
+	t.minRaise = t.rules.BigBlind
+
+	// Heads up the button posts the small blind and acts first before the flop
+	sb := t.button
+	if len(t.hand) > 2 {
+		sb = t.nextSeat(t.button, func(p *Player) bool { return p.InHand })
+	}
+	bb := t.nextSeat(sb, func(p *Player) bool { return p.InHand })
+	t.put(t.seats[sb], min(t.rules.SmallBlind, t.seats[sb].Stack))
+	t.put(t.seats[bb], min(t.rules.BigBlind, t.seats[bb].Stack))
+	t.currentBet = t.rules.BigBlind
+
+	// Two cards each, one at a time starting left of the button
+	for round := 0; round < 2; round++ {
+		seat := t.button
+		for range t.hand {
+			seat = t.nextSeat(seat, func(p *Player) bool { return p.InHand })
+			t.seats[seat].Hole = append(t.seats[seat].Hole, t.deck.Draw())
+		}
+	}
+
+	t.active = bb
+	t.nextToAct()
+	t.broadcast()
+}
+
+// clearHand resets the table after a hand and stands up the players who asked to leave.
+func (t *Table) clearHand() {
+	for _, p := range t.seats {
+		if p == nil {
+			continue
+		}
+		if p.Leaving {
+			t.standUp(p)
+			continue
+		}
+		p.Hole = nil
+		p.Bet = 0
+		p.Committed = 0
+		p.InHand = false
+		p.Folded = false
+		p.AllIn = false
+		p.Acted = false
+	}
+	t.hand = nil
+	t.board = nil
+	t.pots = nil
+	t.results = nil
+	t.currentBet = 0
+	t.active = -1
+}
+
+// nextSeat returns the first occupied seat after seat whose player matches want.
+// Returns seat itself if nobody else does.
+func (t *Table) nextSeat(seat int, want func(p *Player) bool) int {
+	n := len(t.seats)
+	for i := 1; i <= n; i++ {
+		s := ((seat+i)%n + n) % n
+		if p := t.seats[s]; p != nil && want(p) {
+			return s
+		}
+	}
+	return seat
+}
+
+// canBet reports whether a player in the hand can still put chips in.
+func canBet(p *Player) bool {
+	return p.InHand && !p.Folded && !p.AllIn
+}
+
+// put moves chips from a player's stack into the current betting round.
+func (t *Table) put(p *Player, amount int) {
+	p.Stack -= amount
+	p.Bet += amount
+	p.Committed += amount
+	if p.Stack == 0 {
+		p.AllIn = true
+	}
+}
+
+// act applies a betting action for the player whose turn it is. It returns a notice
+// for the player if the action is not allowed, in which case nothing changes.
+func (t *Table) act(p *Player, action Action, amount int) string {
+	toCall := t.currentBet - p.Bet
+
+	switch action {
+	case FoldAction:
+		p.Folded = true
+	case CheckAction:
+		if toCall > 0 {
+			return "You cannot check, there is a bet to call"
+		}
+	case CallAction:
+		if toCall <= 0 {
+			return "There is nothing to call"
+		}
+		t.put(p, min(toCall, p.Stack))
+	case AllInAction:
+		if notice := t.raiseTo(p, p.Bet+p.Stack); notice != "" {
+			return notice
+		}
+	case RaiseAction:
+		if amount >= p.Bet+p.Stack {
+			// Raising everything is an all-in, which is allowed below the minimum raise
+			amount = p.Bet + p.Stack
+		} else if amount < t.currentBet+t.minRaise {
+			return "Raise is smaller than the minimum raise"
+		}
+		if notice := t.raiseTo(p, amount); notice != "" {
+			return notice
+		}
+	}
+	p.Acted = true
+	return ""
+}
+
+// raiseTo makes the player's bet this round total chips, or calls if that does not
+// beat the current bet. A full raise reopens the betting for everyone else.
+func (t *Table) raiseTo(p *Player, total int) string {
+	if total <= t.currentBet {
+		// An all-in for less than the bet is a call
+		t.put(p, total-p.Bet)
+		return ""
+	}
+	if p.Acted {
+		return "You can only call or fold, the betting was not reopened"
+	}
+
+	raise := total - t.currentBet
+	t.put(p, total-p.Bet)
+	t.currentBet = total
+	if raise >= t.minRaise {
+		t.minRaise = raise
+		for _, other := range t.hand {
+			if other != p {
+				other.Acted = false
+			}
+		}
+	}
+	return ""
+}
+
+// fold folds a player out of turn, for players who leave the table mid-hand.
+func (t *Table) fold(p *Player) {
+	p.Folded = true
+	if t.active >= 0 && t.seats[t.active] == p {
+		t.advance()
+	} else if t.playersLeft() == 1 {
+		t.endStreet()
+	}
+}
+
+// timeOut acts for a player who ran out of time: a check if that is free, otherwise a
+// fold. The player is sat out until they sit back in.
+func (t *Table) timeOut(p *Player) {
+	if t.currentBet > p.Bet {
+		t.act(p, FoldAction, 0)
+	} else {
+		t.act(p, CheckAction, 0)
+	}
+	p.SittingOut = true
+	t.send(p, "You ran out of time and are sitting out")
+	t.advance()
+	t.broadcast()
+}
+
+// playersLeft returns the number of players in the hand who have not folded.
+func (t *Table) playersLeft() int {
+	left := 0
+	for _, p := range t.hand {
+		if !p.Folded {
+			left++
+		}
+	}
+	return left
+}
+
+// needsToAct reports whether a player still has a decision to make this betting round.
+func (t *Table) needsToAct(p *Player) bool {
+	if !canBet(p) {
+		return false
+	}
+	if p.Bet < t.currentBet {
+		return true
+	}
+	if p.Acted {
+		return false
+	}
+	// Nobody is left to bet against
+	for _, other := range t.hand {
+		if other != p && canBet(other) {
+			return true
+		}
+	}
+	return false
+}
+
+// advance moves the action on after a player has acted.
+func (t *Table) advance() {
+	if t.playersLeft() == 1 {
+		t.endStreet()
+		return
+	}
+	t.nextToAct()
+}
+
+// nextToAct gives the turn to the next player after t.active who has a decision to make,
+// or ends the betting round if nobody does.
+func (t *Table) nextToAct() {
+	seat := t.nextSeat(t.active, t.needsToAct)
+	if seat < 0 || t.seats[seat] == nil || !t.needsToAct(t.seats[seat]) {
+		t.endStreet()
+		return
+	}
+	t.active = seat
+	t.schedule(time.Duration(t.rules.ActionSeconds) * time.Second)
+}
+
+// endStreet closes the betting round and deals the next street, or settles the hand
+// after the river or when everyone but one player has folded. When fewer than two
+// players can still bet the remaining streets are dealt without betting.
+func (t *Table) endStreet() {
+	t.active = -1
+	t.currentBet = 0
+	t.minRaise = t.rules.BigBlind
+	for _, p := range t.hand {
+		p.Bet = 0
+		p.Acted = false
+	}
+
+	if t.playersLeft() == 1 || t.phase == River {
+		t.settle()
+		return
+	}
+
+	t.deck.Draw() // burn
+	switch t.phase {
+	case PreFlop:
+		t.board = append(t.board, t.deck.Draw(), t.deck.Draw(), t.deck.Draw())
+		t.phase = Flop
+	case Flop:
+		t.board = append(t.board, t.deck.Draw())
+		t.phase = Turn
+	case Turn:
+		t.board = append(t.board, t.deck.Draw())
+		t.phase = River
+	}
+
+	bettors := 0
+	for _, p := range t.hand {
+		if canBet(p) {
+			bettors++
+		}
+	}
+	if bettors < 2 {
+		t.schedule(runOutDelay)
+		return
+	}
+
+	// First to act after the flop is the first player left of the button
+	t.active = t.button
+	t.nextToAct()
+}
+
+// settle returns uncalled bets, builds the pots, takes the rake and pays the winners.
+func (t *Table) settle() {
+	t.phase = Showdown
+	t.active = -1
+	t.HandsPlayed++
+
+	returnUncalled(t.hand)
+	t.pots = buildPots(t.hand)
+	takeRake(t.pots, t.rules.rake(potTotal(t.pots), len(t.board) > 0))
+
+	won := make(map[uint]int)
+	showdown := t.playersLeft() > 1
+	hands := make(map[uint]carddeck.PokerHand)
+	if showdown {
+		for _, p := range t.hand {
+			if !p.Folded {
+				hands[p.ID] = carddeck.EvaluatePoker(append(append([]carddeck.Card{}, p.Hole...), t.board...))
+			}
+		}
+	}
+
+	for i, pot := range t.pots {
+		if pot.Amount == 0 {
+			continue
+		}
+		var winners []*Player
+		var best carddeck.HandScore
+		for _, p := range t.hand {
+			if !eligible(pot, p.ID) {
+				continue
+			}
+			score := hands[p.ID].Score
+			if winners == nil || score > best {
+				winners, best = []*Player{p}, score
+			} else if score == best {
+				winners = append(winners, p)
+			}
+		}
+
+		// Split evenly, odd chips go to the winners closest to the left of the button
+		share, odd := pot.Amount/len(winners), pot.Amount%len(winners)
+		for _, w := range t.fromButton(winners) {
+			amount := share
+			if odd > 0 {
+				amount++
+				odd--
+			}
+			w.Stack += amount
+			won[w.ID] += amount
+			result := Result{PlayerID: w.ID, Pot: i, Amount: amount}
+			if showdown {
+				result.HandName = hands[w.ID].Category.String()
+				result.HandCards = hands[w.ID].Cards
+			}
+			t.results = append(t.results, result)
+		}
+	}
+
+	t.recordWagers(won)
+	t.saveSnapshot()
+	t.schedule(showdownDelay)
+}
+
+// eligible reports whether a player can win the pot.
+func eligible(pot Pot, playerID uint) bool {
+	for _, id := range pot.Eligible {
+		if id == playerID {
+			return true
+		}
+	}
+	return false
+}
+
+// fromButton orders players by seat starting left of the button.
+func (t *Table) fromButton(players []*Player) []*Player {
+	ordered := make([]*Player, 0, len(players))
+	n := len(t.seats)
+	for i := 1; i <= n; i++ {
+		seat := (t.button + i) % n
+		for _, p := range players {
+			if p.Seat == seat {
+				ordered = append(ordered, p)
+			}
+		}
+	}
+	return ordered
+}
+
+// recordWagers stores every player's result for the hand as a wager.
+func (t *Table) recordWagers(won map[uint]int) {
+	for _, p := range t.hand {
+		if p.Committed == 0 {
+			continue
+		}
+		t.DB.Create(&models.Wager{
+			AccountID:   p.ID,
+			WagerAmount: p.Committed,
+			WagerWon:    won[p.ID] > p.Committed,
+			AmountWon:   won[p.ID],
+			GameType:    GameName,
+		})
+	}
+}
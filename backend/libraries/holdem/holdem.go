@@ -0,0 +1,641 @@
+package holdem
+
+// holdem.go
+// This file contains the main logic for a no-limit Texas Hold'em cash table: seating,
+// buy-ins, the game loop and the state sent to players.
+
+// Players sit down with an empty stack and buy chips in from their account balance.
+// Whatever is left in their stack goes back to the account when they leave the table.
+// The hand itself (blinds, betting rounds and showdown) is in hand.go.
+
+// All table state is guarded by the table mutex. The game loop takes it for every
+// player action and timer event, and Join and Leave take it from the WebSocket handlers.
+
+import (
+	carddeck "cardgames/backend/libraries/cardDeck"
+	"cardgames/backend/libraries/game"
+	"cardgames/backend/models"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// ------------------------------------------------------------------
+// Constants and Types
+// ------------------------------------------------------------------
+const (
+	ActionTimeLimit = 30 // seconds
+	MaxSeats        = 9
+	startDelay      = 3 * time.Second // pause before a hand starts once enough players are ready
+	runOutDelay     = 1 * time.Second // pause between streets when nobody can bet
+	showdownDelay   = 5 * time.Second // time the result stays up before the next hand
+)
+
+// Phase represents the current phase of the table.
+type Phase string
+
+const (
+	Waiting  Phase = "waiting" // fewer than two players with chips
+	PreFlop  Phase = "preflop"
+	Flop     Phase = "flop"
+	Turn     Phase = "turn"
+	River    Phase = "river"
+	Showdown Phase = "showdown" // hand is over, results are shown
+)
+
+// Action represents the type of action a player can take.
+type Action string
+
+const (
+	CheckAction  Action = "check"
+	CallAction   Action = "call"
+	RaiseAction  Action = "raise" // bet or raise so the player's bet this round is Amount
+	FoldAction   Action = "fold"
+	AllInAction  Action = "all_in"  // put the whole stack in
+	BuyInAction  Action = "buy_in"  // buy Amount chips from the account balance, between hands
+	SitOutAction Action = "sit_out" // skip hands until sitting in again
+	SitInAction  Action = "sit_in"
+	LeaveAction  Action = "leave" // fold and stand up, the stack goes back to the account
+)
+
+// IncomingUpdate is a message from a player to the table.
+type IncomingUpdate struct {
+	PlayerID uint
+	Action   Action
+	Amount   int // Optional, used by RaiseAction and BuyInAction
+}
+
+// OutgoingUpdate is a message from the table to a player.
+type OutgoingUpdate struct {
+	Phase          Phase
+	YourID         uint
+	YourHand       []carddeck.Card
+	Board          []carddeck.Card
+	Players        []PlayerInfo
+	ButtonSeat     int
+	ActivePlayerID uint
+	Pots           []Pot
+	CurrentBet     int      // highest bet in the current betting round
+	MinRaise       int      // smallest total bet a raise can make
+	ToCall         int      // chips this player needs to call
+	Results        []Result `json:",omitempty"` // winners of the hand, set at showdown
+	Notice         string   `json:",omitempty"` // message for this player only
+	Rules          Rules
+}
+
+// PlayerInfo contains public information about a player.
+type PlayerInfo struct {
+	ID         uint
+	Username   string
+	Seat       int
+	Stack      int
+	Bet        int
+	Hand       []carddeck.Card // hidden cards while the hand is live, shown at showdown
+	Folded     bool
+	AllIn      bool
+	SittingOut bool
+	Connected  bool
+}
+
+// Result is a player's winnings from one pot.
+type Result struct {
+	PlayerID  uint
+	Pot       int // index into Pots, 0 is the main pot
+	Amount    int
+	HandName  string          `json:",omitempty"` // empty when everyone else folded
+	HandCards []carddeck.Card `json:",omitempty"` // the 5 cards of the winning hand
+}
+
+//------------------------------------------------------------------
+// Structs
+//------------------------------------------------------------------
+
+// Player represents a player seated at the table.
+type Player struct {
+	ID         uint
+	Account    *models.Account
+	Seat       int
+	Stack      int // chips in front of the player
+	Bet        int // chips put in during the current betting round
+	Committed  int // chips put in during the current hand
+	Hole       []carddeck.Card
+	InHand     bool // dealt into the current hand
+	Folded     bool
+	AllIn      bool
+	Acted      bool // has acted since the last full raise
+	SittingOut bool
+	Leaving    bool // stands up when the hand is over
+	Connected  bool
+	Incoming   chan json.RawMessage
+	Outgoing   chan any
+	stop       chan struct{}
+	held       bool // seat restored from a snapshot, kept until the player reconnects or the hold runs out
+}
+
+// Options customizes a Hold'em table.
+type Options struct {
+	Rules      *Rules                      // table rules, defaults to DefaultRules
+	HostID     uint                        // creator of a private table
+	Password   string                      // optional password required to join
+	OnSnapshot func(state json.RawMessage) // optional hook the table state is saved through, see snapshot.go
+}
+
+// Table is a Hold'em cash table.
+type Table struct {
+	DB           *gorm.DB
+	seats        []*Player // indexed by seat number, nil for an empty seat
+	hand         []*Player // players dealt into the current hand in seat order, kept after they stand up
+	deck         carddeck.Deck
+	board        []carddeck.Card
+	phase        Phase
+	button       int // seat of the dealer button
+	active       int // seat of the player to act, -1 when nobody is
+	currentBet   int
+	minRaise     int // size of the last full raise, a new raise must be at least this much
+	pots         []Pot
+	results      []Result
+	HandsPlayed  int
+	paused       bool // no new hands are dealt
+	rules        Rules
+	hostID       uint
+	passwordHash []byte
+	onSnapshot   func(state json.RawMessage)
+	incoming     chan IncomingUpdate
+	timer        *time.Timer
+	idleSince    time.Time
+	done         chan struct{}
+	closeOnce    sync.Once
+	suspended    bool // closed by Suspend, the stacks are left to the snapshot
+	mu           sync.Mutex
+}
+
+// NewTable creates a Hold'em table customized by opts and starts its game loop.
+func NewTable(db *gorm.DB, opts Options) *Table {
+	t := newTable(db, opts)
+	go t.gameLoop()
+	return t
+}
+
+// newTable creates a Hold'em table customized by opts without starting its game loop.
+func newTable(db *gorm.DB, opts Options) *Table {
+	t := &Table{
+		DB:         db,
+		phase:      Waiting,
+		button:     -1,
+		active:     -1,
+		rules:      DefaultRules(),
+		hostID:     opts.HostID,
+		onSnapshot: opts.OnSnapshot,
+		incoming:   make(chan IncomingUpdate),
+		timer:      time.NewTimer(time.Hour),
+		idleSince:  time.Now(),
+		done:       make(chan struct{}),
+	}
+	t.timer.Stop()
+	if opts.Rules != nil {
+		t.rules = *opts.Rules
+	}
+	t.seats = make([]*Player, t.rules.Seats)
+	if opts.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(opts.Password), bcrypt.DefaultCost)
+		if err != nil {
+			log.Println("Failed to hash table password:", err)
+		} else {
+			t.passwordHash = hash
+		}
+	}
+
+	return t
+}
+
+//------------------------------------------------------------------
+// Seating
+//------------------------------------------------------------------
+
+// Join seats a player at the first empty seat, or reconnects them if they already have one.
+// New players sit down with an empty stack and buy in with BuyInAction.
+func (t *Table) Join(req game.JoinRequest) (*game.Seat, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	select {
+	case <-t.done:
+		return nil, game.ErrTableClosed
+	default:
+	}
+
+	if p := t.findPlayer(req.PlayerID); p != nil {
+		// Reconnect: close the old connection's channels and hand out new ones
+		t.detach(p)
+		p.Incoming = make(chan json.RawMessage)
+		p.Outgoing = make(chan any, 10)
+		p.Connected = true
+		p.Leaving = false
+		t.updateIdle()
+		t.startForwarding(p)
+		if p.held {
+			// Back at a restored table
+			p.held = false
+			t.maybeStart()
+		}
+		return seatOf(p), nil
+	}
+
+	if !req.Invited && t.passwordHash != nil && bcrypt.CompareHashAndPassword(t.passwordHash, []byte(req.Password)) != nil {
+		return nil, game.ErrWrongPassword
+	}
+
+	seat := -1
+	for i, p := range t.seats {
+		if p == nil {
+			seat = i
+			break
+		}
+	}
+	if seat < 0 {
+		return nil, game.ErrTableFull
+	}
+
+	var account models.Account
+	if err := t.DB.First(&account, req.PlayerID).Error; err != nil {
+		return nil, game.ErrNotAllowed
+	}
+
+	p := &Player{
+		ID:        req.PlayerID,
+		Account:   &account,
+		Seat:      seat,
+		Connected: true,
+		Incoming:  make(chan json.RawMessage),
+		Outgoing:  make(chan any, 10),
+	}
+	t.seats[seat] = p
+	t.updateIdle()
+	t.startForwarding(p)
+
+	return seatOf(p), nil
+}
+
+// seatOf returns the seat handed to a player's connection.
+func seatOf(p *Player) *game.Seat {
+	return &game.Seat{PlayerID: p.ID, Actions: p.Incoming, State: p.Outgoing}
+}
+
+// Leave marks a player as disconnected. A player who is not in a hand stands up right
+// away, otherwise they are folded when their turn comes and stand up after the hand.
+// A seat the player has since reconnected on is left alone.
+func (t *Table) Leave(seat *game.Seat) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := t.findPlayer(seat.PlayerID)
+	if p == nil || p.Outgoing != seat.State {
+		return
+	}
+	p.Connected = false
+	p.Leaving = true
+	if !t.inLiveHand(p) {
+		t.standUp(p)
+		t.saveSnapshot()
+	}
+	t.updateIdle()
+	t.broadcast()
+}
+
+// IsSeated reports whether the player already has a seat at the table.
+func (t *Table) IsSeated(playerID uint) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.findPlayer(playerID) != nil
+}
+
+// Seats returns the number of seats taken and the size of the table.
+func (t *Table) Seats() (int, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	taken := 0
+	for _, p := range t.seats {
+		if p != nil {
+			taken++
+		}
+	}
+	return taken, len(t.seats)
+}
+
+// Describe summarizes the table for the lobby's table browser. The stakes are the
+// buy-in limits, seated players sitting out or not dealt into the hand in progress
+// count as spectators.
+func (t *Table) Describe() game.Summary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	spectators := 0
+	ids := make([]uint, 0, len(t.seats))
+	for _, p := range t.seats {
+		if p == nil {
+			continue
+		}
+		if p.SittingOut || (t.handInProgress() && !p.InHand) {
+			spectators++
+		}
+		ids = append(ids, p.ID)
+	}
+	return game.Summary{
+		Phase:         string(t.phase),
+		Rules:         t.rules.Summary(),
+		MinStake:      t.rules.MinBuyIn,
+		MaxStake:      t.rules.MaxBuyIn,
+		Spectators:    spectators,
+		TurnSeconds:   t.rules.ActionSeconds,
+		BetweenRounds: !t.handInProgress(),
+		PlayerIDs:     ids,
+	}
+}
+
+// HostID returns the player who created the table, 0 for public tables.
+func (t *Table) HostID() uint {
+	return t.hostID
+}
+
+// KeepWhenEmpty reports whether the table must not be cleaned up when idle. Hold'em
+// tables hold no chips once everyone has stood up, so they never need to be kept.
+func (t *Table) KeepWhenEmpty() bool {
+	return false
+}
+
+// Greet sends a newly connected player the table state.
+func (t *Table) Greet(playerID uint) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if p := t.findPlayer(playerID); p != nil {
+		t.send(p, "")
+	}
+}
+
+// findPlayer returns the seated player with the given ID, or nil.
+func (t *Table) findPlayer(playerID uint) *Player {
+	for _, p := range t.seats {
+		if p != nil && p.ID == playerID {
+			return p
+		}
+	}
+	return nil
+}
+
+// handInProgress reports whether a hand is being played.
+func (t *Table) handInProgress() bool {
+	return t.phase != Waiting && t.phase != Showdown
+}
+
+// inLiveHand reports whether the player still has cards in a hand being played.
+func (t *Table) inLiveHand(p *Player) bool {
+	return p.InHand && !p.Folded && t.handInProgress()
+}
+
+// standUp cashes a player's stack out to their account and frees the seat.
+// Chips they committed to a hand in progress stay in the pot.
+func (t *Table) standUp(p *Player) {
+	t.cashOut(p, p.Stack)
+	p.Stack = 0
+	t.seats[p.Seat] = nil
+	t.detach(p)
+}
+
+//------------------------------------------------------------------
+// Chips
+//------------------------------------------------------------------
+
+// buyIn moves chips from the player's account balance to their stack. The stack after
+// buying must be within the table's buy-in range.
+func (t *Table) buyIn(p *Player, amount int) string {
+	if p.InHand && t.handInProgress() {
+		return "You can only buy in between hands"
+	}
+	if amount <= 0 || p.Stack+amount < t.rules.MinBuyIn || p.Stack+amount > t.rules.MaxBuyIn {
+		return "Your stack must be between the minimum and maximum buy-in"
+	}
+
+	// Only take the chips if the balance still covers them
+	res := t.DB.Model(&models.Account{}).
+		Where("id = ? AND balance >= ?", p.ID, amount).
+		Update("balance", gorm.Expr("balance - ?", amount))
+	if res.Error != nil || res.RowsAffected == 0 {
+		return "Not enough balance"
+	}
+	p.Account.Balance -= amount
+	p.Stack += amount
+	return ""
+}
+
+// cashOut moves chips from the table back to the player's account balance.
+func (t *Table) cashOut(p *Player, amount int) {
+	if amount <= 0 {
+		return
+	}
+	err := t.DB.Model(&models.Account{}).
+		Where("id = ?", p.ID).
+		Update("balance", gorm.Expr("balance + ?", amount)).Error
+	if err != nil {
+		log.Println("Failed to cash out", amount, "chips to player", p.ID, ":", err)
+		return
+	}
+	p.Account.Balance += amount
+}
+
+//------------------------------------------------------------------
+// Game Loop
+//------------------------------------------------------------------
+
+// gameLoop handles player actions and timer events until the table is closed.
+func (t *Table) gameLoop() {
+	defer t.shutdown()
+
+	for {
+		select {
+		case <-t.done:
+			return
+		case update := <-t.incoming:
+			t.mu.Lock()
+			t.processUpdate(update)
+			t.mu.Unlock()
+		case <-t.timer.C:
+			t.mu.Lock()
+			t.onTimer()
+			t.mu.Unlock()
+		}
+	}
+}
+
+// schedule arms the table timer. Must be called with t.mu held.
+func (t *Table) schedule(d time.Duration) {
+	t.timer.Reset(d)
+}
+
+// onTimer moves the table along when the timer fires: it starts the next hand, acts
+// for a player who ran out of time or deals the next street when nobody can bet.
+func (t *Table) onTimer() {
+	switch t.phase {
+	case Waiting, Showdown:
+		if t.paused {
+			return
+		}
+		t.startHand()
+	default:
+		if t.active < 0 {
+			t.endStreet()
+			return
+		}
+		t.timeOut(t.seats[t.active])
+	}
+}
+
+// processUpdate handles one message from a player.
+func (t *Table) processUpdate(update IncomingUpdate) {
+	p := t.findPlayer(update.PlayerID)
+	if p == nil {
+		return
+	}
+
+	switch update.Action {
+	case BuyInAction:
+		if notice := t.buyIn(p, update.Amount); notice != "" {
+			t.send(p, notice)
+			return
+		}
+		t.saveSnapshot()
+		t.maybeStart()
+	case SitOutAction:
+		p.SittingOut = true
+	case SitInAction:
+		p.SittingOut = false
+		t.maybeStart()
+	case LeaveAction:
+		p.Leaving = true
+		if t.inLiveHand(p) {
+			t.fold(p)
+		} else {
+			t.standUp(p)
+			t.saveSnapshot()
+		}
+	case CheckAction, CallAction, RaiseAction, FoldAction, AllInAction:
+		if t.active < 0 || t.seats[t.active] != p {
+			return
+		}
+		if notice := t.act(p, update.Action, update.Amount); notice != "" {
+			t.send(p, notice)
+			return
+		}
+		t.advance()
+	default:
+		return
+	}
+	t.broadcast()
+}
+
+// maybeStart schedules the next hand if the table is waiting and enough players are ready.
+func (t *Table) maybeStart() {
+	if t.phase == Waiting && !t.paused && len(t.readyPlayers()) >= 2 {
+		t.schedule(startDelay)
+	}
+}
+
+// readyPlayers returns the players who will be dealt into the next hand, in seat order.
+func (t *Table) readyPlayers() []*Player {
+	var ready []*Player
+	for _, p := range t.seats {
+		if p != nil && p.Connected && !p.Leaving && !p.SittingOut && p.Stack > 0 {
+			ready = append(ready, p)
+		}
+	}
+	return ready
+}
+
+//------------------------------------------------------------------
+// Updates
+//------------------------------------------------------------------
+
+// broadcast sends every seated player their view of the table.
+func (t *Table) broadcast() {
+	for _, p := range t.seats {
+		if p != nil {
+			t.send(p, "")
+		}
+	}
+}
+
+// send delivers a player's view of the table without blocking the game loop.
+func (t *Table) send(p *Player, notice string) {
+	update := OutgoingUpdate{
+		Phase:      t.phase,
+		YourID:     p.ID,
+		YourHand:   p.Hole,
+		Board:      t.board,
+		ButtonSeat: t.button,
+		Pots:       t.pots,
+		CurrentBet: t.currentBet,
+		MinRaise:   t.currentBet + t.minRaise,
+		Results:    t.results,
+		Notice:     notice,
+		Rules:      t.rules,
+	}
+	if t.active >= 0 {
+		update.ActivePlayerID = t.seats[t.active].ID
+	}
+	if p.InHand && t.currentBet > p.Bet {
+		update.ToCall = min(t.currentBet-p.Bet, p.Stack)
+	}
+	if t.handInProgress() {
+		// Pots so far, including the bets of the round in progress
+		update.Pots = []Pot{{Amount: t.chipsInPlay()}}
+	}
+
+	for _, other := range t.seats {
+		if other == nil {
+			continue
+		}
+		info := PlayerInfo{
+			ID:         other.ID,
+			Username:   other.Account.Username,
+			Seat:       other.Seat,
+			Stack:      other.Stack,
+			Bet:        other.Bet,
+			Folded:     other.Folded,
+			AllIn:      other.AllIn,
+			SittingOut: other.SittingOut,
+			Connected:  other.Connected,
+		}
+		if other.InHand && !other.Folded {
+			if t.phase == Showdown && t.shownDown() {
+				info.Hand = other.Hole
+			} else {
+				info.Hand = []carddeck.Card{carddeck.Hidden, carddeck.Hidden}
+			}
+		}
+		update.Players = append(update.Players, info)
+	}
+
+	select {
+	case p.Outgoing <- update:
+	default:
+		log.Println("Failed to send update to player", p.ID)
+	}
+}
+
+// chipsInPlay returns every chip put in during the hand in progress.
+func (t *Table) chipsInPlay() int {
+	total := 0
+	for _, p := range t.hand {
+		total += p.Committed
+	}
+	return total
+}
+
+// shownDown reports whether the last hand went to a showdown, as opposed to everyone
+// but one player folding.
+func (t *Table) shownDown() bool {
+	return len(t.results) > 0 && t.results[0].HandName != ""
+}
@@ -0,0 +1,127 @@
+package holdem
+
+// lifecycle.go
+// This file contains the lifecycle of a Hold'em table: closing it, the goroutines that
+// forward player input to the game loop, and idle detection for the game instance manager.
+// Closing a table cashes every stack out, and chips in a hand that was cut short go back
+// to the players who put them in.
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Close stops the game loop, pays every player's chips back to their account and closes
+// the players' Outgoing channels. It is safe to call more than once.
+func (t *Table) Close() {
+	t.closeOnce.Do(func() {
+		close(t.done)
+	})
+}
+
+// Suspend closes the table for a server restart. Unlike Close, nobody is cashed out:
+// the last snapshot keeps the stacks, and seats them again when it is restored.
+func (t *Table) Suspend() {
+	t.closeOnce.Do(func() {
+		t.suspended = true
+		close(t.done)
+	})
+}
+
+// Done returns a channel that is closed once the table has been closed.
+func (t *Table) Done() <-chan struct{} {
+	return t.done
+}
+
+// IdleFor returns how long the table has had no connected players, or 0 if
+// someone is connected right now.
+func (t *Table) IdleFor() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.idleSince.IsZero() {
+		return 0
+	}
+	return time.Since(t.idleSince)
+}
+
+// updateIdle records when the last connected player left. Must be called with t.mu held.
+func (t *Table) updateIdle() {
+	for _, p := range t.seats {
+		if p != nil && p.Connected {
+			t.idleSince = time.Time{}
+			return
+		}
+	}
+	if t.idleSince.IsZero() {
+		t.idleSince = time.Now()
+	}
+}
+
+// startForwarding starts the goroutine that moves a player's input onto the game loop.
+// Messages that are not valid updates are dropped, and every update is marked as
+// coming from the seated player whatever the client put in PlayerID.
+func (t *Table) startForwarding(p *Player) {
+	p.stop = make(chan struct{})
+	go func(playerID uint, incoming chan json.RawMessage, stop chan struct{}) {
+		for {
+			select {
+			case raw := <-incoming:
+				var update IncomingUpdate
+				if err := json.Unmarshal(raw, &update); err != nil {
+					continue
+				}
+				update.PlayerID = playerID
+				select {
+				case t.incoming <- update:
+				case <-stop:
+					return
+				case <-t.done:
+					return
+				}
+			case <-stop:
+				return
+			case <-t.done:
+				return
+			}
+		}
+	}(p.ID, p.Incoming, p.stop)
+}
+
+// detach stops a player's forwarding goroutine and closes their Outgoing channel.
+// Incoming is left open: the WebSocket reader may still be sending on it.
+func (t *Table) detach(p *Player) {
+	close(p.stop)
+	close(p.Outgoing)
+}
+
+// shutdown refunds a hand cut short, cashes out every player and detaches them once
+// the game loop has stopped. A suspended table only detaches them.
+func (t *Table) shutdown() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.timer.Stop()
+	if t.suspended {
+		for _, p := range t.seats {
+			if p != nil {
+				t.detach(p)
+			}
+		}
+		return
+	}
+	if t.handInProgress() {
+		for _, p := range t.hand {
+			p.Stack += p.Committed
+			if t.seats[p.Seat] != p {
+				// Already stood up, pay the refund straight to the account
+				t.cashOut(p, p.Stack)
+			}
+		}
+	}
+	for _, p := range t.seats {
+		if p != nil {
+			t.standUp(p)
+		}
+	}
+}
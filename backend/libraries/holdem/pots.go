@@ -0,0 +1,109 @@
+package holdem
+
+// pots.go
+// This file contains the pot calculation. Every chip a player puts in during a hand is
+// added to their Committed total. When the hand ends, bets nobody called are returned,
+// and the chips are split into a main pot and side pots by the all-in amounts, each one
+// only winnable by the players who covered it.
+
+import (
+	"sort"
+)
+
+// Pot is a main or side pot and the players who can win it.
+type Pot struct {
+	Amount   int
+	Eligible []uint // IDs of the players still in the hand who covered this pot
+}
+
+// returnUncalled gives back the part of the biggest bet of the hand that nobody matched.
+func returnUncalled(players []*Player) {
+	var top, second *Player
+	for _, p := range players {
+		if top == nil || p.Committed > top.Committed {
+			top, second = p, top
+		} else if second == nil || p.Committed > second.Committed {
+			second = p
+		}
+	}
+	if top == nil {
+		return
+	}
+	matched := 0
+	if second != nil {
+		matched = second.Committed
+	}
+	if excess := top.Committed - matched; excess > 0 {
+		top.Committed -= excess
+		top.Stack += excess
+	}
+}
+
+// buildPots splits the chips committed by players into the main pot and side pots.
+// Folded players' chips go into the pots but they are not eligible to win any of them.
+func buildPots(players []*Player) []Pot {
+	// The pot boundaries are the amounts committed by players who have not folded
+	levelSet := make(map[int]bool)
+	for _, p := range players {
+		if !p.Folded && p.Committed > 0 {
+			levelSet[p.Committed] = true
+		}
+	}
+	levels := make([]int, 0, len(levelSet))
+	for l := range levelSet {
+		levels = append(levels, l)
+	}
+	sort.Ints(levels)
+
+	var pots []Pot
+	prev := 0
+	for _, level := range levels {
+		pot := Pot{}
+		for _, p := range players {
+			pot.Amount += min(p.Committed, level) - min(p.Committed, prev)
+			if !p.Folded && p.Committed >= level {
+				pot.Eligible = append(pot.Eligible, p.ID)
+			}
+		}
+		prev = level
+		// A level with the same players as the one below just adds to that pot
+		if n := len(pots); n > 0 && len(pots[n-1].Eligible) == len(pot.Eligible) {
+			pots[n-1].Amount += pot.Amount
+			continue
+		}
+		pots = append(pots, pot)
+	}
+
+	// Chips folded players put in above the last level go to the last pot
+	leftover := 0
+	for _, p := range players {
+		if p.Committed > prev {
+			leftover += p.Committed - prev
+		}
+	}
+	if leftover > 0 && len(pots) > 0 {
+		pots[len(pots)-1].Amount += leftover
+	}
+	return pots
+}
+
+// takeRake removes rake chips from the pots, starting with the main pot.
+func takeRake(pots []Pot, rake int) {
+	for i := range pots {
+		if rake == 0 {
+			return
+		}
+		take := min(rake, pots[i].Amount)
+		pots[i].Amount -= take
+		rake -= take
+	}
+}
+
+// potTotal returns the chips in all pots.
+func potTotal(pots []Pot) int {
+	total := 0
+	for _, pot := range pots {
+		total += pot.Amount
+	}
+	return total
+}
@@ -0,0 +1,70 @@
+package holdem
+
+// register.go
+// This file registers Texas Hold'em with the game registry, so the lobby can create
+// Hold'em tables and the WebSocket handler can connect players to them.
+
+import (
+	"cardgames/backend/libraries/game"
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// GameName is the name Hold'em is registered under.
+const GameName = "holdem"
+
+func init() {
+	game.Register(game.Definition{
+		Name:  GameName,
+		Modes: []string{"standard"},
+		New:   newFromConfig,
+	})
+}
+
+// newFromConfig creates a Hold'em table from the settings chosen in the lobby, or
+// restores one from a snapshot.
+func newFromConfig(db *gorm.DB, cfg game.Config) (game.Game, error) {
+	var opts Options
+	if cfg.Private {
+		opts.HostID = cfg.HostID
+		opts.Password = cfg.Password
+	}
+	if len(cfg.Rules) > 0 && string(cfg.Rules) != "null" {
+		rules := DefaultRules()
+		if err := json.Unmarshal(cfg.Rules, &rules); err != nil {
+			return nil, fmt.Errorf("%w: %v", game.ErrInvalidConfig, err)
+		}
+		if err := rules.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %v", game.ErrInvalidConfig, err)
+		}
+		opts.Rules = &rules
+	}
+	if cfg.FastPace {
+		rules := DefaultRules()
+		if opts.Rules != nil {
+			rules = *opts.Rules
+		}
+		if rules.ActionSeconds > game.FastPaceSeconds {
+			rules.ActionSeconds = game.FastPaceSeconds
+		}
+		opts.Rules = &rules
+	}
+	opts.OnSnapshot = cfg.OnSnapshot
+
+	if len(cfg.Snapshot) == 0 {
+		return NewTable(db, opts), nil
+	}
+	var snap snapshot
+	if err := json.Unmarshal(cfg.Snapshot, &snap); err != nil {
+		return nil, fmt.Errorf("%w: %v", game.ErrInvalidConfig, err)
+	}
+	if err := snap.Rules.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", game.ErrInvalidConfig, err)
+	}
+	t := newTable(db, opts)
+	t.restore(snap)
+	go t.gameLoop()
+	return t, nil
+}
@@ -0,0 +1,88 @@
+package holdem
+
+// rules.go
+// This file contains the configurable rules of a Hold'em table: the blinds, the buy-in
+// range, the number of seats, the rake and the action clock. Public tables use
+// DefaultRules, private tables can set their own when they are created.
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidRules is returned when a Rules value fails validation.
+var ErrInvalidRules = errors.New("invalid table rules")
+
+// Rules are the settings a Hold'em table plays by.
+type Rules struct {
+	SmallBlind    int  // forced bet of the player left of the button
+	BigBlind      int  // forced bet of the next player, also the minimum bet
+	MinBuyIn      int  // smallest stack a player can sit down with
+	MaxBuyIn      int  // largest stack a player can buy up to
+	Seats         int  // table size, 2 to 9
+	RakePercent   int  // percent of each pot kept by the house, 0 for no rake
+	RakeCap       int  // most rake taken from a single hand, 0 for no cap
+	NoFlopNoDrop  bool // no rake is taken from hands that end before the flop
+	ActionSeconds int  // time each player has to act on their turn
+}
+
+// DefaultRules returns the rules used by tables that do not set their own.
+func DefaultRules() Rules {
+	return Rules{
+		SmallBlind:    1,
+		BigBlind:      2,
+		MinBuyIn:      40,
+		MaxBuyIn:      200,
+		Seats:         6,
+		RakePercent:   5,
+		RakeCap:       10,
+		NoFlopNoDrop:  true,
+		ActionSeconds: ActionTimeLimit,
+	}
+}
+
+// Validate checks that the rules describe a playable table.
+func (r Rules) Validate() error {
+	if r.SmallBlind < 1 || r.BigBlind < r.SmallBlind {
+		return ErrInvalidRules
+	}
+	if r.MinBuyIn < r.BigBlind || r.MaxBuyIn < r.MinBuyIn {
+		return ErrInvalidRules
+	}
+	if r.Seats < 2 || r.Seats > MaxSeats {
+		return ErrInvalidRules
+	}
+	if r.RakePercent < 0 || r.RakePercent > 10 || r.RakeCap < 0 {
+		return ErrInvalidRules
+	}
+	if r.ActionSeconds < 5 || r.ActionSeconds > 120 {
+		return ErrInvalidRules
+	}
+	return nil
+}
+
+// Summary describes the rules in a few words, e.g. "blinds 1/2, 6 seats, rake 5% capped at 10".
+func (r Rules) Summary() string {
+	summary := fmt.Sprintf("blinds %d/%d, %d seats", r.SmallBlind, r.BigBlind, r.Seats)
+	switch {
+	case r.RakePercent == 0:
+		return summary + ", no rake"
+	case r.RakeCap > 0:
+		return fmt.Sprintf("%s, rake %d%% capped at %d", summary, r.RakePercent, r.RakeCap)
+	default:
+		return fmt.Sprintf("%s, rake %d%%", summary, r.RakePercent)
+	}
+}
+
+// rake returns the house's share of a pot of total chips.
+// sawFlop reports whether the hand reached the flop.
+func (r Rules) rake(total int, sawFlop bool) int {
+	if r.NoFlopNoDrop && !sawFlop {
+		return 0
+	}
+	rake := total * r.RakePercent / 100
+	if r.RakeCap > 0 && rake > r.RakeCap {
+		rake = r.RakeCap
+	}
+	return rake
+}
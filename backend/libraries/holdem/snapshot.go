@@ -0,0 +1,168 @@
+package holdem
+
+// snapshot.go
+// This file contains saving a Hold'em table so it survives a server restart, and
+// restoring it. The table is saved whenever chips come on or off it: after a buy-in,
+// when a player stands up and when a hand is settled. The stacks are saved with what
+// the players committed to the hand in progress, so a hand cut short by the restart is
+// paid back the same way closing the table pays it back.
+
+// A restored table waits for its players. Their seats are held with their stacks for
+// game.SeatHoldTime, after which the players who have not reconnected are stood up and
+// cashed out.
+
+import (
+	"cardgames/backend/libraries/game"
+	"cardgames/backend/models"
+	"encoding/json"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// snapshot is the saved state of a table.
+type snapshot struct {
+	Rules        Rules
+	HostID       uint
+	PasswordHash []byte `json:",omitempty"`
+	Button       int
+	HandsPlayed  int
+	Seats        []seatSnapshot
+	Refunds      []refund `json:",omitempty"` // chips in the hand in progress of players who stood up
+}
+
+// seatSnapshot is the saved state of a seated player.
+type seatSnapshot struct {
+	ID         uint
+	Seat       int
+	Stack      int // includes the chips committed to the hand in progress
+	SittingOut bool
+}
+
+// refund is chips to pay back to a player who is no longer seated.
+type refund struct {
+	ID     uint
+	Amount int
+}
+
+// saveSnapshot hands the table state to the OnSnapshot hook, if there is one.
+// Must be called with t.mu held.
+func (t *Table) saveSnapshot() {
+	if t.onSnapshot == nil {
+		return
+	}
+
+	snap := snapshot{
+		Rules:        t.rules,
+		HostID:       t.hostID,
+		PasswordHash: t.passwordHash,
+		Button:       t.button,
+		HandsPlayed:  t.HandsPlayed,
+	}
+	inProgress := t.handInProgress()
+	for _, p := range t.seats {
+		if p == nil {
+			continue
+		}
+		seat := seatSnapshot{ID: p.ID, Seat: p.Seat, Stack: p.Stack, SittingOut: p.SittingOut}
+		if inProgress && p.InHand {
+			seat.Stack += p.Committed
+		}
+		snap.Seats = append(snap.Seats, seat)
+	}
+	if inProgress {
+		for _, p := range t.hand {
+			if t.seats[p.Seat] != p && p.Committed > 0 {
+				snap.Refunds = append(snap.Refunds, refund{ID: p.ID, Amount: p.Committed})
+			}
+		}
+	}
+
+	state, err := json.Marshal(snap)
+	if err != nil {
+		log.Println("Failed to encode Hold'em snapshot:", err)
+		return
+	}
+	t.onSnapshot(state)
+}
+
+// restore sets the table up from a snapshot. It is called before the game loop starts.
+// Players whose account is gone are left out.
+func (t *Table) restore(snap snapshot) {
+	t.rules = snap.Rules
+	t.seats = make([]*Player, t.rules.Seats)
+	t.hostID = snap.HostID
+	t.passwordHash = snap.PasswordHash
+	t.button = snap.Button
+	t.HandsPlayed = snap.HandsPlayed
+
+	for _, r := range snap.Refunds {
+		err := t.DB.Model(&models.Account{}).
+			Where("id = ?", r.ID).
+			Update("balance", gorm.Expr("balance + ?", r.Amount)).Error
+		if err != nil {
+			log.Println("Failed to refund", r.Amount, "chips to player", r.ID, ":", err)
+		}
+	}
+
+	held := false
+	for _, seat := range snap.Seats {
+		var account models.Account
+		if err := t.DB.First(&account, seat.ID).Error; err != nil {
+			log.Println("Not restoring seat of player", seat.ID, ":", err)
+			continue
+		}
+		p := &Player{
+			ID:         seat.ID,
+			Account:    &account,
+			Seat:       seat.Seat,
+			Stack:      seat.Stack,
+			SittingOut: seat.SittingOut,
+			Incoming:   make(chan json.RawMessage),
+			Outgoing:   make(chan any, 10),
+			stop:       make(chan struct{}),
+			held:       true,
+		}
+		if p.Seat < 0 || p.Seat >= len(t.seats) || t.seats[p.Seat] != nil {
+			// No seat to put them back in, cash them out instead
+			t.cashOut(p, p.Stack)
+			continue
+		}
+		t.seats[p.Seat] = p
+		held = true
+	}
+	t.updateIdle()
+
+	if held {
+		time.AfterFunc(game.SeatHoldTime, t.releaseHeldSeats)
+	}
+
+	// Save right away so the refunds are not paid again after another restart
+	t.saveSnapshot()
+}
+
+// releaseHeldSeats stands up the players of a restored table who did not come back.
+func (t *Table) releaseHeldSeats() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	select {
+	case <-t.done:
+		return
+	default:
+	}
+
+	released := false
+	for _, p := range t.seats {
+		if p != nil && p.held && !p.Connected {
+			t.standUp(p)
+			released = true
+		}
+	}
+	if released {
+		t.updateIdle()
+		t.broadcast()
+		t.saveSnapshot()
+	}
+}
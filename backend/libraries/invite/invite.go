@@ -0,0 +1,132 @@
+// Package invite provides signed, expiring invite tokens for private tables.
+// A token names an invite row and carries an HMAC signature over the invite ID,
+// game ID and expiry, so tokens cannot be forged or pointed at another table.
+// The invite row tracks how many times the token has been used.
+package invite
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"cardgames/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// Errors returned when checking or redeeming a token.
+var (
+	ErrInvalidToken = errors.New("invalid invite")
+	ErrExpired      = errors.New("invite has expired")
+	ErrUsedUp       = errors.New("invite has no uses left")
+	ErrNotForYou    = errors.New("invite is for another player")
+)
+
+// Manager creates and redeems invite tokens.
+type Manager struct {
+	DB     *gorm.DB
+	secret []byte
+}
+
+// NewManager creates an invite manager. Tokens are signed with the INVITE_SECRET
+// environment variable, or with a random key if it is not set, in which case
+// tokens stop working when the server restarts.
+func NewManager(db *gorm.DB) *Manager {
+	secret := []byte(os.Getenv("INVITE_SECRET"))
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			panic(err)
+		}
+	}
+	return &Manager{DB: db, secret: secret}
+}
+
+// Create stores a new invite for gameID and returns its token.
+// targetID limits the invite to a single player, 0 lets anyone with the token use it.
+func (m *Manager) Create(hostID uint, gameID string, ttl time.Duration, maxUses int, targetID uint) (string, *models.Invite, error) {
+	inv := &models.Invite{
+		GameID:    gameID,
+		HostID:    hostID,
+		TargetID:  targetID,
+		ExpiresAt: time.Now().Add(ttl),
+		MaxUses:   maxUses,
+	}
+	if err := m.DB.Create(inv).Error; err != nil {
+		return "", nil, err
+	}
+	return m.token(inv), inv, nil
+}
+
+// Check validates a token for userID without using it up and returns the invite.
+func (m *Manager) Check(token string, userID uint) (*models.Invite, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+	id, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var inv models.Invite
+	if err := m.DB.First(&inv, id).Error; err != nil {
+		return nil, ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(m.token(&inv)), []byte(token)) {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().After(inv.ExpiresAt) {
+		return nil, ErrExpired
+	}
+	if inv.Uses >= inv.MaxUses {
+		return nil, ErrUsedUp
+	}
+	if inv.TargetID != 0 && inv.TargetID != userID {
+		return nil, ErrNotForYou
+	}
+	return &inv, nil
+}
+
+// Redeem validates a token for userID and uses it once.
+func (m *Manager) Redeem(token string, userID uint) (*models.Invite, error) {
+	inv, err := m.Check(token, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only count the use if another player has not taken the last one in the meantime
+	res := m.DB.Model(&models.Invite{}).
+		Where("id = ? AND uses < max_uses", inv.ID).
+		Update("uses", gorm.Expr("uses + 1"))
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrUsedUp
+	}
+	inv.Uses++
+	return inv, nil
+}
+
+// Release gives back a use taken by Redeem, for when the player could not be seated.
+func (m *Manager) Release(inv *models.Invite) {
+	m.DB.Model(&models.Invite{}).
+		Where("id = ? AND uses > 0", inv.ID).
+		Update("uses", gorm.Expr("uses - 1"))
+}
+
+// token builds the signed token for an invite: "<id>.<expiry>.<signature>".
+func (m *Manager) token(inv *models.Invite) string {
+	payload := fmt.Sprintf("%d.%d", inv.ID, inv.ExpiresAt.Unix())
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(payload + "." + inv.GameID))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,60 @@
+// Package klondike runs single player Klondike solitaire with server-validated moves.
+// This file contains the seeded deal. The same seed always deals the same game, which
+// is how every player gets the same daily deal.
+package klondike
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"time"
+
+	carddeck "cardgames/backend/libraries/cardDeck"
+)
+
+// dailyDateFormat is the format of daily deal dates.
+const dailyDateFormat = "2006-01-02"
+
+// DailyDate returns the date of the daily deal being played at t, in UTC.
+func DailyDate(t time.Time) string {
+	return t.UTC().Format(dailyDateFormat)
+}
+
+// dailySeed returns the seed of the daily deal for date. Seeds are mixed with the
+// KLONDIKE_SECRET environment variable, if set, so players cannot work the deal out
+// ahead of time from the date alone.
+func dailySeed(date string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(os.Getenv("KLONDIKE_SECRET")))
+	h.Write([]byte("klondike:" + date))
+	return int64(h.Sum64())
+}
+
+// shuffledDeck returns a deck in the order given by seed.
+func shuffledDeck(seed int64) carddeck.Deck {
+	deck := carddeck.New()
+	r := rand.New(rand.NewSource(seed))
+	r.Shuffle(len(deck), func(i, j int) {
+		deck[i], deck[j] = deck[j], deck[i]
+	})
+	return deck
+}
+
+// deal lays out a new game from seed: seven tableau piles of one to seven cards with
+// only the top card face up, and the rest of the deck in the stock.
+func deal(seed int64) *board {
+	deck := shuffledDeck(seed)
+	b := &board{}
+	for row := 0; row < tableauPiles; row++ {
+		for pile := row; pile < tableauPiles; pile++ {
+			card := deck.Draw()
+			if pile == row {
+				b.Tableau[pile].Up = append(b.Tableau[pile].Up, card)
+			} else {
+				b.Tableau[pile].Down = append(b.Tableau[pile].Down, card)
+			}
+		}
+	}
+	b.Stock = deck
+	return b
+}
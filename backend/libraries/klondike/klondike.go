@@ -0,0 +1,365 @@
+// Package klondike runs single player Klondike solitaire with server-validated moves.
+// This file contains the board and the rules: which moves are allowed, what they do
+// and how they score.
+//
+// Scoring follows the usual standard scoring. Moving a card from the waste to the
+// tableau scores 5, any card to a foundation 10 and turning a tableau card face up 5.
+// Moving a card back off a foundation costs 15. Recycling the waste into the stock
+// costs 100 in draw-1, and 20 in draw-3 after the first three passes through the
+// stock. A won game earns a time bonus. The score never goes below 0.
+package klondike
+
+import (
+	"errors"
+	"time"
+
+	carddeck "cardgames/backend/libraries/cardDeck"
+)
+
+// Board dimensions.
+const (
+	tableauPiles   = 7
+	foundationSize = 13
+	suits          = 4
+)
+
+// Scores for each kind of move.
+const (
+	scoreWasteToTableau   = 5
+	scoreToFoundation     = 10
+	scoreTurnOver         = 5
+	scoreFromFoundation   = -15
+	scoreRecycleDrawOne   = -100
+	scoreRecycleDrawThree = -20
+	freeRecyclesDrawThree = 3       // passes through the stock in draw-3 before recycling costs points
+	timeBonusMinSeconds   = 30      // games won faster than this earn no more bonus than at 30 seconds
+	timeBonusNumerator    = 700_000 // the time bonus is this divided by the seconds taken
+)
+
+// Errors returned for moves that are not allowed.
+var (
+	ErrIllegalMove = errors.New("illegal move")
+	ErrBadPile     = errors.New("no such pile")
+)
+
+// PileKind names a kind of pile on the board.
+type PileKind string
+
+const (
+	WastePile      PileKind = "waste"
+	TableauPile    PileKind = "tableau"
+	FoundationPile PileKind = "foundation"
+)
+
+// PileRef points at a pile. Index is only used for tableau (0 to 6) and foundation (0 to 3) piles.
+type PileRef struct {
+	Kind  PileKind `json:"kind"`
+	Index int      `json:"index"`
+}
+
+// MoveType is the kind of move a player makes.
+type MoveType string
+
+const (
+	DrawMove MoveType = "draw" // turn cards from the stock, or recycle the waste when the stock is empty
+	CardMove MoveType = "move" // move cards from one pile to another
+)
+
+// Move is a single move sent by the player.
+type Move struct {
+	Type  MoveType `json:"type"`
+	From  PileRef  `json:"from"`
+	To    PileRef  `json:"to"`
+	Count int      `json:"count"` // cards moved off a tableau pile, defaults to 1
+}
+
+// tableau is one tableau pile: face down cards under a run of face up cards.
+type tableau struct {
+	Down []carddeck.Card
+	Up   []carddeck.Card
+}
+
+// board is the full state of the cards. The last card of every slice is its top card.
+type board struct {
+	Stock       []carddeck.Card
+	Waste       []carddeck.Card
+	Foundations [suits][]carddeck.Card
+	Tableau     [tableauPiles]tableau
+}
+
+// rank returns the rank of a card from 1 for an ace up to 13 for a king.
+func rank(c carddeck.Card) int {
+	return c.Value.Number()
+}
+
+// red reports whether a card is a heart or a diamond.
+func red(c carddeck.Card) bool {
+	return c.Suit.Red()
+}
+
+// fitsTableau reports whether card can be placed on a tableau pile whose top card is top.
+// Only a king can go on an empty pile.
+func fitsTableau(card carddeck.Card, top *carddeck.Card) bool {
+	if top == nil {
+		return rank(card) == 13
+	}
+	return red(card) != red(*top) && rank(card) == rank(*top)-1
+}
+
+// fitsFoundation reports whether card can be placed on a foundation pile.
+func fitsFoundation(card carddeck.Card, pile []carddeck.Card) bool {
+	if len(pile) == 0 {
+		return rank(card) == 1
+	}
+	top := pile[len(pile)-1]
+	return card.Suit == top.Suit && rank(card) == rank(top)+1
+}
+
+// topOf returns a pointer to the last card of cards, or nil if there are none.
+func topOf(cards []carddeck.Card) *carddeck.Card {
+	if len(cards) == 0 {
+		return nil
+	}
+	return &cards[len(cards)-1]
+}
+
+// won reports whether every card is on a foundation.
+func (b *board) won() bool {
+	for _, f := range b.Foundations {
+		if len(f) != foundationSize {
+			return false
+		}
+	}
+	return true
+}
+
+// Game is a game of Klondike in progress.
+type Game struct {
+	board
+	DrawCount int    // cards turned from the stock at a time, 1 or 3
+	DailyDate string // date of the daily deal, empty for a random deal
+	Moves     int    // moves made, every draw and recycle counts as one
+	Score     int    // standard score, without the time bonus until the game is won
+	Passes    int    // times the waste has been recycled into the stock
+	StartedAt time.Time
+	Won       bool
+	Duration  time.Duration // time taken to win, set once the game is won
+}
+
+// newGame deals a game from seed.
+func newGame(seed int64, drawCount int, dailyDate string) *Game {
+	return &Game{
+		board:     *deal(seed),
+		DrawCount: drawCount,
+		DailyDate: dailyDate,
+		StartedAt: time.Now(),
+	}
+}
+
+// apply validates a move and makes it. Nothing changes if the move is not allowed.
+func (g *Game) apply(m Move) error {
+	if g.Won {
+		return ErrIllegalMove
+	}
+
+	var err error
+	switch m.Type {
+	case DrawMove:
+		err = g.draw()
+	case CardMove:
+		err = g.moveCards(m)
+	default:
+		err = ErrIllegalMove
+	}
+	if err != nil {
+		return err
+	}
+
+	g.Moves++
+	if g.won() {
+		g.Won = true
+		g.Duration = time.Since(g.StartedAt)
+		g.addScore(timeBonus(g.Duration))
+	}
+	return nil
+}
+
+// draw turns the next cards from the stock onto the waste, or turns the waste back
+// over into the stock once the stock is empty.
+func (g *Game) draw() error {
+	if len(g.Stock) == 0 {
+		if len(g.Waste) == 0 {
+			return ErrIllegalMove
+		}
+		for i := len(g.Waste) - 1; i >= 0; i-- {
+			g.Stock = append(g.Stock, g.Waste[i])
+		}
+		g.Waste = nil
+		g.Passes++
+		if g.DrawCount == 1 {
+			g.addScore(scoreRecycleDrawOne)
+		} else if g.Passes > freeRecyclesDrawThree {
+			g.addScore(scoreRecycleDrawThree)
+		}
+		return nil
+	}
+
+	for i := 0; i < g.DrawCount && len(g.Stock) > 0; i++ {
+		g.Waste = append(g.Waste, g.Stock[len(g.Stock)-1])
+		g.Stock = g.Stock[:len(g.Stock)-1]
+	}
+	return nil
+}
+
+// moveCards moves one card, or a run of face up cards off a tableau pile, to another pile.
+func (g *Game) moveCards(m Move) error {
+	count := m.Count
+	if count == 0 {
+		count = 1
+	}
+
+	// Pick up the cards to move
+	var cards []carddeck.Card
+	switch m.From.Kind {
+	case WastePile:
+		if len(g.Waste) == 0 || count != 1 {
+			return ErrIllegalMove
+		}
+		cards = g.Waste[len(g.Waste)-1:]
+	case TableauPile:
+		if m.From.Index < 0 || m.From.Index >= tableauPiles {
+			return ErrBadPile
+		}
+		up := g.Tableau[m.From.Index].Up
+		if count < 1 || count > len(up) {
+			return ErrIllegalMove
+		}
+		cards = up[len(up)-count:]
+	case FoundationPile:
+		if m.From.Index < 0 || m.From.Index >= suits {
+			return ErrBadPile
+		}
+		f := g.Foundations[m.From.Index]
+		if len(f) == 0 || count != 1 {
+			return ErrIllegalMove
+		}
+		cards = f[len(f)-1:]
+	default:
+		return ErrBadPile
+	}
+
+	// Check they can go where they are going
+	switch m.To.Kind {
+	case TableauPile:
+		if m.To.Index < 0 || m.To.Index >= tableauPiles {
+			return ErrBadPile
+		}
+		if m.From.Kind == TableauPile && m.From.Index == m.To.Index {
+			return ErrIllegalMove
+		}
+		if !fitsTableau(cards[0], topOf(g.Tableau[m.To.Index].Up)) {
+			return ErrIllegalMove
+		}
+	case FoundationPile:
+		if m.To.Index < 0 || m.To.Index >= suits {
+			return ErrBadPile
+		}
+		if len(cards) != 1 || m.From.Kind == FoundationPile || !fitsFoundation(cards[0], g.Foundations[m.To.Index]) {
+			return ErrIllegalMove
+		}
+	default:
+		return ErrBadPile
+	}
+
+	// Move them
+	moved := append([]carddeck.Card(nil), cards...)
+	switch m.From.Kind {
+	case WastePile:
+		g.Waste = g.Waste[:len(g.Waste)-1]
+	case TableauPile:
+		pile := &g.Tableau[m.From.Index]
+		pile.Up = pile.Up[:len(pile.Up)-count]
+		if len(pile.Up) == 0 && len(pile.Down) > 0 {
+			pile.Up = append(pile.Up, pile.Down[len(pile.Down)-1])
+			pile.Down = pile.Down[:len(pile.Down)-1]
+			g.addScore(scoreTurnOver)
+		}
+	case FoundationPile:
+		g.Foundations[m.From.Index] = g.Foundations[m.From.Index][:len(g.Foundations[m.From.Index])-1]
+		g.addScore(scoreFromFoundation)
+	}
+
+	switch m.To.Kind {
+	case TableauPile:
+		g.Tableau[m.To.Index].Up = append(g.Tableau[m.To.Index].Up, moved...)
+		if m.From.Kind == WastePile {
+			g.addScore(scoreWasteToTableau)
+		}
+	case FoundationPile:
+		g.Foundations[m.To.Index] = append(g.Foundations[m.To.Index], moved[0])
+		g.addScore(scoreToFoundation)
+	}
+	return nil
+}
+
+// addScore changes the score, which never goes below 0.
+func (g *Game) addScore(points int) {
+	g.Score = max(g.Score+points, 0)
+}
+
+// timeBonus returns the bonus for winning a game in d.
+func timeBonus(d time.Duration) int {
+	seconds := max(int(d.Seconds()), timeBonusMinSeconds)
+	return timeBonusNumerator / seconds
+}
+
+// TableauView is a tableau pile as the player sees it.
+type TableauView struct {
+	Down int             `json:"down"` // face down cards, which stay hidden
+	Up   []carddeck.Card `json:"up"`
+}
+
+// View is the game as sent to the player. Face down cards and the stock are hidden.
+type View struct {
+	DrawCount   int                    `json:"drawCount"`
+	DailyDate   string                 `json:"dailyDate,omitempty"`
+	Stock       int                    `json:"stock"` // cards left in the stock
+	Waste       []carddeck.Card        `json:"waste"`
+	Foundations [suits][]carddeck.Card `json:"foundations"`
+	Tableau     []TableauView          `json:"tableau"`
+	Moves       int                    `json:"moves"`
+	Score       int                    `json:"score"`
+	Passes      int                    `json:"passes"`
+	StartedAt   time.Time              `json:"startedAt"`
+	Won         bool                   `json:"won"`
+	DurationMs  int64                  `json:"durationMs,omitempty"`
+}
+
+// view returns what the player can see of the game. It shares no memory with the game,
+// so it can be sent after the manager's lock is released.
+func (g *Game) view() *View {
+	v := &View{
+		DrawCount:  g.DrawCount,
+		DailyDate:  g.DailyDate,
+		Stock:      len(g.Stock),
+		Waste:      clone(g.Waste),
+		Moves:      g.Moves,
+		Score:      g.Score,
+		Passes:     g.Passes,
+		StartedAt:  g.StartedAt,
+		Won:        g.Won,
+		DurationMs: g.Duration.Milliseconds(),
+	}
+	for i, f := range g.Foundations {
+		v.Foundations[i] = clone(f)
+	}
+	for _, t := range g.Tableau {
+		v.Tableau = append(v.Tableau, TableauView{Down: len(t.Down), Up: clone(t.Up)})
+	}
+	return v
+}
+
+// clone returns a copy of cards, never nil so empty piles are sent as [].
+func clone(cards []carddeck.Card) []carddeck.Card {
+	return append([]carddeck.Card{}, cards...)
+}
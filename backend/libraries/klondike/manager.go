@@ -0,0 +1,183 @@
+// Package klondike runs single player Klondike solitaire with server-validated moves.
+// This file contains the Manager, which keeps every player's game in progress, saves
+// won games and ranks the daily deal.
+//
+// Games in progress are kept in memory, one per player. Starting a new game abandons
+// the old one. Each player is dealt the daily deal once a day, the first attempt is the
+// one that is ranked.
+package klondike
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"cardgames/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// leaderboardSize is the number of players shown on a daily leaderboard.
+const leaderboardSize = 50
+
+// Errors returned by the Manager.
+var (
+	ErrNoGame      = errors.New("no game in progress")
+	ErrInvalidDraw = errors.New("draw count must be 1 or 3")
+	ErrInvalidDate = errors.New("invalid date")
+	ErrDailyPlayed = errors.New("today's daily deal has already been played")
+)
+
+// Manager runs Klondike games.
+type Manager struct {
+	DB    *gorm.DB
+	games map[uint]*Game // games in progress by account ID
+	mu    sync.Mutex
+}
+
+// NewManager creates a Klondike manager.
+func NewManager(db *gorm.DB) *Manager {
+	return &Manager{
+		DB:    db,
+		games: make(map[uint]*Game),
+	}
+}
+
+// Start deals the player a new game, turning drawCount cards from the stock at a time.
+// A daily game deals today's daily deal, otherwise the deal is random. Asking for the
+// daily deal while it is in progress carries on with it, asking again once it has been
+// won or abandoned returns ErrDailyPlayed.
+func (m *Manager) Start(playerID uint, drawCount int, daily bool) (*View, error) {
+	if drawCount != 1 && drawCount != 3 {
+		return nil, ErrInvalidDraw
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var g *Game
+	if daily {
+		date := DailyDate(time.Now())
+		if current, ok := m.games[playerID]; ok && current.DailyDate == date {
+			return current.view(), nil
+		}
+
+		res := m.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.SolitaireDailyAttempt{
+			AccountID: playerID,
+			DailyDate: date,
+			DrawCount: drawCount,
+		})
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil, ErrDailyPlayed
+		}
+		g = newGame(dailySeed(date), drawCount, date)
+	} else {
+		g = newGame(rand.Int63(), drawCount, "")
+	}
+
+	m.games[playerID] = g
+	return g.view(), nil
+}
+
+// Current returns the player's game in progress.
+func (m *Manager) Current(playerID uint) (*View, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	g, ok := m.games[playerID]
+	if !ok {
+		return nil, ErrNoGame
+	}
+	return g.view(), nil
+}
+
+// Move makes a move in the player's game. A move that wins the game saves the result.
+func (m *Manager) Move(playerID uint, move Move) (*View, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	g, ok := m.games[playerID]
+	if !ok {
+		return nil, ErrNoGame
+	}
+	if err := g.apply(move); err != nil {
+		return nil, err
+	}
+	if g.Won {
+		delete(m.games, playerID)
+		result := &models.SolitaireResult{
+			AccountID:  playerID,
+			DailyDate:  g.DailyDate,
+			DrawCount:  g.DrawCount,
+			Moves:      g.Moves,
+			Score:      g.Score,
+			DurationMs: g.Duration.Milliseconds(),
+		}
+		if err := m.DB.Create(result).Error; err != nil {
+			return nil, err
+		}
+	}
+	return g.view(), nil
+}
+
+// LeaderboardEntry is a player's best result on a daily deal.
+type LeaderboardEntry struct {
+	Rank       int    `json:"rank"`
+	AccountID  uint   `json:"accountId"`
+	Username   string `json:"username"`
+	Moves      int    `json:"moves"`
+	DurationMs int64  `json:"durationMs"`
+	Score      int    `json:"score"`
+}
+
+// Leaderboard ranks the players who won the daily deal of date (YYYY-MM-DD) in
+// drawCount mode by fewest moves, then fastest time. Each player appears once, with
+// the game of their one attempt at the deal.
+func (m *Manager) Leaderboard(date string, drawCount int) ([]LeaderboardEntry, error) {
+	if drawCount != 1 && drawCount != 3 {
+		return nil, ErrInvalidDraw
+	}
+	if _, err := time.Parse(dailyDateFormat, date); err != nil {
+		return nil, ErrInvalidDate
+	}
+
+	var rows []struct {
+		models.SolitaireResult
+		Username string
+	}
+	err := m.DB.Model(&models.SolitaireResult{}).
+		Select("solitaire_results.*, accounts.username").
+		Joins("JOIN accounts ON accounts.id = solitaire_results.account_id").
+		Where("solitaire_results.daily_date = ? AND solitaire_results.draw_count = ?", date, drawCount).
+		Order("solitaire_results.moves ASC, solitaire_results.duration_ms ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	board := []LeaderboardEntry{}
+	seen := make(map[uint]bool)
+	for _, row := range rows {
+		if seen[row.AccountID] {
+			continue
+		}
+		seen[row.AccountID] = true
+		board = append(board, LeaderboardEntry{
+			Rank:       len(board) + 1,
+			AccountID:  row.AccountID,
+			Username:   row.Username,
+			Moves:      row.Moves,
+			DurationMs: row.DurationMs,
+			Score:      row.Score,
+		})
+		if len(board) == leaderboardSize {
+			break
+		}
+	}
+	return board, nil
+}
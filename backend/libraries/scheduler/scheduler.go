@@ -0,0 +1,386 @@
+// Package scheduler runs scheduled table events. An event opens a public table of
+// any registered game with preset rules at a set time, such as a Friday high-roller
+// blackjack table at 8pm, and keeps it open for a set duration. Users who subscribed
+// to the event get a notification when it opens. At the end the table is paused so
+// the round in progress can finish, then closed. Recurring events are scheduled again
+// once they have closed.
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"cardgames/backend/libraries/game"
+	gameinstancemanager "cardgames/backend/libraries/gameInstanceManager"
+	"cardgames/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// checkInterval is how often the manager looks for events that are due to open or close.
+const checkInterval = 10 * time.Second
+
+// closeGrace is how long a table that is due to close may keep playing the round in
+// progress before it is closed anyway. Closing refunds chips in an unsettled round.
+const closeGrace = 2 * time.Minute
+
+// Errors returned by the Manager.
+var (
+	ErrNotFound        = errors.New("event not found")
+	ErrInvalidSettings = errors.New("invalid event settings")
+	ErrEventOpen       = errors.New("event is open")
+)
+
+// Settings are the parameters used to create or change an event.
+type Settings struct {
+	Name            string          `json:"name"`
+	Game            string          `json:"game"`
+	Mode            string          `json:"mode"`
+	Rules           json.RawMessage `json:"rules,omitempty"` // game specific rules, as for a private table
+	StartsAt        time.Time       `json:"startsAt"`
+	DurationMinutes int             `json:"durationMinutes"`
+	RepeatDays      int             `json:"repeatDays"` // 0 for a one-off event, 7 for a weekly one
+}
+
+// Event is an event as users see it.
+type Event struct {
+	models.TableEvent
+	Subscribers int  `json:"subscribers"`
+	Subscribed  bool `json:"subscribed"` // the requesting user is subscribed
+}
+
+// Manager stores events and opens and closes their tables.
+type Manager struct {
+	mu      sync.Mutex
+	DB      *gorm.DB
+	GIM     *gameinstancemanager.GameInstanceManager
+	closing map[uint]time.Time // event ID -> when its table was paused to close
+	stop    chan struct{}
+}
+
+// NewManager creates an event manager and starts the background routine that
+// opens and closes event tables on time.
+func NewManager(db *gorm.DB, gim *gameinstancemanager.GameInstanceManager) *Manager {
+	m := &Manager{
+		DB:      db,
+		GIM:     gim,
+		closing: make(map[uint]time.Time),
+		stop:    make(chan struct{}),
+	}
+	m.reopenInterrupted()
+	go m.loop()
+	return m
+}
+
+// Stop halts the background routine.
+func (m *Manager) Stop() {
+	close(m.stop)
+}
+
+func (m *Manager) loop() {
+	ticker := time.NewTicker(checkInterval)
+	for {
+		select {
+		case <-ticker.C:
+			m.check()
+		case <-m.stop:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+// Create validates the settings and stores a new event.
+func (m *Manager) Create(creatorID uint, st Settings) (*models.TableEvent, error) {
+	ev := &models.TableEvent{CreatorID: creatorID, Status: models.EventScheduled}
+	if err := m.apply(ev, st); err != nil {
+		return nil, err
+	}
+	if err := m.DB.Create(ev).Error; err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
+
+// Update changes the settings of an event that is not open, and schedules it again if
+// it had finished or been cancelled.
+func (m *Manager) Update(eventID uint, st Settings) (*models.TableEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ev models.TableEvent
+	if err := m.DB.First(&ev, eventID).Error; err != nil {
+		return nil, ErrNotFound
+	}
+	if ev.Status == models.EventOpen {
+		return nil, ErrEventOpen
+	}
+	if err := m.apply(&ev, st); err != nil {
+		return nil, err
+	}
+	ev.Status = models.EventScheduled
+	if err := m.DB.Save(&ev).Error; err != nil {
+		return nil, err
+	}
+	return &ev, nil
+}
+
+// Cancel stops an event from opening again. An open event closes its table the same
+// way it would at the end of the event.
+func (m *Manager) Cancel(eventID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ev models.TableEvent
+	if err := m.DB.First(&ev, eventID).Error; err != nil {
+		return ErrNotFound
+	}
+	ev.RepeatDays = 0
+	if ev.Status == models.EventOpen {
+		ev.ClosesAt = time.Now()
+	} else {
+		ev.Status = models.EventCancelled
+	}
+	return m.DB.Save(&ev).Error
+}
+
+// apply validates st and copies it onto ev. The game, mode and rules are checked by
+// building a table from them, which is closed straight away.
+func (m *Manager) apply(ev *models.TableEvent, st Settings) error {
+	if st.StartsAt.IsZero() || st.DurationMinutes <= 0 || st.RepeatDays < 0 {
+		return ErrInvalidSettings
+	}
+	if st.RepeatDays > 0 && st.DurationMinutes > st.RepeatDays*24*60 {
+		return fmt.Errorf("%w: the event must end before it repeats", ErrInvalidSettings)
+	}
+	def, ok := game.Lookup(st.Game)
+	if !ok {
+		return fmt.Errorf("%w: unknown game %q", ErrInvalidSettings, st.Game)
+	}
+	mode, ok := def.Mode(st.Mode)
+	if !ok {
+		return fmt.Errorf("%w: unknown mode %q", ErrInvalidSettings, st.Mode)
+	}
+	g, err := def.New(m.DB, game.Config{Mode: mode, Rules: st.Rules})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSettings, err)
+	}
+	g.Close()
+
+	rules := ""
+	if len(st.Rules) > 0 && string(st.Rules) != "null" {
+		rules = string(st.Rules)
+	}
+	ev.Name = st.Name
+	ev.Game = def.Name
+	ev.Mode = mode
+	ev.Rules = rules
+	ev.StartsAt = st.StartsAt
+	ev.DurationMinutes = st.DurationMinutes
+	ev.RepeatDays = st.RepeatDays
+	return nil
+}
+
+// List returns every event that is scheduled or open, soonest first, along with the
+// number of subscribers and whether userID is one of them.
+func (m *Manager) List(userID uint) ([]Event, error) {
+	var evs []models.TableEvent
+	err := m.DB.Where("status IN ?", []string{models.EventScheduled, models.EventOpen}).
+		Order("starts_at").Find(&evs).Error
+	if err != nil {
+		return nil, err
+	}
+	return m.withSubscribers(evs, userID), nil
+}
+
+// All returns every event, finished and cancelled ones included, newest first.
+func (m *Manager) All() ([]Event, error) {
+	var evs []models.TableEvent
+	if err := m.DB.Order("starts_at desc").Find(&evs).Error; err != nil {
+		return nil, err
+	}
+	return m.withSubscribers(evs, 0), nil
+}
+
+// withSubscribers adds the subscriber counts to evs.
+func (m *Manager) withSubscribers(evs []models.TableEvent, userID uint) []Event {
+	list := make([]Event, len(evs))
+	for i, ev := range evs {
+		var count int64
+		m.DB.Model(&models.EventSubscription{}).Where("event_id = ?", ev.ID).Count(&count)
+		list[i] = Event{TableEvent: ev, Subscribers: int(count)}
+		if userID != 0 {
+			var mine int64
+			m.DB.Model(&models.EventSubscription{}).Where("event_id = ? AND account_id = ?", ev.ID, userID).Count(&mine)
+			list[i].Subscribed = mine > 0
+		}
+	}
+	return list
+}
+
+// Subscribe asks for a notification whenever the event opens. Subscribing twice is not an error.
+func (m *Manager) Subscribe(eventID uint, accountID uint) error {
+	var ev models.TableEvent
+	if err := m.DB.First(&ev, eventID).Error; err != nil {
+		return ErrNotFound
+	}
+	var count int64
+	m.DB.Model(&models.EventSubscription{}).Where("event_id = ? AND account_id = ?", eventID, accountID).Count(&count)
+	if count > 0 {
+		return nil
+	}
+	return m.DB.Create(&models.EventSubscription{EventID: eventID, AccountID: accountID}).Error
+}
+
+// Unsubscribe stops the notifications for an event.
+func (m *Manager) Unsubscribe(eventID uint, accountID uint) error {
+	return m.DB.Unscoped().Where("event_id = ? AND account_id = ?", eventID, accountID).
+		Delete(&models.EventSubscription{}).Error
+}
+
+// check opens the events that are due and closes the ones that are over.
+func (m *Manager) check() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var due []models.TableEvent
+	m.DB.Where("status = ? AND starts_at <= ?", models.EventScheduled, now).Find(&due)
+	for i := range due {
+		m.open(&due[i])
+	}
+
+	var over []models.TableEvent
+	m.DB.Where("status = ? AND closes_at <= ?", models.EventOpen, now).Find(&over)
+	for i := range over {
+		m.close(&over[i])
+	}
+}
+
+// open opens the event's table and notifies the subscribers.
+func (m *Manager) open(ev *models.TableEvent) {
+	if m.openTable(ev) {
+		m.notify(ev)
+	}
+}
+
+// openTable opens the event's table. An event whose whole run was missed, while the
+// server was down, is skipped. Returns whether the table was opened.
+func (m *Manager) openTable(ev *models.TableEvent) bool {
+	closesAt := ev.StartsAt.Add(time.Duration(ev.DurationMinutes) * time.Minute)
+	if !time.Now().Before(closesAt) {
+		log.Println("Event", ev.ID, "missed, it was over before it could open")
+		m.finish(ev)
+		return false
+	}
+
+	id, err := m.GIM.CreateScheduledGame(ev.Game, game.Config{Mode: ev.Mode, Rules: json.RawMessage(ev.Rules)})
+	if err != nil {
+		log.Println("Failed to open table for event", ev.ID, ":", err)
+		ev.Status = models.EventCancelled
+		m.DB.Save(ev)
+		return false
+	}
+	ev.Status = models.EventOpen
+	ev.TableID = id
+	ev.ClosesAt = closesAt
+	m.DB.Save(ev)
+	log.Println("Event", ev.ID, "opened table", id)
+	return true
+}
+
+// notify tells the event's subscribers that its table is open.
+func (m *Manager) notify(ev *models.TableEvent) {
+	var subs []models.EventSubscription
+	m.DB.Where("event_id = ?", ev.ID).Find(&subs)
+	if len(subs) == 0 {
+		return
+	}
+
+	name := ev.Name
+	if name == "" {
+		name = "A " + ev.Game + " event"
+	}
+	data, _ := json.Marshal(map[string]any{"eventId": ev.ID, "gameId": ev.TableID, "game": ev.Game})
+	notifications := make([]models.Notification, len(subs))
+	for i, sub := range subs {
+		notifications[i] = models.Notification{
+			AccountID: sub.AccountID,
+			Kind:      models.NotificationEventOpened,
+			Message:   fmt.Sprintf("%s is open until %s", name, ev.ClosesAt.Format("15:04")),
+			Data:      string(data),
+		}
+	}
+	if err := m.DB.Create(&notifications).Error; err != nil {
+		log.Println("Failed to notify subscribers of event", ev.ID, ":", err)
+	}
+}
+
+// close winds the event's table down. The table is paused first, so no new round
+// starts, and closed once it is between rounds or closeGrace has passed.
+func (m *Manager) close(ev *models.TableEvent) {
+	t, ok := m.GIM.GetTable(ev.TableID)
+	if !ok || game.IsClosed(t.Game) {
+		delete(m.closing, ev.ID)
+		m.finish(ev)
+		return
+	}
+
+	pausedAt, paused := m.closing[ev.ID]
+	if !paused {
+		if err := m.GIM.SetPaused(ev.TableID, true); err != nil && !errors.Is(err, gameinstancemanager.ErrNotPausable) {
+			log.Println("Failed to pause table", ev.TableID, "of event", ev.ID, ":", err)
+		}
+		pausedAt = time.Now()
+		m.closing[ev.ID] = pausedAt
+	}
+	if d, ok := t.Game.(game.Describer); ok && !d.Describe().BetweenRounds && time.Since(pausedAt) < closeGrace {
+		return
+	}
+
+	m.GIM.RemoveGame(ev.TableID)
+	delete(m.closing, ev.ID)
+	log.Println("Event", ev.ID, "closed table", ev.TableID)
+	m.finish(ev)
+}
+
+// finish schedules a recurring event for its next run, the first one after the run
+// that just ended that has not ended yet, and marks a one-off event finished.
+func (m *Manager) finish(ev *models.TableEvent) {
+	ev.TableID = ""
+	if ev.RepeatDays <= 0 {
+		ev.Status = models.EventFinished
+		m.DB.Save(ev)
+		return
+	}
+
+	duration := time.Duration(ev.DurationMinutes) * time.Minute
+	ev.StartsAt = ev.StartsAt.AddDate(0, 0, ev.RepeatDays)
+	for !time.Now().Before(ev.StartsAt.Add(duration)) {
+		ev.StartsAt = ev.StartsAt.AddDate(0, 0, ev.RepeatDays)
+	}
+	ev.Status = models.EventScheduled
+	m.DB.Save(ev)
+}
+
+// reopenInterrupted opens the tables again of events that were open when the server
+// last stopped and did not have their table restored, so they run to their end. The
+// subscribers were told when the event first opened and are not notified again.
+func (m *Manager) reopenInterrupted() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var open []models.TableEvent
+	m.DB.Where("status = ?", models.EventOpen).Find(&open)
+	for i := range open {
+		ev := &open[i]
+		if _, ok := m.GIM.GetTable(ev.TableID); ok {
+			continue
+		}
+		m.openTable(ev)
+	}
+}
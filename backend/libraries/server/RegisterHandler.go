@@ -67,7 +67,7 @@ func (s *Server) registerHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Creates a session cookie for the new user
-	cookie := createCookie(s.SM.Create(account.ID))
+	cookie := createCookie(s.SM.Create(account.ID, r.UserAgent(), clientIP(r)))
 	http.SetCookie(w, cookie)
 
 	// Sends success response
@@ -0,0 +1,124 @@
+// Package server provides HTTP handlers and server functionality for the card games application.
+// This file contains the admin API for the game tables: listing every instance, showing
+// the full state of one, pausing and resuming a table and force-closing it. Only
+// accounts with IsAdmin set can use it, they are granted at startup from the
+// comma-separated ADMIN_EMAILS environment variable.
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	gameinstancemanager "cardgames/backend/libraries/gameInstanceManager"
+	"cardgames/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// grantAdmins makes the accounts listed in ADMIN_EMAILS admins.
+func grantAdmins(db *gorm.DB) {
+	var emails []string
+	for _, e := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			emails = append(emails, e)
+		}
+	}
+	if len(emails) == 0 {
+		return
+	}
+	if err := db.Model(&models.Account{}).Where("email IN ?", emails).Update("is_admin", true).Error; err != nil {
+		log.Println("Failed to grant admins:", err)
+	}
+}
+
+// checkAdmin reports whether the request comes from a logged in admin. It writes
+// 401 for requests that are not logged in and 403 for players who are not admins.
+func (s *Server) checkAdmin(w http.ResponseWriter, r *http.Request) bool {
+	userID, ok := s.checkCookie(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return false
+	}
+	var account models.Account
+	if err := s.DB.Select("is_admin").Where("id = ?", userID).First(&account).Error; err != nil || !account.IsAdmin {
+		SendGenericResponse(w, false, http.StatusForbidden, "admins only")
+		return false
+	}
+	return true
+}
+
+// adminTablesHandler lists every table, public and private, with its phase, players,
+// shoe depth, uptime and rounds played.
+func (s *Server) adminTablesHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAdmin(w, r) {
+		return
+	}
+	SendGenericResponse(w, true, http.StatusOK, s.GIM.AdminTables())
+}
+
+// adminTableHandler shows the full state of a table, hidden cards included.
+func (s *Server) adminTableHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAdmin(w, r) {
+		return
+	}
+	table, err := s.GIM.AdminTable(r.PathValue("id"))
+	if err != nil {
+		sendAdminError(w, err)
+		return
+	}
+	SendGenericResponse(w, true, http.StatusOK, table)
+}
+
+// adminPauseHandler pauses a table once the round in progress is over.
+func (s *Server) adminPauseHandler(w http.ResponseWriter, r *http.Request) {
+	s.setPaused(w, r, true)
+}
+
+// adminResumeHandler resumes a paused table.
+func (s *Server) adminResumeHandler(w http.ResponseWriter, r *http.Request) {
+	s.setPaused(w, r, false)
+}
+
+// setPaused pauses or resumes the table named in the path.
+func (s *Server) setPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	if !s.checkAdmin(w, r) {
+		return
+	}
+	id := r.PathValue("id")
+	if err := s.GIM.SetPaused(id, paused); err != nil {
+		sendAdminError(w, err)
+		return
+	}
+	log.Printf("Admin set table %s paused=%v", id, paused)
+	SendGenericResponse(w, true, http.StatusOK, "ok")
+}
+
+// adminCloseHandler closes a table right away. Chips on the table in an unsettled
+// round go back to the players.
+func (s *Server) adminCloseHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAdmin(w, r) {
+		return
+	}
+	id := r.PathValue("id")
+	if err := s.GIM.ForceClose(id); err != nil {
+		sendAdminError(w, err)
+		return
+	}
+	log.Printf("Admin closed table %s", id)
+	SendGenericResponse(w, true, http.StatusOK, "closed")
+}
+
+// sendAdminError maps a game instance manager error to a response.
+func sendAdminError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gameinstancemanager.ErrTableNotFound):
+		SendGenericResponse(w, false, http.StatusNotFound, err.Error())
+	case errors.Is(err, gameinstancemanager.ErrNotPausable):
+		SendGenericResponse(w, false, http.StatusConflict, err.Error())
+	default:
+		SendGenericResponse(w, false, http.StatusInternalServerError, err.Error())
+	}
+}
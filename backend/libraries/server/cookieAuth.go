@@ -6,6 +6,7 @@
 package server
 
 import (
+	"net"
 	"net/http"
 	"os"
 )
@@ -38,6 +39,17 @@ func createCookie(sessionID string) *http.Cookie {
 	return &cookie
 }
 
+// clearedCookie returns a cookie that removes the session cookie from the client's browser.
+func clearedCookie() *http.Cookie {
+	return &http.Cookie{
+		Name:     "sessionId",
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		MaxAge:   -1,
+	}
+}
+
 // checkCookie validates the session cookie from an incoming HTTP request.
 // It retrieves the session ID from the cookie, looks up the session in the
 // session manager, and verifies it has not expired.
@@ -59,3 +71,12 @@ func (s *Server) checkCookie(r *http.Request) (uint, bool) {
 
 	return session.UserID, true
 }
+
+// clientIP returns the address of the client that sent the request, without the port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,186 @@
+// Package server provides HTTP handlers and server functionality for the card games application.
+// This file contains the handlers for scheduled table events: listing the upcoming
+// events and subscribing to them for players, and creating, changing and cancelling
+// them through the admin API.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"cardgames/backend/libraries/scheduler"
+)
+
+// eventIDFromPath parses the {id} path value of an event route.
+func eventIDFromPath(r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// listEventsHandler returns the events that are scheduled or open, soonest first,
+// with whether the user is subscribed to each.
+func (s *Server) listEventsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.checkCookie(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	events, err := s.Events.List(userID)
+	if err != nil {
+		SendGenericResponse(w, false, http.StatusInternalServerError, "could not load events")
+		return
+	}
+	SendGenericResponse(w, true, http.StatusOK, events)
+}
+
+// subscribeEventHandler signs the user up for a notification whenever the event opens.
+func (s *Server) subscribeEventHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.checkCookie(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	id, ok := eventIDFromPath(r)
+	if !ok {
+		SendGenericResponse(w, false, http.StatusBadRequest, "invalid event id")
+		return
+	}
+
+	err := s.Events.Subscribe(id, userID)
+	switch {
+	case err == nil:
+		SendGenericResponse(w, true, http.StatusOK, "subscribed")
+	case errors.Is(err, scheduler.ErrNotFound):
+		SendGenericResponse(w, false, http.StatusNotFound, err.Error())
+	default:
+		SendGenericResponse(w, false, http.StatusInternalServerError, "could not subscribe")
+	}
+}
+
+// unsubscribeEventHandler stops the user's notifications for an event.
+func (s *Server) unsubscribeEventHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.checkCookie(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	id, ok := eventIDFromPath(r)
+	if !ok {
+		SendGenericResponse(w, false, http.StatusBadRequest, "invalid event id")
+		return
+	}
+
+	if err := s.Events.Unsubscribe(id, userID); err != nil {
+		SendGenericResponse(w, false, http.StatusInternalServerError, "could not unsubscribe")
+		return
+	}
+	SendGenericResponse(w, true, http.StatusOK, "unsubscribed")
+}
+
+// adminEventsHandler lists every event, finished and cancelled ones included.
+func (s *Server) adminEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAdmin(w, r) {
+		return
+	}
+
+	events, err := s.Events.All()
+	if err != nil {
+		SendGenericResponse(w, false, http.StatusInternalServerError, "could not load events")
+		return
+	}
+	SendGenericResponse(w, true, http.StatusOK, events)
+}
+
+// adminCreateEventHandler schedules a new event. The request body holds the event
+// settings, the rules are the game's rules as for a private table.
+func (s *Server) adminCreateEventHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAdmin(w, r) {
+		return
+	}
+	userID, _ := s.checkCookie(r)
+
+	var req scheduler.Settings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendGenericResponse(w, false, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	ev, err := s.Events.Create(userID, req)
+	if err != nil {
+		sendEventError(w, err)
+		return
+	}
+	log.Printf("Admin scheduled event %d (%s) at %s", ev.ID, ev.Game, ev.StartsAt)
+	SendGenericResponse(w, true, http.StatusCreated, ev)
+}
+
+// adminUpdateEventHandler changes the settings of an event that is not open.
+func (s *Server) adminUpdateEventHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAdmin(w, r) {
+		return
+	}
+
+	id, ok := eventIDFromPath(r)
+	if !ok {
+		SendGenericResponse(w, false, http.StatusBadRequest, "invalid event id")
+		return
+	}
+
+	var req scheduler.Settings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendGenericResponse(w, false, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	ev, err := s.Events.Update(id, req)
+	if err != nil {
+		sendEventError(w, err)
+		return
+	}
+	log.Printf("Admin changed event %d", ev.ID)
+	SendGenericResponse(w, true, http.StatusOK, ev)
+}
+
+// adminCancelEventHandler cancels an event. An open event closes its table as it
+// would at its end.
+func (s *Server) adminCancelEventHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAdmin(w, r) {
+		return
+	}
+
+	id, ok := eventIDFromPath(r)
+	if !ok {
+		SendGenericResponse(w, false, http.StatusBadRequest, "invalid event id")
+		return
+	}
+
+	if err := s.Events.Cancel(id); err != nil {
+		sendEventError(w, err)
+		return
+	}
+	log.Printf("Admin cancelled event %d", id)
+	SendGenericResponse(w, true, http.StatusOK, "cancelled")
+}
+
+// sendEventError maps a scheduler error to a response.
+func sendEventError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, scheduler.ErrNotFound):
+		SendGenericResponse(w, false, http.StatusNotFound, err.Error())
+	case errors.Is(err, scheduler.ErrInvalidSettings):
+		SendGenericResponse(w, false, http.StatusBadRequest, err.Error())
+	case errors.Is(err, scheduler.ErrEventOpen):
+		SendGenericResponse(w, false, http.StatusConflict, err.Error())
+	default:
+		SendGenericResponse(w, false, http.StatusInternalServerError, "could not save event")
+	}
+}
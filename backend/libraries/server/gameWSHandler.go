@@ -0,0 +1,152 @@
+// Package server provides HTTP handlers and server functionality for the card games application.
+// This file contains the WebSocket handler for managing game connections, for any game
+// in the game registry.
+package server
+
+import (
+	"cardgames/backend/libraries/game"
+	"cardgames/backend/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"golang.org/x/net/websocket"
+)
+
+// gameWSHandler handles WebSocket connections for games.
+// It authenticates the user, validates the game name and ID, seats the player at the game,
+// and manages bidirectional communication between the client and game instance.
+// The handler forwards incoming player actions and sends game state updates.
+func (s *Server) gameWSHandler(w http.ResponseWriter, r *http.Request) {
+	userID, isAuth := s.checkCookie(r)
+	if !isAuth {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		log.Println("Unauthorized WebSocket connection attempt")
+		return
+	}
+
+	gameName := r.PathValue("game")
+	gameID := r.PathValue("gameID")
+	if gameID == "" {
+		http.Error(w, "gameID is required", http.StatusBadRequest)
+		return
+	}
+
+	table, ok := s.GIM.GetTable(gameID)
+	if !ok || table.GameName != gameName {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+	g := table.Game
+
+	// Password protected private tables take the password through a join token from
+	// joinTokenHandler, players with an invite from the host pass the invite token instead
+	req := game.JoinRequest{PlayerID: userID}
+	if token := r.URL.Query().Get("joinToken"); token != "" {
+		password, ok := s.joinTokens.redeem(token, userID, gameID)
+		if !ok {
+			http.Error(w, "Invalid join token", http.StatusForbidden)
+			return
+		}
+		req.Password = password
+	}
+
+	var inv *models.Invite
+	if token := r.URL.Query().Get("invite"); token != "" && !g.IsSeated(userID) {
+		var err error
+		inv, err = s.Invites.Redeem(token, userID)
+		if err != nil || inv.GameID != gameID {
+			if inv != nil {
+				s.Invites.Release(inv)
+			}
+			http.Error(w, "Invalid invite", http.StatusForbidden)
+			return
+		}
+		req.Invited = true
+	}
+
+	seat, err := g.Join(req)
+	if err != nil {
+		if inv != nil {
+			s.Invites.Release(inv)
+		}
+		status := http.StatusForbidden
+		if errors.Is(err, game.ErrTableFull) {
+			status = http.StatusConflict
+		} else if errors.Is(err, game.ErrTableClosed) {
+			status = http.StatusGone
+		}
+		http.Error(w, err.Error(), status)
+		log.Println("Unable to join game:", err)
+		return
+	}
+
+	// Let the lobby know, the manager only publishes it for public tables
+	s.GIM.PlayerJoined(gameID, userID)
+
+	wsLogic := func(ws *websocket.Conn) {
+		defer ws.Close()
+
+		cookie, _ := r.Cookie("sessionId")
+
+		if greeter, ok := g.(game.Greeter); ok {
+			greeter.Greet(userID)
+		}
+
+		done := make(chan struct{})
+
+		// Goroutine to read from WebSocket
+		go func() {
+			defer close(done)
+			for {
+				var msg json.RawMessage
+				if err := websocket.JSON.Receive(ws, &msg); err != nil {
+					// WebSocket closed or error
+					return
+				}
+
+				// Refresh user session on activity. A session that was revoked or has
+				// expired ends the connection, the seat is left like any dropped socket
+				if cookie == nil {
+					return
+				}
+				if _, ok := s.SM.Get(cookie.Value); !ok {
+					log.Println("Closing game connection of user", userID, ": session ended")
+					return
+				}
+
+				// Non-blocking send to the seat; the game attributes the message to this player
+				select {
+				case seat.Actions <- msg:
+				default:
+					// Game busy, skip
+				}
+			}
+		}()
+
+		for {
+			select {
+			case <-done:
+				// WebSocket read goroutine exited
+				g.Leave(seat)
+				s.GIM.PlayerLeft(gameID)
+				return
+			case update, ok := <-seat.State:
+				if !ok {
+					// Channel closed (player reconnected elsewhere, was kicked or the table closed).
+					// The seat is no longer this connection's, so it is not marked disconnected
+					return
+				}
+				if err := websocket.JSON.Send(ws, update); err != nil {
+					g.Leave(seat)
+					s.GIM.PlayerLeft(gameID)
+					return
+				}
+			}
+		}
+	}
+
+	websocket.Handler(wsLogic).ServeHTTP(w, r)
+
+}
@@ -0,0 +1,101 @@
+// Package server provides HTTP handlers and server functionality for the card games application.
+// This file contains the handler hosts use to create invite links for their private tables.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"cardgames/backend/libraries/game"
+	"cardgames/backend/models"
+)
+
+// Invite limits.
+const (
+	defaultInviteMinutes = 60
+	maxInviteMinutes     = 7 * 24 * 60
+	defaultInviteUses    = 1
+	maxInviteUses        = 50
+)
+
+// createInviteRequest is the JSON body for creating an invite.
+// FriendID is optional; when set only that friend can use the invite and they get a notification.
+type createInviteRequest struct {
+	GameID           string `json:"gameId"`
+	ExpiresInMinutes int    `json:"expiresInMinutes"`
+	MaxUses          int    `json:"maxUses"`
+	FriendID         uint   `json:"friendId"`
+}
+
+// createInviteHandler creates a signed invite token for a private table.
+// Only the current host of the table can create invites.
+func (s *Server) createInviteHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.checkCookie(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req createInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendGenericResponse(w, false, http.StatusBadRequest, "invalid request")
+		return
+	}
+	if req.ExpiresInMinutes == 0 {
+		req.ExpiresInMinutes = defaultInviteMinutes
+	}
+	if req.MaxUses == 0 {
+		req.MaxUses = defaultInviteUses
+	}
+	if req.ExpiresInMinutes < 0 || req.ExpiresInMinutes > maxInviteMinutes || req.MaxUses < 0 || req.MaxUses > maxInviteUses {
+		SendGenericResponse(w, false, http.StatusBadRequest, "invalid expiry or max uses")
+		return
+	}
+
+	table, ok := s.GIM.GetTable(req.GameID)
+	if !ok || !table.Private {
+		SendGenericResponse(w, false, http.StatusNotFound, "private game not found")
+		return
+	}
+	hosted, ok := table.Game.(game.Hosted)
+	if !ok || hosted.HostID() != userID {
+		SendGenericResponse(w, false, http.StatusForbidden, "only the host can invite players")
+		return
+	}
+
+	if req.FriendID != 0 {
+		var count int64
+		s.DB.Model(&models.Friend{}).Where("user_id = ? AND friend_id = ?", userID, req.FriendID).Count(&count)
+		if count == 0 {
+			SendGenericResponse(w, false, http.StatusBadRequest, "can only target friends")
+			return
+		}
+	}
+
+	token, inv, err := s.Invites.Create(userID, req.GameID, time.Duration(req.ExpiresInMinutes)*time.Minute, req.MaxUses, req.FriendID)
+	if err != nil {
+		SendGenericResponse(w, false, http.StatusInternalServerError, "could not create invite")
+		return
+	}
+
+	if req.FriendID != 0 {
+		var host models.Account
+		s.DB.First(&host, userID)
+		data, _ := json.Marshal(map[string]string{"gameId": req.GameID, "invite": token})
+		s.DB.Create(&models.Notification{
+			AccountID: req.FriendID,
+			Kind:      models.NotificationTableInvite,
+			Message:   fmt.Sprintf("%s invited you to their %s table", host.Username, table.GameName),
+			Data:      string(data),
+		})
+	}
+
+	SendGenericResponse(w, true, http.StatusCreated, map[string]any{
+		"invite":    token,
+		"gameId":    inv.GameID,
+		"expiresAt": inv.ExpiresAt,
+		"maxUses":   inv.MaxUses,
+	})
+}
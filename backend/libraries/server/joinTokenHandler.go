@@ -0,0 +1,113 @@
+// Package server provides HTTP handlers and server functionality for the card games application.
+// This file contains the join tokens for password protected tables. The password is
+// posted once and swapped for a short-lived, single-use token that the WebSocket URL
+// carries instead, so the password never ends up in access logs or browser history.
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// joinTokenTTL is how long a join token can be used for.
+const joinTokenTTL = time.Minute
+
+// joinToken is a table password held for one connection of a user to a table.
+type joinToken struct {
+	userID   uint
+	gameID   string
+	password string
+	expires  time.Time
+}
+
+// joinTokens holds the join tokens handed out and not used yet.
+type joinTokens struct {
+	mu     sync.Mutex
+	tokens map[string]joinToken
+}
+
+func newJoinTokens() *joinTokens {
+	return &joinTokens{tokens: make(map[string]joinToken)}
+}
+
+// issue keeps the password for the user's next connection to the table and returns
+// the token that redeems it.
+func (jt *joinTokens) issue(userID uint, gameID, password string) (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(bytes)
+
+	jt.mu.Lock()
+	defer jt.mu.Unlock()
+
+	now := time.Now()
+	for t, held := range jt.tokens {
+		if now.After(held.expires) {
+			delete(jt.tokens, t)
+		}
+	}
+	jt.tokens[token] = joinToken{userID: userID, gameID: gameID, password: password, expires: now.Add(joinTokenTTL)}
+	return token, nil
+}
+
+// redeem returns the password held by the token and uses the token up. It returns
+// false if the token has expired or was issued to another user or for another table.
+func (jt *joinTokens) redeem(token string, userID uint, gameID string) (string, bool) {
+	jt.mu.Lock()
+	defer jt.mu.Unlock()
+
+	held, ok := jt.tokens[token]
+	if !ok || held.userID != userID || held.gameID != gameID {
+		return "", false
+	}
+	delete(jt.tokens, token)
+	if time.Now().After(held.expires) {
+		return "", false
+	}
+	return held.password, true
+}
+
+// joinTokenRequest is the body of a join token request.
+type joinTokenRequest struct {
+	Password string `json:"password"`
+}
+
+// joinTokenResponse holds the token to pass as ?joinToken= when connecting to the table.
+type joinTokenResponse struct {
+	Token string `json:"token"`
+}
+
+// joinTokenHandler swaps a table password for a join token. The password is checked
+// when the token is used to join.
+func (s *Server) joinTokenHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.checkCookie(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	gameID := r.PathValue("gameID")
+	if _, ok := s.GIM.GetTable(gameID); !ok {
+		SendGenericResponse(w, false, http.StatusNotFound, "table not found")
+		return
+	}
+
+	var req joinTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendGenericResponse(w, false, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	token, err := s.joinTokens.issue(userID, gameID, req.Password)
+	if err != nil {
+		SendGenericResponse(w, false, http.StatusInternalServerError, "could not create join token")
+		return
+	}
+	SendGenericResponse(w, true, http.StatusOK, joinTokenResponse{Token: token})
+}
@@ -0,0 +1,124 @@
+// Package server provides HTTP handlers and server functionality for the card games application.
+// This file contains the handlers for Klondike solitaire: starting a game, making moves
+// and the daily deal leaderboard.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cardgames/backend/libraries/klondike"
+)
+
+// klondikeStartRequest is the body of a request to start a game.
+type klondikeStartRequest struct {
+	DrawCount int  `json:"drawCount"` // 1 or 3
+	Daily     bool `json:"daily"`     // play today's daily deal instead of a random one
+}
+
+// klondikeHandler returns the user's game in progress.
+func (s *Server) klondikeHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.checkCookie(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	view, err := s.KL.Current(userID)
+	if err != nil {
+		SendGenericResponse(w, false, http.StatusNotFound, err.Error())
+		return
+	}
+	SendGenericResponse(w, true, http.StatusOK, view)
+}
+
+// klondikeStartHandler deals the user a new game, abandoning any game in progress.
+// The daily deal can be played once a day.
+func (s *Server) klondikeStartHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.checkCookie(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req klondikeStartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendGenericResponse(w, false, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	view, err := s.KL.Start(userID, req.DrawCount, req.Daily)
+	switch {
+	case err == nil:
+		SendGenericResponse(w, true, http.StatusCreated, view)
+	case errors.Is(err, klondike.ErrInvalidDraw):
+		SendGenericResponse(w, false, http.StatusBadRequest, err.Error())
+	case errors.Is(err, klondike.ErrDailyPlayed):
+		SendGenericResponse(w, false, http.StatusConflict, err.Error())
+	default:
+		SendGenericResponse(w, false, http.StatusInternalServerError, "could not start game")
+	}
+}
+
+// klondikeMoveHandler makes a move in the user's game and returns the game after it.
+func (s *Server) klondikeMoveHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.checkCookie(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var move klondike.Move
+	if err := json.NewDecoder(r.Body).Decode(&move); err != nil {
+		SendGenericResponse(w, false, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	view, err := s.KL.Move(userID, move)
+	switch {
+	case err == nil:
+		SendGenericResponse(w, true, http.StatusOK, view)
+	case errors.Is(err, klondike.ErrNoGame):
+		SendGenericResponse(w, false, http.StatusNotFound, err.Error())
+	case errors.Is(err, klondike.ErrIllegalMove), errors.Is(err, klondike.ErrBadPile):
+		SendGenericResponse(w, false, http.StatusUnprocessableEntity, err.Error())
+	default:
+		SendGenericResponse(w, false, http.StatusInternalServerError, "could not save game")
+	}
+}
+
+// klondikeLeaderboardHandler returns the leaderboard of a daily deal.
+// Query parameters: date (YYYY-MM-DD, defaults to today) and draw (1 or 3, defaults to 1).
+func (s *Server) klondikeLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.checkCookie(r); !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = klondike.DailyDate(time.Now())
+	}
+	drawCount := 1
+	if d := r.URL.Query().Get("draw"); d != "" {
+		n, err := strconv.Atoi(d)
+		if err != nil {
+			SendGenericResponse(w, false, http.StatusBadRequest, klondike.ErrInvalidDraw.Error())
+			return
+		}
+		drawCount = n
+	}
+
+	board, err := s.KL.Leaderboard(date, drawCount)
+	switch {
+	case err == nil:
+		SendGenericResponse(w, true, http.StatusOK, board)
+	case errors.Is(err, klondike.ErrInvalidDraw), errors.Is(err, klondike.ErrInvalidDate):
+		SendGenericResponse(w, false, http.StatusBadRequest, err.Error())
+	default:
+		SendGenericResponse(w, false, http.StatusInternalServerError, "could not load leaderboard")
+	}
+}
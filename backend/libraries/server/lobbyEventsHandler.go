@@ -0,0 +1,118 @@
+// Package server provides HTTP handlers and server functionality for the card games application.
+// This file contains the Server-Sent Events stream of lobby changes, so the lobby page
+// sees tables open, fill up and close without polling.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	gameinstancemanager "cardgames/backend/libraries/gameInstanceManager"
+	"cardgames/backend/models"
+)
+
+// lobbyHeartbeat is how often a comment line is sent on an idle stream so proxies keep it open.
+const lobbyHeartbeat = 30 * time.Second
+
+// FriendSeated is the lobby event sent when one of the user's friends sits down at a public table.
+type FriendSeated struct {
+	TableID  string
+	Game     string
+	Mode     string
+	FriendID uint
+	Username string
+}
+
+// lobbyEventsHandler streams lobby changes to the client as Server-Sent Events.
+// The stream opens with a "tables" event holding the current table browser listing,
+// followed by "table_created", "table_closed" and "occupancy" events from the game
+// instance manager and "friend_seated" when a friend sits down at a public table.
+// The friend list is read when the stream opens.
+// A client that cannot keep up is disconnected, EventSource reconnects on its own
+// and gets a fresh listing.
+func (s *Server) lobbyEventsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.checkCookie(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		SendGenericResponse(w, false, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	var friends []models.Friend
+	if err := s.DB.Where("user_id = ?", userID).Find(&friends).Error; err != nil {
+		SendGenericResponse(w, false, http.StatusInternalServerError, "could not load friends")
+		return
+	}
+	friendIDs := make(map[uint]bool, len(friends))
+	for _, f := range friends {
+		friendIDs[f.FriendID] = true
+	}
+
+	// Subscribe before taking the listing so no change falls between the two
+	events, cancel := s.GIM.Subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeSSE(w, "tables", s.GIM.ListPublicTables(gameinstancemanager.TableFilter{})); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(lobbyHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case e, ok := <-events:
+			if !ok {
+				// Dropped for falling behind, or the server is shutting down
+				return
+			}
+			var err error
+			if e.Type == gameinstancemanager.PlayerSeated {
+				if !friendIDs[e.PlayerID] {
+					continue
+				}
+				seated := FriendSeated{TableID: e.TableID, Game: e.Game, Mode: e.Mode, FriendID: e.PlayerID}
+				var account models.Account
+				if s.DB.Select("username").Where("id = ?", e.PlayerID).First(&account).Error == nil {
+					seated.Username = account.Username
+				}
+				err = writeSSE(w, "friend_seated", seated)
+			} else {
+				err = writeSSE(w, string(e.Type), e)
+			}
+			if err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeSSE writes one Server-Sent Event with data encoded as JSON.
+func writeSSE(w http.ResponseWriter, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
@@ -7,22 +7,50 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+
+	"cardgames/backend/libraries/game"
+	gameinstancemanager "cardgames/backend/libraries/gameInstanceManager"
+	"cardgames/backend/models"
 )
 
 // LobbyRequest represents the JSON request body for lobby operations.
-// It specifies the game type and visibility (public or private) for the game.
+// It specifies the game type, visibility (public or private) and mode for the game.
+// Game is the name of a registered game, e.g. "blackjack". Mode is one of the game's
+// modes and defaults to its first; for blackjack that is "standard" for real-money tables,
+// "practice" for play-money tables, "trainer" for play-money tables with the card
+// counting trainer, or "spanish21" and "free_bet" for real-money tables of those variants.
+// Password and Rules are optional and only used when creating a private game; Rules
+// are decoded by the game.
+// Stake, SitWithFriends, FastPace and NoBots are the player's preferences for the
+// matchmaker when joining a public game.
+// Invite is an invite token from a table host; when set the other fields are ignored
+// and the invited table is returned.
+// GameID is a public table picked in the table browser; when set the other fields are
+// ignored and that table is returned if it has a free seat.
 type LobbyRequest struct {
-	Game       string `json:"game"`
-	Visibility string `json:"visibility"`
+	Game       string          `json:"game"`
+	Visibility string          `json:"visibility"`
+	Mode       string          `json:"mode"`
+	Password   string          `json:"password"`
+	Rules      json.RawMessage `json:"rules"`
+	Invite     string          `json:"invite"`
+	GameID     string          `json:"gameId"`
+
+	Stake          int  `json:"stake"`
+	SitWithFriends bool `json:"sitWithFriends"`
+	FastPace       bool `json:"fastPace"`
+	NoBots         bool `json:"noBots"`
 }
 
 // lobbyHandler handles requests to join or create game lobbies.
-// It authenticates the user, parses the request for game type and visibility,
-// then either finds an available public game or creates a new private game.
-// Returns the game ID on success for the client to connect via WebSocket.
+// It authenticates the user, looks the game up in the game registry, checks the mode,
+// then either lets the matchmaker pick a public game or creates a new private game.
+// Returns the game ID on success for the client to connect via WebSocket, for public
+// games along with the matchmaker's reason for picking it.
 func (s *Server) lobbyHandler(w http.ResponseWriter, r *http.Request) {
-	_, ok := s.checkCookie(r)
+	userID, ok := s.checkCookie(r)
 	if !ok {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
@@ -34,36 +62,126 @@ func (s *Server) lobbyHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	switch req.Game {
-	case "blackjack":
+	if req.Invite != "" {
+		// The invite is used up when the player connects to the table
+		inv, err := s.Invites.Check(req.Invite, userID)
+		if err != nil {
+			SendGenericResponse(w, false, http.StatusForbidden, err.Error())
+			return
+		}
+		table, ok := s.GIM.GetTable(inv.GameID)
+		if !ok {
+			SendGenericResponse(w, false, http.StatusNotFound, "game no longer exists")
+			return
+		}
+		SendGenericResponse(w, true, http.StatusOK, map[string]string{"gameId": inv.GameID, "game": table.GameName, "invite": req.Invite})
+		return
+	}
 
-		switch req.Visibility {
-		case "public":
+	if req.GameID != "" {
+		table, err := s.GIM.PublicTable(req.GameID, userID)
+		switch {
+		case err == nil:
+			SendGenericResponse(w, true, http.StatusOK, map[string]string{"gameId": table.ID, "game": table.GameName})
+		case errors.Is(err, gameinstancemanager.ErrTableFull):
+			SendGenericResponse(w, false, http.StatusConflict, err.Error())
+		default:
+			SendGenericResponse(w, false, http.StatusNotFound, err.Error())
+		}
+		return
+	}
 
-			id, err := s.GIM.FindAvailablePublicGame()
-			if err != nil {
-				SendGenericResponse(w, false, http.StatusInternalServerError, "could not create or find game")
-				return
-			}
-			SendGenericResponse(w, true, http.StatusOK, map[string]string{"gameId": id})
-			return
-		case "private":
+	def, ok := game.Lookup(req.Game)
+	if !ok {
+		SendGenericResponse(w, false, http.StatusBadRequest, "unsupported game")
+		return
+	}
+	if _, ok := def.Mode(req.Mode); !ok {
+		SendGenericResponse(w, false, http.StatusBadRequest, "invalid mode")
+		return
+	}
 
-			id, err := s.GIM.CreatePrivateGame()
-			if err != nil {
-				SendGenericResponse(w, false, http.StatusInternalServerError, "could not create game")
-				return
+	switch req.Visibility {
+	case "public":
+
+		match := gameinstancemanager.MatchRequest{
+			Game:           def.Name,
+			Mode:           req.Mode,
+			PlayerID:       userID,
+			Stake:          req.Stake,
+			SitWithFriends: req.SitWithFriends,
+			FastPace:       req.FastPace,
+			NoBots:         req.NoBots,
+			Skill:          s.skillRating(def.Name),
+		}
+		if req.SitWithFriends {
+			var friends []models.Friend
+			s.DB.Where("user_id = ?", userID).Find(&friends)
+			for _, f := range friends {
+				match.Friends = append(match.Friends, f.FriendID)
 			}
-			SendGenericResponse(w, true, http.StatusOK, map[string]string{"gameId": id})
+		}
+
+		m, err := s.GIM.Match(match)
+		if err != nil {
+			SendGenericResponse(w, false, http.StatusInternalServerError, "could not create or find game")
 			return
+		}
+		SendGenericResponse(w, true, http.StatusOK, map[string]string{"gameId": m.TableID, "reason": m.Reason})
+		return
+	case "private":
 
-		default:
-			SendGenericResponse(w, false, http.StatusBadRequest, "invalid visibility")
+		// The creator becomes the host of the private table
+		id, err := s.GIM.CreatePrivateGame(def.Name, game.Config{
+			Mode:     req.Mode,
+			HostID:   userID,
+			Password: req.Password,
+			Rules:    req.Rules,
+		})
+		if errors.Is(err, game.ErrInvalidConfig) {
+			SendGenericResponse(w, false, http.StatusBadRequest, "invalid rules")
 			return
 		}
+		if err != nil {
+			SendGenericResponse(w, false, http.StatusInternalServerError, "could not create game")
+			return
+		}
+		SendGenericResponse(w, true, http.StatusOK, map[string]string{"gameId": id})
+		return
 
 	default:
-		SendGenericResponse(w, false, http.StatusBadRequest, "unsupported game")
+		SendGenericResponse(w, false, http.StatusBadRequest, "invalid visibility")
 		return
 	}
 }
+
+// skillRatingWagers is how many of a player's latest wagers their skill rating is based on.
+const skillRatingWagers = 50
+
+// skillRating returns the matchmaker's skill rating for players of gameName: what
+// their latest wagers at the game paid back per chip staked. Players without wagers
+// rate 1, breaking even. Ratings are looked up once per player and match.
+func (s *Server) skillRating(gameName string) func(playerID uint) float64 {
+	ratings := make(map[uint]float64)
+	return func(playerID uint) float64 {
+		if rating, ok := ratings[playerID]; ok {
+			return rating
+		}
+		rating := 1.0
+		var wagers []models.Wager
+		err := s.DB.Where("account_id = ? AND game_type = ?", playerID, gameName).
+			Order("id desc").Limit(skillRatingWagers).Find(&wagers).Error
+		if err == nil {
+			staked, won := 0, 0
+			for _, w := range wagers {
+				staked += w.WagerAmount
+				won += w.AmountWon
+			}
+			if staked > 0 {
+				rating = float64(won) / float64(staked)
+			}
+		}
+		ratings[playerID] = rating
+		return rating
+	}
+}
@@ -38,14 +38,9 @@ func (s *Server) loginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	session, active := s.SM.CheckIfActiveSession(account.ID)
-	if !active {
-		sessionCookie := createCookie(s.SM.Create(account.ID))
-		http.SetCookie(w, sessionCookie)
-	} else {
-		sessionCookie := createCookie(session.SessionID)
-		http.SetCookie(w, sessionCookie)
-	}
+	// Each login gets its own session, so logging in on another device leaves this one alone
+	sessionCookie := createCookie(s.SM.Create(account.ID, r.UserAgent(), clientIP(r)))
+	http.SetCookie(w, sessionCookie)
 
 	SendGenericResponse(w, true, http.StatusOK, nil)
 }
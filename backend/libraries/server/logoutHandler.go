@@ -26,12 +26,5 @@ func (s *Server) logoutHandler(w http.ResponseWriter, r *http.Request) {
 	s.SM.Delete(sessionID)
 
 	// Clear the session cookie.
-	clearedCookie := &http.Cookie{
-		Name:     "sessionId",
-		Value:    "",
-		Path:     "/",
-		HttpOnly: true,
-		MaxAge:   -1,
-	}
-	http.SetCookie(w, clearedCookie)
+	http.SetCookie(w, clearedCookie())
 }
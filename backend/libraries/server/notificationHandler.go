@@ -0,0 +1,51 @@
+// Package server provides HTTP handlers and server functionality for the card games application.
+// This file contains the handlers for listing a user's notifications and marking them read.
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"cardgames/backend/models"
+)
+
+// notificationLimit is the number of notifications returned by the list endpoint.
+const notificationLimit = 50
+
+// notificationsHandler returns the user's most recent notifications, newest first.
+func (s *Server) notificationsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.checkCookie(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var notifications []models.Notification
+	if err := s.DB.Where("account_id = ?", userID).Order("created_at desc").Limit(notificationLimit).Find(&notifications).Error; err != nil {
+		SendGenericResponse(w, false, http.StatusInternalServerError, "could not load notifications")
+		return
+	}
+	SendGenericResponse(w, true, http.StatusOK, notifications)
+}
+
+// readNotificationHandler marks one of the user's notifications as read.
+func (s *Server) readNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.checkCookie(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		SendGenericResponse(w, false, http.StatusBadRequest, "invalid notification id")
+		return
+	}
+
+	res := s.DB.Model(&models.Notification{}).Where("id = ? AND account_id = ?", id, userID).Update("read", true)
+	if res.Error != nil || res.RowsAffected == 0 {
+		SendGenericResponse(w, false, http.StatusNotFound, "notification not found")
+		return
+	}
+	SendGenericResponse(w, true, http.StatusOK, nil)
+}
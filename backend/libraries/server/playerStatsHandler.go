@@ -14,6 +14,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+
+	"gorm.io/gorm"
 )
 
 // handler to get players stats and return them for player stats page
@@ -52,14 +54,21 @@ func (s *Server) playerStatsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Calculate stats from wagers table
+	// Calculate stats from wagers table, optionally for one game type (?game=blackjack)
 	var wagersPlaced int64
 	var wagersWon int64
 	var totalAmountWon int64
 
-	s.DB.Model(&models.Wager{}).Where("account_id = ?", userID).Count(&wagersPlaced)
-	s.DB.Model(&models.Wager{}).Where("account_id = ? AND wager_won = ?", userID, true).Count(&wagersWon)
-	s.DB.Model(&models.Wager{}).Where("account_id = ?", userID).Select("COALESCE(SUM(amount_won), 0)").Scan(&totalAmountWon)
+	wagers := func() *gorm.DB {
+		q := s.DB.Model(&models.Wager{}).Where("account_id = ?", userID)
+		if game := r.URL.Query().Get("game"); game != "" {
+			q = q.Where("game_type = ?", game)
+		}
+		return q
+	}
+	wagers().Count(&wagersPlaced)
+	wagers().Where("wager_won = ?", true).Count(&wagersWon)
+	wagers().Select("COALESCE(SUM(amount_won), 0)").Scan(&totalAmountWon)
 
 	wagersLost := wagersPlaced - wagersWon
 
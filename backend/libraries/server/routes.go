@@ -7,18 +7,47 @@ package server
 
 // setupRoutes registers all the HTTP handlers for the server.
 // It configures routes for authentication, game lobbies, WebSocket connections,
-// currency management, player statistics, user information, and store operations.
+// invites, notifications, tournaments, scheduled events, video poker, solitaire, currency management, player statistics, user information, store operations and table administration.
 func (s *Server) setupRoutes() {
 	s.Router.HandleFunc("POST /api/register", s.registerHandler)
 	s.Router.HandleFunc("POST /api/login", s.loginHandler)
 	s.Router.HandleFunc("POST /api/logout", s.logoutHandler)
+	s.Router.HandleFunc("GET /api/sessions", s.listSessionsHandler)
+	s.Router.HandleFunc("DELETE /api/sessions", s.revokeAllSessionsHandler)
+	s.Router.HandleFunc("DELETE /api/sessions/{id}", s.revokeSessionHandler)
 
 	// Routes that require auth go down here.
 	s.Router.HandleFunc("GET /api/auth", s.authHandler)
 	s.Router.HandleFunc("GET /api/active-players", s.activePlayersHandler)
 
 	s.Router.HandleFunc("POST /api/lobby", s.lobbyHandler)
-	s.Router.HandleFunc("GET /api/ws/BlackJack/{gameID}", s.blackJackWSHandler)
+	s.Router.HandleFunc("GET /api/lobby/tables", s.lobbyTablesHandler)
+	s.Router.HandleFunc("GET /api/lobby/events", s.lobbyEventsHandler)
+	s.Router.HandleFunc("GET /api/ws/{game}/{gameID}", s.gameWSHandler)
+	s.Router.HandleFunc("POST /api/tables/{gameID}/join-token", s.joinTokenHandler)
+	s.Router.HandleFunc("POST /api/invites", s.createInviteHandler)
+
+	s.Router.HandleFunc("GET /api/notifications", s.notificationsHandler)
+	s.Router.HandleFunc("POST /api/notifications/{id}/read", s.readNotificationHandler)
+
+	s.Router.HandleFunc("GET /api/tournaments", s.listTournamentsHandler)
+	s.Router.HandleFunc("POST /api/tournaments", s.createTournamentHandler)
+	s.Router.HandleFunc("GET /api/tournaments/{id}", s.tournamentStandingsHandler)
+	s.Router.HandleFunc("POST /api/tournaments/{id}/join", s.joinTournamentHandler)
+
+	s.Router.HandleFunc("GET /api/events", s.listEventsHandler)
+	s.Router.HandleFunc("POST /api/events/{id}/subscribe", s.subscribeEventHandler)
+	s.Router.HandleFunc("DELETE /api/events/{id}/subscribe", s.unsubscribeEventHandler)
+
+	s.Router.HandleFunc("GET /api/video-poker", s.videoPokerHandler)
+	s.Router.HandleFunc("GET /api/video-poker/paytables", s.videoPokerPayTablesHandler)
+	s.Router.HandleFunc("POST /api/video-poker/deal", s.videoPokerDealHandler)
+	s.Router.HandleFunc("POST /api/video-poker/draw", s.videoPokerDrawHandler)
+
+	s.Router.HandleFunc("GET /api/klondike", s.klondikeHandler)
+	s.Router.HandleFunc("POST /api/klondike", s.klondikeStartHandler)
+	s.Router.HandleFunc("POST /api/klondike/move", s.klondikeMoveHandler)
+	s.Router.HandleFunc("GET /api/klondike/leaderboard", s.klondikeLeaderboardHandler)
 
 	s.Router.HandleFunc("/api/currency", s.getCurrencyHandler)
 	s.Router.HandleFunc("/api/currency/add", s.addCurrencyHandler)
@@ -38,4 +67,14 @@ func (s *Server) setupRoutes() {
 	s.Router.HandleFunc("/api/store/buy", s.buyItemHandler)
 	s.Router.HandleFunc("/api/store/lootbox", s.lootboxHandler)
 
+	s.Router.HandleFunc("GET /api/admin/tables", s.adminTablesHandler)
+	s.Router.HandleFunc("GET /api/admin/tables/{id}", s.adminTableHandler)
+	s.Router.HandleFunc("POST /api/admin/tables/{id}/pause", s.adminPauseHandler)
+	s.Router.HandleFunc("POST /api/admin/tables/{id}/resume", s.adminResumeHandler)
+	s.Router.HandleFunc("POST /api/admin/tables/{id}/close", s.adminCloseHandler)
+	s.Router.HandleFunc("GET /api/admin/events", s.adminEventsHandler)
+	s.Router.HandleFunc("POST /api/admin/events", s.adminCreateEventHandler)
+	s.Router.HandleFunc("PUT /api/admin/events/{id}", s.adminUpdateEventHandler)
+	s.Router.HandleFunc("DELETE /api/admin/events/{id}", s.adminCancelEventHandler)
+
 }
@@ -8,9 +8,19 @@ package server
 import (
 	"log"
 	"net/http"
+	"os"
 
+	_ "cardgames/backend/libraries/baccarat"  // registers baccarat with the game registry
+	_ "cardgames/backend/libraries/blackjack" // registers blackjack with the game registry
 	gameinstancemanager "cardgames/backend/libraries/gameInstanceManager"
+	_ "cardgames/backend/libraries/hearts" // registers Hearts with the game registry
+	_ "cardgames/backend/libraries/holdem" // registers Texas Hold'em with the game registry
+	"cardgames/backend/libraries/invite"
+	"cardgames/backend/libraries/klondike"
+	"cardgames/backend/libraries/scheduler"
 	sessionmanager "cardgames/backend/libraries/sessionManager"
+	"cardgames/backend/libraries/tournament"
+	"cardgames/backend/libraries/videopoker"
 	"cardgames/backend/models"
 
 	"gorm.io/driver/sqlite"
@@ -21,10 +31,17 @@ const DEV_MODE = true
 
 // Server holds dependencies for the application.
 type Server struct {
-	DB     *gorm.DB
-	Router *http.ServeMux
-	SM     *sessionmanager.SessionManager
-	GIM    *gameinstancemanager.GameInstanceManager
+	DB      *gorm.DB
+	Router  *http.ServeMux
+	SM      *sessionmanager.SessionManager
+	GIM     *gameinstancemanager.GameInstanceManager
+	TM      *tournament.Manager
+	Invites *invite.Manager
+	VP      *videopoker.Manager
+	KL      *klondike.Manager
+	Events  *scheduler.Manager
+
+	joinTokens *joinTokens // passwords posted for joining private tables
 }
 
 // NewServer creates and returns a new Server instance.
@@ -35,20 +52,33 @@ func NewServer() *Server {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	runMigrations(db)
+	grantAdmins(db)
 
 	// session manager set up
-	sm := sessionmanager.NewSessionManager()
+	sm := sessionmanager.NewSessionManagerWithStore(newSessionStore(db))
 
 	// game instance manager set up
 	gim := gameinstancemanager.NewGameInstanceManager(db)
-	gim.Start() // Start the background cleanup goroutine
+
+	// tournament manager set up
+	tm := tournament.NewManager(db, gim)
+
+	// event scheduler set up, after the game instance manager has restored its tables
+	events := scheduler.NewManager(db, gim)
 
 	// set server config
 	s := &Server{
-		DB:     db,
-		Router: http.NewServeMux(),
-		SM:     sm,
-		GIM:    gim,
+		DB:      db,
+		Router:  http.NewServeMux(),
+		SM:      sm,
+		GIM:     gim,
+		TM:      tm,
+		Invites: invite.NewManager(db),
+		VP:      videopoker.NewManager(db),
+		KL:      klondike.NewManager(db),
+		Events:  events,
+
+		joinTokens: newJoinTokens(),
 	}
 	s.setupRoutes()
 
@@ -56,6 +86,17 @@ func NewServer() *Server {
 	return s
 }
 
+// newSessionStore picks where sessions are kept from the SESSION_STORE environment
+// variable. "memory" keeps them in the process, anything else keeps them in the
+// database so players stay logged in across a restart.
+func newSessionStore(db *gorm.DB) sessionmanager.SessionStore {
+	if os.Getenv("SESSION_STORE") == "memory" {
+		log.Println("Keeping sessions in memory")
+		return sessionmanager.NewMemoryStore()
+	}
+	return sessionmanager.NewSQLiteStore(db)
+}
+
 // Runs migrations
 func runMigrations(db *gorm.DB) {
 	err := db.AutoMigrate(
@@ -75,6 +116,41 @@ func runMigrations(db *gorm.DB) {
 	if err != nil {
 		log.Fatalf("Failed to auto-migrate: %v", err)
 	}
+
+	err = db.AutoMigrate(&models.Tournament{}, &models.TournamentEntry{})
+	if err != nil {
+		log.Fatalf("Failed to auto-migrate: %v", err)
+	}
+
+	err = db.AutoMigrate(&models.Invite{}, &models.Notification{})
+	if err != nil {
+		log.Fatalf("Failed to auto-migrate: %v", err)
+	}
+
+	err = db.AutoMigrate(&models.VideoPokerHand{})
+	if err != nil {
+		log.Fatalf("Failed to auto-migrate: %v", err)
+	}
+
+	err = db.AutoMigrate(&models.SolitaireResult{}, &models.SolitaireDailyAttempt{})
+	if err != nil {
+		log.Fatalf("Failed to auto-migrate: %v", err)
+	}
+
+	err = db.AutoMigrate(&models.TableSnapshot{})
+	if err != nil {
+		log.Fatalf("Failed to auto-migrate: %v", err)
+	}
+
+	err = db.AutoMigrate(&models.TableEvent{}, &models.EventSubscription{})
+	if err != nil {
+		log.Fatalf("Failed to auto-migrate: %v", err)
+	}
+
+	err = db.AutoMigrate(&models.Session{})
+	if err != nil {
+		log.Fatalf("Failed to auto-migrate: %v", err)
+	}
 }
 
 // Start runs the HTTP server on a given address.
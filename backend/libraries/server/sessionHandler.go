@@ -0,0 +1,90 @@
+// Package server provides HTTP handlers and server functionality for the card games application.
+// This file contains the handlers for managing a user's sessions: listing the devices
+// they are logged in on, logging one of them out, and logging out everywhere.
+package server
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	sessionmanager "cardgames/backend/libraries/sessionManager"
+)
+
+// sessionInfo describes one of the user's sessions. ID is the session's public ID,
+// never the session ID itself.
+type sessionInfo struct {
+	ID        string    `json:"id"`
+	UserAgent string    `json:"userAgent"`
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"createdAt"`
+	LastSeen  time.Time `json:"lastSeen"`
+	Current   bool      `json:"current"` // the session the request was made with
+}
+
+// currentSession returns the session of the request's session cookie.
+func (s *Server) currentSession(r *http.Request) (sessionmanager.Session, bool) {
+	cookie, err := r.Cookie("sessionId")
+	if err != nil {
+		return sessionmanager.Session{}, false
+	}
+	return s.SM.Get(cookie.Value)
+}
+
+// listSessionsHandler returns the user's active sessions, last seen first.
+func (s *Server) listSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	current, ok := s.currentSession(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	sessions := []sessionInfo{}
+	for _, session := range s.SM.ListByUser(current.UserID) {
+		sessions = append(sessions, sessionInfo{
+			ID:        session.PublicID(),
+			UserAgent: session.UserAgent,
+			IP:        session.IP,
+			CreatedAt: session.CreatedAt,
+			LastSeen:  session.LastSeen,
+			Current:   session.PublicID() == current.PublicID(),
+		})
+	}
+	SendGenericResponse(w, true, http.StatusOK, sessions)
+}
+
+// revokeSessionHandler logs out one of the user's sessions. Revoking the session the
+// request was made with also clears its cookie.
+func (s *Server) revokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	current, ok := s.currentSession(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	publicID := r.PathValue("id")
+	if !s.SM.Revoke(current.UserID, publicID) {
+		SendGenericResponse(w, false, http.StatusNotFound, "session not found")
+		return
+	}
+	if publicID == current.PublicID() {
+		http.SetCookie(w, clearedCookie())
+	}
+	SendGenericResponse(w, true, http.StatusOK, "logged out")
+}
+
+// revokeAllSessionsHandler logs the user out everywhere, the session the request was
+// made with included.
+func (s *Server) revokeAllSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	current, ok := s.currentSession(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	removed := s.SM.DeleteByUser(current.UserID)
+	log.Printf("User %d logged out of %d sessions", current.UserID, removed)
+
+	http.SetCookie(w, clearedCookie())
+	SendGenericResponse(w, true, http.StatusOK, "logged out everywhere")
+}
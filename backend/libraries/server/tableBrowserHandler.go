@@ -0,0 +1,46 @@
+// Package server provides HTTP handlers and server functionality for the card games application.
+// This file contains the handler for the lobby's table browser, which lists the public tables.
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	gameinstancemanager "cardgames/backend/libraries/gameInstanceManager"
+)
+
+// lobbyTablesHandler lists the public tables for the table browser.
+// The query parameters filter and sort the list: game, mode and phase match exactly,
+// open=true leaves out full tables, maxStake leaves out tables whose minimum stake is
+// higher, sort is one of players, open, stakes or id and order=desc reverses it.
+// A table from the list is joined by sending its ID as gameId to the lobby.
+func (s *Server) lobbyTablesHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.checkCookie(r); !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	filter := gameinstancemanager.TableFilter{
+		Game:     q.Get("game"),
+		Mode:     q.Get("mode"),
+		Phase:    q.Get("phase"),
+		OpenOnly: q.Get("open") == "true",
+		Sort:     q.Get("sort"),
+		Desc:     q.Get("order") == "desc",
+	}
+	if !gameinstancemanager.ValidSort(filter.Sort) {
+		SendGenericResponse(w, false, http.StatusBadRequest, "invalid sort")
+		return
+	}
+	if v := q.Get("maxStake"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			SendGenericResponse(w, false, http.StatusBadRequest, "invalid maxStake")
+			return
+		}
+		filter.MaxStake = n
+	}
+
+	SendGenericResponse(w, true, http.StatusOK, s.GIM.ListPublicTables(filter))
+}
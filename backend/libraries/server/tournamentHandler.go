@@ -0,0 +1,115 @@
+// Package server provides HTTP handlers and server functionality for the card games application.
+// This file contains the handlers for creating, joining and viewing blackjack tournaments.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"cardgames/backend/libraries/tournament"
+)
+
+// tournamentIDFromPath parses the {id} path value of a tournament route.
+func tournamentIDFromPath(r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// listTournamentsHandler returns every tournament.
+func (s *Server) listTournamentsHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.checkCookie(r); !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	ts, err := s.TM.List()
+	if err != nil {
+		SendGenericResponse(w, false, http.StatusInternalServerError, "could not load tournaments")
+		return
+	}
+	SendGenericResponse(w, true, http.StatusOK, ts)
+}
+
+// createTournamentHandler creates a new tournament open for registration.
+// The request body holds the tournament settings, missing values get defaults.
+func (s *Server) createTournamentHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.checkCookie(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req tournament.Settings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendGenericResponse(w, false, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	t, err := s.TM.Create(userID, req)
+	if err != nil {
+		if errors.Is(err, tournament.ErrInvalidSettings) {
+			SendGenericResponse(w, false, http.StatusBadRequest, err.Error())
+			return
+		}
+		SendGenericResponse(w, false, http.StatusInternalServerError, "could not create tournament")
+		return
+	}
+	SendGenericResponse(w, true, http.StatusCreated, t)
+}
+
+// joinTournamentHandler registers the user for a tournament and takes the buy-in.
+func (s *Server) joinTournamentHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.checkCookie(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	id, ok := tournamentIDFromPath(r)
+	if !ok {
+		SendGenericResponse(w, false, http.StatusBadRequest, "invalid tournament id")
+		return
+	}
+
+	entry, err := s.TM.Join(id, userID)
+	switch {
+	case err == nil:
+		SendGenericResponse(w, true, http.StatusOK, entry)
+	case errors.Is(err, tournament.ErrNotFound):
+		SendGenericResponse(w, false, http.StatusNotFound, err.Error())
+	case errors.Is(err, tournament.ErrRegistrationClosed),
+		errors.Is(err, tournament.ErrAlreadyRegistered),
+		errors.Is(err, tournament.ErrTournamentFull),
+		errors.Is(err, tournament.ErrInsufficientFunds):
+		SendGenericResponse(w, false, http.StatusConflict, err.Error())
+	default:
+		SendGenericResponse(w, false, http.StatusInternalServerError, "could not join tournament")
+	}
+}
+
+// tournamentStandingsHandler returns a tournament with its current standings,
+// including the table each remaining player is seated at.
+func (s *Server) tournamentStandingsHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.checkCookie(r); !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	id, ok := tournamentIDFromPath(r)
+	if !ok {
+		SendGenericResponse(w, false, http.StatusBadRequest, "invalid tournament id")
+		return
+	}
+
+	view, err := s.TM.Standings(id)
+	if err != nil {
+		SendGenericResponse(w, false, http.StatusNotFound, err.Error())
+		return
+	}
+	SendGenericResponse(w, true, http.StatusOK, view)
+}
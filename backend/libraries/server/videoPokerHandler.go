@@ -0,0 +1,102 @@
+// Package server provides HTTP handlers and server functionality for the card games application.
+// This file contains the handlers for single player video poker: dealing a hand, drawing
+// it and listing the pay tables.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"cardgames/backend/libraries/videopoker"
+)
+
+// videoPokerDealRequest is the body of a deal request.
+type videoPokerDealRequest struct {
+	Bet      int    `json:"bet"`
+	PayTable string `json:"payTable"` // optional, defaults to videopoker.DefaultPayTable
+}
+
+// videoPokerDrawRequest is the body of a draw request.
+type videoPokerDrawRequest struct {
+	Hold []int `json:"hold"` // positions of the cards to keep, 0 to 4
+}
+
+// videoPokerHandler returns the user's open hand, or 404 if they have none.
+func (s *Server) videoPokerHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.checkCookie(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	hand, ok := s.VP.Current(userID)
+	if !ok {
+		SendGenericResponse(w, false, http.StatusNotFound, videopoker.ErrNoHand.Error())
+		return
+	}
+	SendGenericResponse(w, true, http.StatusOK, hand)
+}
+
+// videoPokerPayTablesHandler returns the pay tables a hand can be dealt on.
+func (s *Server) videoPokerPayTablesHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.checkCookie(r); !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	SendGenericResponse(w, true, http.StatusOK, s.VP.PayTables())
+}
+
+// videoPokerDealHandler takes the bet and deals a new hand.
+func (s *Server) videoPokerDealHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.checkCookie(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req videoPokerDealRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendGenericResponse(w, false, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	hand, err := s.VP.Deal(userID, req.Bet, req.PayTable)
+	switch {
+	case err == nil:
+		SendGenericResponse(w, true, http.StatusOK, hand)
+	case errors.Is(err, videopoker.ErrInvalidBet), errors.Is(err, videopoker.ErrUnknownPayTable):
+		SendGenericResponse(w, false, http.StatusBadRequest, err.Error())
+	case errors.Is(err, videopoker.ErrHandInProgress), errors.Is(err, videopoker.ErrInsufficientFunds):
+		SendGenericResponse(w, false, http.StatusConflict, err.Error())
+	default:
+		SendGenericResponse(w, false, http.StatusInternalServerError, "could not deal hand")
+	}
+}
+
+// videoPokerDrawHandler draws the cards that were not held and pays the hand.
+func (s *Server) videoPokerDrawHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.checkCookie(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req videoPokerDrawRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendGenericResponse(w, false, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	result, err := s.VP.Draw(userID, req.Hold)
+	switch {
+	case err == nil:
+		SendGenericResponse(w, true, http.StatusOK, result)
+	case errors.Is(err, videopoker.ErrInvalidHold):
+		SendGenericResponse(w, false, http.StatusBadRequest, err.Error())
+	case errors.Is(err, videopoker.ErrNoHand):
+		SendGenericResponse(w, false, http.StatusConflict, err.Error())
+	default:
+		SendGenericResponse(w, false, http.StatusInternalServerError, "could not draw hand")
+	}
+}
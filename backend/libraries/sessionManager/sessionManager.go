@@ -7,9 +7,9 @@ package sessionmanager
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"log"
-	"sync"
 	"time"
 )
 
@@ -17,34 +17,64 @@ import (
 const cleanupInterval = 600
 const sessionLength = 300
 
+// refreshAfter is how long after its expiry was last pushed back a session is pushed
+// back again on use, so a busy connection does not write to the store on every message.
+const refreshAfter = 30 * time.Second
+
 // could add more fields to this and store in db so user can see historical how they have done each session
-type sessionData struct {
+type Session struct {
 	UserID    uint
 	SessionID string
 	Expiry    time.Time
+	UserAgent string    // user agent of the device the session was created on
+	IP        string    // address the session was created from
+	CreatedAt time.Time // when the user logged in
+	LastSeen  time.Time // when the session was last used, to within refreshAfter
+
+	hash string // hashSessionID of the session ID, set by stores that do not keep the ID itself
 }
 
-func (sd sessionData) IsExpired() bool {
+func (sd Session) IsExpired() bool {
 	return time.Now().After(sd.Expiry)
 }
 
+// PublicID names the session to its user without giving away the session ID, which
+// is all it takes to use the session.
+func (sd Session) PublicID() string {
+	if sd.hash != "" {
+		return sd.hash[:16]
+	}
+	return hashSessionID(sd.SessionID)[:16]
+}
+
+// hashSessionID returns the hex SHA-256 of a session ID. Stores that write sessions
+// to disk key them by it, so a leaked database does not hand out working cookies.
+func hashSessionID(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:])
+}
+
 type SessionManager struct {
-	mu          sync.RWMutex           // mutex so we dont get any race conditions. Need to call mw.rLock() or mu.rwLock() and their corresponding unlock functions to use
-	sessions    map[string]sessionData // maps session ID to session data
-	idToSession map[uint]sessionData
+	store SessionStore // where the sessions are kept, see store.go
 
 	stop   chan struct{}
 	ticker *time.Ticker
 }
 
+// NewSessionManager creates a session manager that keeps its sessions in memory.
 func NewSessionManager() *SessionManager {
+	return NewSessionManagerWithStore(NewMemoryStore())
+}
+
+// NewSessionManagerWithStore creates a session manager that keeps its sessions in store.
+func NewSessionManagerWithStore(store SessionStore) *SessionManager {
 	sm := &SessionManager{
-		sessions:    make(map[string]sessionData),
-		idToSession: make(map[uint]sessionData),
-		stop:        make(chan struct{}),
-		ticker:      time.NewTicker(cleanupInterval * time.Second),
+		store:  store,
+		stop:   make(chan struct{}),
+		ticker: time.NewTicker(cleanupInterval * time.Second),
 	}
 
+	sm.deleteExpired() // sessions kept across a restart may have expired while the server was down
 	go sm.cleanUpSessions()
 
 	return sm
@@ -62,16 +92,14 @@ func (sm *SessionManager) cleanUpSessions() {
 	}
 }
 
-func (sm *SessionManager) deleteExpired() {
-
-	sm.mu.Lock()         // gets full read write lock
-	defer sm.mu.Unlock() // unlock the lock after the function call
+// Stop halts the clean up routine.
+func (sm *SessionManager) Stop() {
+	close(sm.stop)
+}
 
-	for id, data := range sm.sessions {
-		if data.IsExpired() {
-			delete(sm.sessions, id)
-			log.Println("removed ", id, " from active sessions due to expiry")
-		}
+func (sm *SessionManager) deleteExpired() {
+	if removed := sm.store.DeleteExpired(time.Now()); removed > 0 {
+		log.Println("removed", removed, "sessions from active sessions due to expiry")
 	}
 }
 
@@ -83,67 +111,72 @@ func generateRandomString(length int) (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
-func (sm *SessionManager) Create(userID uint) (sessionID string) {
+// Create starts a new session for the user on the device with the given user agent
+// and address. The user's other sessions are left alone.
+func (sm *SessionManager) Create(userID uint, userAgent, ip string) (sessionID string) {
 	randomString, err := generateRandomString(32)
 	if err != nil {
 		panic(err)
 	}
 
-	sm.mu.Lock()         //gets full read write lock
-	defer sm.mu.Unlock() // unlocks after the function returns
-
-	sm.sessions[randomString] = sessionData{
+	now := time.Now()
+	session := Session{
 		UserID:    userID,
 		SessionID: randomString,
-		Expiry:    time.Now().Add(sessionLength * time.Second),
+		Expiry:    now.Add(sessionLength * time.Second),
+		UserAgent: userAgent,
+		IP:        ip,
+		CreatedAt: now,
+		LastSeen:  now,
+	}
+	if err := sm.store.Save(session); err != nil {
+		log.Println("Failed to save session for user", userID, ":", err)
 	}
-
-	sm.idToSession[uint(userID)] = sm.sessions[randomString]
 
 	return randomString
 }
 
-func (sm *SessionManager) Get(sessionID string) (sessionData, bool) {
-	sm.mu.Lock()
-	defer sm.mu.Unlock()
-
-	data, ok := sm.sessions[sessionID]
-	if !ok { // key was not found in map
-		return sessionData{}, false
+// Get returns the session and pushes its expiry back, or false if there is no such
+// session or it has expired. A session revoked while it is being refreshed stays gone.
+func (sm *SessionManager) Get(sessionID string) (Session, bool) {
+	data, ok := sm.store.Get(sessionID)
+	if !ok { // session was not found or has expired
+		return Session{}, false
 	}
 
-	data.Expiry = time.Now().Add(sessionLength * time.Second)
-	sm.sessions[sessionID] = data
+	now := time.Now()
+	expiry := now.Add(sessionLength * time.Second)
+	if expiry.Sub(data.Expiry) >= refreshAfter {
+		if !sm.store.Touch(sessionID, expiry, now) {
+			return Session{}, false
+		}
+		data.Expiry = expiry
+		data.LastSeen = now
+	}
 
 	return data, true
 }
 
 func (sm *SessionManager) ActiveSessions() int {
-	sm.mu.RLock()
-	defer sm.mu.RUnlock()
-	return len(sm.sessions)
+	return sm.store.Count()
 }
 
-func (sm *SessionManager) CheckIfActiveSession(userID uint) (sessionData, bool) {
-	sm.mu.RLock()
-	defer sm.mu.RUnlock()
-
-	session, ok := sm.idToSession[userID]
-	if !ok || session.IsExpired() {
-		return sessionData{}, false
-	}
-	return session, true
+// ListByUser returns the user's active sessions, last seen first.
+func (sm *SessionManager) ListByUser(userID uint) []Session {
+	return sm.store.ListByUser(userID)
 }
 
 func (sm *SessionManager) Delete(sessionID string) {
-	sm.mu.Lock()
-	defer sm.mu.Unlock()
+	sm.store.Delete(sessionID)
+}
 
-	data, ok := sm.sessions[sessionID]
-	if !ok {
-		return
-	}
+// Revoke ends the user's session with the given PublicID. It returns false if the user
+// has no such session.
+func (sm *SessionManager) Revoke(userID uint, publicID string) bool {
+	return sm.store.Revoke(userID, publicID)
+}
 
-	delete(sm.sessions, sessionID)
-	delete(sm.idToSession, data.UserID)
+// DeleteByUser ends every session of the user, logging them out everywhere.
+func (sm *SessionManager) DeleteByUser(userID uint) int {
+	return sm.store.DeleteByUser(userID)
 }
@@ -0,0 +1,141 @@
+// File contains the SQLite session store. Sessions are kept in the sessions table of
+// the server's database, so players stay logged in when the server restarts. Rows are
+// keyed by the SHA-256 of the session ID, the ID itself is never written to disk.
+
+package sessionmanager
+
+import (
+	"log"
+	"time"
+
+	"cardgames/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// sqliteStore keeps sessions in the database.
+type sqliteStore struct {
+	db *gorm.DB
+}
+
+// NewSQLiteStore creates a session store on db. The models.Session table must
+// already have been migrated.
+func NewSQLiteStore(db *gorm.DB) SessionStore {
+	return &sqliteStore{db: db}
+}
+
+func (ss *sqliteStore) Save(session Session) error {
+	row := models.Session{
+		ID:        hashSessionID(session.SessionID),
+		UserID:    session.UserID,
+		Expiry:    session.Expiry,
+		UserAgent: session.UserAgent,
+		IP:        session.IP,
+		CreatedAt: session.CreatedAt,
+		LastSeen:  session.LastSeen,
+	}
+	return ss.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"expiry", "last_seen"}),
+	}).Create(&row).Error
+}
+
+func (ss *sqliteStore) Get(sessionID string) (Session, bool) {
+	var row models.Session
+	err := ss.db.Where("id = ? AND expiry > ?", hashSessionID(sessionID), time.Now()).Take(&row).Error
+	if err != nil {
+		return Session{}, false
+	}
+	session := fromRow(row)
+	session.SessionID = sessionID
+	return session, true
+}
+
+func (ss *sqliteStore) Touch(sessionID string, expiry, lastSeen time.Time) bool {
+	res := ss.db.Model(&models.Session{}).Where("id = ?", hashSessionID(sessionID)).
+		Updates(map[string]any{"expiry": expiry, "last_seen": lastSeen})
+	if res.Error != nil {
+		log.Println("Failed to refresh session:", res.Error)
+		return false
+	}
+	return res.RowsAffected > 0
+}
+
+func (ss *sqliteStore) ListByUser(userID uint) []Session {
+	var rows []models.Session
+	err := ss.db.Where("user_id = ? AND expiry > ?", userID, time.Now()).Order("last_seen desc").Find(&rows).Error
+	if err != nil {
+		log.Println("Failed to load sessions of user", userID, ":", err)
+		return nil
+	}
+	sessions := make([]Session, len(rows))
+	for i, row := range rows {
+		sessions[i] = fromRow(row)
+	}
+	return sessions
+}
+
+func (ss *sqliteStore) Delete(sessionID string) {
+	if err := ss.db.Delete(&models.Session{}, "id = ?", hashSessionID(sessionID)).Error; err != nil {
+		log.Println("Failed to delete session:", err)
+	}
+}
+
+func (ss *sqliteStore) Revoke(userID uint, publicID string) bool {
+	var ids []string
+	if err := ss.db.Model(&models.Session{}).Where("user_id = ?", userID).Pluck("id", &ids).Error; err != nil {
+		log.Println("Failed to load sessions of user", userID, ":", err)
+		return false
+	}
+	for _, id := range ids {
+		if (Session{hash: id}).PublicID() != publicID {
+			continue
+		}
+		res := ss.db.Delete(&models.Session{}, "id = ?", id)
+		if res.Error != nil {
+			log.Println("Failed to revoke session of user", userID, ":", res.Error)
+			return false
+		}
+		return res.RowsAffected > 0
+	}
+	return false
+}
+
+func (ss *sqliteStore) DeleteByUser(userID uint) int {
+	res := ss.db.Delete(&models.Session{}, "user_id = ?", userID)
+	if res.Error != nil {
+		log.Println("Failed to delete sessions of user", userID, ":", res.Error)
+		return 0
+	}
+	return int(res.RowsAffected)
+}
+
+func (ss *sqliteStore) DeleteExpired(now time.Time) int {
+	res := ss.db.Delete(&models.Session{}, "expiry <= ?", now)
+	if res.Error != nil {
+		log.Println("Failed to delete expired sessions:", res.Error)
+		return 0
+	}
+	return int(res.RowsAffected)
+}
+
+func (ss *sqliteStore) Count() int {
+	var count int64
+	ss.db.Model(&models.Session{}).Where("expiry > ?", time.Now()).Count(&count)
+	return int(count)
+}
+
+// fromRow converts a database row to a Session. The row only holds the hash of the
+// session ID, so SessionID is left empty.
+func fromRow(row models.Session) Session {
+	return Session{
+		UserID:    row.UserID,
+		hash:      row.ID,
+		Expiry:    row.Expiry,
+		UserAgent: row.UserAgent,
+		IP:        row.IP,
+		CreatedAt: row.CreatedAt,
+		LastSeen:  row.LastSeen,
+	}
+}
@@ -0,0 +1,168 @@
+// File contains the SessionStore interface the session manager keeps its sessions in,
+// and the in-memory store. Sessions in memory are lost when the server restarts, the
+// SQLite store in sqliteStore.go keeps them across restarts.
+
+package sessionmanager
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// SessionStore keeps sessions for the session manager. Stores must be safe for
+// concurrent use. Expired sessions are never returned, and are removed by DeleteExpired.
+type SessionStore interface {
+	Save(session Session) error                              // adds the session, or replaces the one with the same SessionID
+	Get(sessionID string) (Session, bool)                    // returns the session if it exists and has not expired
+	Touch(sessionID string, expiry, lastSeen time.Time) bool // updates the times of an existing session, false if there is none
+	ListByUser(userID uint) []Session                        // returns the user's sessions that have not expired, last seen first
+	Delete(sessionID string)                                 // removes the session
+	Revoke(userID uint, publicID string) bool                // removes the user's session with the given PublicID
+	DeleteByUser(userID uint) (removed int)                  // removes every session of the user
+	DeleteExpired(now time.Time) (removed int)               // removes every session that expired by now
+	Count() int                                              // returns the number of sessions that have not expired
+}
+
+// memoryStore keeps sessions in process maps.
+type memoryStore struct {
+	mu       sync.RWMutex                 // mutex so we dont get any race conditions
+	sessions map[string]Session           // maps session ID to session data
+	byUser   map[uint]map[string]struct{} // IDs of each user's sessions
+}
+
+// NewMemoryStore creates an empty in-memory session store.
+func NewMemoryStore() SessionStore {
+	return &memoryStore{
+		sessions: make(map[string]Session),
+		byUser:   make(map[uint]map[string]struct{}),
+	}
+}
+
+func (ms *memoryStore) Save(session Session) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.sessions[session.SessionID] = session
+	ids, ok := ms.byUser[session.UserID]
+	if !ok {
+		ids = make(map[string]struct{})
+		ms.byUser[session.UserID] = ids
+	}
+	ids[session.SessionID] = struct{}{}
+	return nil
+}
+
+func (ms *memoryStore) Get(sessionID string) (Session, bool) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	session, ok := ms.sessions[sessionID]
+	if !ok || session.IsExpired() {
+		return Session{}, false
+	}
+	return session, true
+}
+
+func (ms *memoryStore) Touch(sessionID string, expiry, lastSeen time.Time) bool {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	session, ok := ms.sessions[sessionID]
+	if !ok {
+		return false
+	}
+	session.Expiry = expiry
+	session.LastSeen = lastSeen
+	ms.sessions[sessionID] = session
+	return true
+}
+
+func (ms *memoryStore) ListByUser(userID uint) []Session {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var sessions []Session
+	for id := range ms.byUser[userID] {
+		if session := ms.sessions[id]; !session.IsExpired() {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeen.After(sessions[j].LastSeen)
+	})
+	return sessions
+}
+
+func (ms *memoryStore) Delete(sessionID string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.remove(sessionID)
+}
+
+func (ms *memoryStore) Revoke(userID uint, publicID string) bool {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for id := range ms.byUser[userID] {
+		if ms.sessions[id].PublicID() == publicID {
+			ms.remove(id)
+			return true
+		}
+	}
+	return false
+}
+
+func (ms *memoryStore) DeleteByUser(userID uint) int {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	removed := 0
+	for id := range ms.byUser[userID] {
+		ms.remove(id)
+		removed++
+	}
+	return removed
+}
+
+func (ms *memoryStore) DeleteExpired(now time.Time) int {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	removed := 0
+	for id, session := range ms.sessions {
+		if now.After(session.Expiry) {
+			ms.remove(id)
+			removed++
+		}
+	}
+	return removed
+}
+
+func (ms *memoryStore) Count() int {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	count := 0
+	for _, session := range ms.sessions {
+		if !session.IsExpired() {
+			count++
+		}
+	}
+	return count
+}
+
+// remove deletes the session from both maps. Must be called with ms.mu held.
+func (ms *memoryStore) remove(sessionID string) {
+	session, ok := ms.sessions[sessionID]
+	if !ok {
+		return
+	}
+	delete(ms.sessions, sessionID)
+	ids := ms.byUser[session.UserID]
+	delete(ids, sessionID)
+	if len(ids) == 0 {
+		delete(ms.byUser, session.UserID)
+	}
+}
@@ -0,0 +1,747 @@
+// Package tournament runs elimination blackjack tournaments.
+// Entrants pay a buy-in from their account balance and play with separate tournament
+// chips across several blackjack tables. After a fixed number of hands per round the
+// lowest stacks are eliminated, the remaining players are rebalanced across tables,
+// and once the field is down to the paid places the prize pool is paid out.
+package tournament
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cardgames/backend/libraries/blackjack"
+	gameinstancemanager "cardgames/backend/libraries/gameInstanceManager"
+	"cardgames/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// checkInterval is how often the manager looks for tournaments that are due to start.
+const checkInterval = 10 * time.Second
+
+// Errors returned by the Manager.
+var (
+	ErrNotFound           = errors.New("tournament not found")
+	ErrRegistrationClosed = errors.New("registration is closed")
+	ErrAlreadyRegistered  = errors.New("already registered")
+	ErrTournamentFull     = errors.New("tournament is full")
+	ErrInsufficientFunds  = errors.New("insufficient balance for buy-in")
+	ErrInvalidSettings    = errors.New("invalid tournament settings")
+)
+
+// Settings are the parameters used to create a tournament.
+type Settings struct {
+	Name              string    `json:"name"`
+	BuyIn             int       `json:"buyIn"`
+	StartingChips     int       `json:"startingChips"`
+	HandsPerRound     int       `json:"handsPerRound"`
+	EliminatePerRound int       `json:"eliminatePerRound"`
+	TableSize         int       `json:"tableSize"`
+	MaxPlayers        int       `json:"maxPlayers"`
+	Payouts           []int     `json:"payouts"` // prize percentages by finishing place
+	StartsAt          time.Time `json:"startsAt"`
+}
+
+// Standing is one row of a tournament's standings.
+type Standing struct {
+	AccountID      uint   `json:"accountId"`
+	Username       string `json:"username"`
+	Chips          int    `json:"chips"`
+	TableID        string `json:"tableId,omitempty"`
+	Eliminated     bool   `json:"eliminated"`
+	EliminatedAt   int    `json:"eliminatedAt,omitempty"`
+	FinishPosition int    `json:"finishPosition,omitempty"`
+	Prize          int    `json:"prize,omitempty"`
+}
+
+// View is a tournament together with its current standings.
+type View struct {
+	Tournament models.Tournament `json:"tournament"`
+	Standings  []Standing        `json:"standings"`
+}
+
+// table is one blackjack table used by a running tournament.
+type table struct {
+	ID      string
+	Game    *blackjack.BlackJackInstance
+	hands   int  // hands played in the current round
+	waiting bool // finished the current round, paused until the others catch up
+}
+
+// running holds the in-memory state of a tournament that is being played.
+type running struct {
+	model   *models.Tournament
+	entries map[uint]*models.TournamentEntry // keyed by account ID
+	tables  []*table
+	seats   map[uint]string // account ID -> table ID
+}
+
+// Manager creates tournaments, handles registration and drives running tournaments.
+type Manager struct {
+	mu      sync.Mutex
+	DB      *gorm.DB
+	GIM     *gameinstancemanager.GameInstanceManager
+	running map[uint]*running
+	stop    chan struct{}
+}
+
+// NewManager creates a tournament manager and starts the background routine
+// that starts tournaments when their start time arrives.
+func NewManager(db *gorm.DB, gim *gameinstancemanager.GameInstanceManager) *Manager {
+	m := &Manager{
+		DB:      db,
+		GIM:     gim,
+		running: make(map[uint]*running),
+		stop:    make(chan struct{}),
+	}
+	m.settleInterrupted()
+	go m.loop()
+	return m
+}
+
+// Stop halts the background routine.
+func (m *Manager) Stop() {
+	close(m.stop)
+}
+
+func (m *Manager) loop() {
+	ticker := time.NewTicker(checkInterval)
+	for {
+		select {
+		case <-ticker.C:
+			m.startDue()
+		case <-m.stop:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+// Create validates the settings and stores a new tournament open for registration.
+func (m *Manager) Create(creatorID uint, st Settings) (*models.Tournament, error) {
+	if st.TableSize == 0 {
+		st.TableSize = blackjack.MaxPlayersPerInstance
+	}
+	if st.HandsPerRound == 0 {
+		st.HandsPerRound = 10
+	}
+	if st.EliminatePerRound == 0 {
+		st.EliminatePerRound = 1
+	}
+	if st.StartingChips == 0 {
+		st.StartingChips = 1000
+	}
+	if len(st.Payouts) == 0 {
+		st.Payouts = []int{50, 30, 20}
+	}
+	if st.MaxPlayers == 0 {
+		st.MaxPlayers = st.TableSize * 4
+	}
+
+	total := 0
+	for _, pct := range st.Payouts {
+		if pct <= 0 {
+			return nil, ErrInvalidSettings
+		}
+		total += pct
+	}
+	if total != 100 || st.BuyIn < 0 || st.StartingChips < 0 || st.HandsPerRound < 0 ||
+		st.EliminatePerRound < 0 || st.TableSize < 2 || st.TableSize > blackjack.MaxPlayersPerInstance ||
+		st.MaxPlayers < 2 || st.StartsAt.IsZero() {
+		return nil, ErrInvalidSettings
+	}
+
+	payouts := make([]string, len(st.Payouts))
+	for i, pct := range st.Payouts {
+		payouts[i] = strconv.Itoa(pct)
+	}
+
+	t := &models.Tournament{
+		Name:              st.Name,
+		CreatorID:         creatorID,
+		BuyIn:             st.BuyIn,
+		StartingChips:     st.StartingChips,
+		HandsPerRound:     st.HandsPerRound,
+		EliminatePerRound: st.EliminatePerRound,
+		TableSize:         st.TableSize,
+		MaxPlayers:        st.MaxPlayers,
+		Payouts:           strings.Join(payouts, ","),
+		StartsAt:          st.StartsAt,
+		Status:            models.TournamentRegistering,
+	}
+	if err := m.DB.Create(t).Error; err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Join registers a player, taking the buy-in from their account balance and
+// adding it to the prize pool.
+func (m *Manager) Join(tournamentID uint, accountID uint) (*models.TournamentEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entry models.TournamentEntry
+	err := m.DB.Transaction(func(tx *gorm.DB) error {
+		var t models.Tournament
+		if err := tx.First(&t, tournamentID).Error; err != nil {
+			return ErrNotFound
+		}
+		if t.Status != models.TournamentRegistering {
+			return ErrRegistrationClosed
+		}
+
+		var count int64
+		tx.Model(&models.TournamentEntry{}).Where("tournament_id = ? AND account_id = ?", t.ID, accountID).Count(&count)
+		if count > 0 {
+			return ErrAlreadyRegistered
+		}
+		tx.Model(&models.TournamentEntry{}).Where("tournament_id = ?", t.ID).Count(&count)
+		if int(count) >= t.MaxPlayers {
+			return ErrTournamentFull
+		}
+
+		res := tx.Model(&models.Account{}).
+			Where("id = ? AND balance >= ?", accountID, t.BuyIn).
+			Update("balance", gorm.Expr("balance - ?", t.BuyIn))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrInsufficientFunds
+		}
+
+		entry = models.TournamentEntry{
+			TournamentID: t.ID,
+			AccountID:    accountID,
+			Chips:        t.StartingChips,
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+		return tx.Model(&t).Update("prize_pool", gorm.Expr("prize_pool + ?", t.BuyIn)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// List returns every tournament, newest first.
+func (m *Manager) List() ([]models.Tournament, error) {
+	var ts []models.Tournament
+	err := m.DB.Order("starts_at desc").Find(&ts).Error
+	return ts, err
+}
+
+// Standings returns the tournament and its standings. Players still in the
+// tournament are ordered by chip count, followed by eliminated players by finish.
+func (m *Manager) Standings(tournamentID uint) (*View, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var t models.Tournament
+	var entries []models.TournamentEntry
+	seats := map[uint]string{}
+
+	if r, ok := m.running[tournamentID]; ok {
+		t = *r.model
+		for _, e := range r.entries {
+			entries = append(entries, *e)
+		}
+		seats = r.seats
+	} else {
+		if err := m.DB.First(&t, tournamentID).Error; err != nil {
+			return nil, ErrNotFound
+		}
+		m.DB.Where("tournament_id = ?", t.ID).Find(&entries)
+	}
+
+	ids := make([]uint, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.AccountID)
+	}
+	names := map[uint]string{}
+	if len(ids) > 0 {
+		var accounts []models.Account
+		m.DB.Where("id IN ?", ids).Find(&accounts)
+		for _, a := range accounts {
+			names[a.ID] = a.Username
+		}
+	}
+
+	standings := make([]Standing, 0, len(entries))
+	for _, e := range entries {
+		s := Standing{
+			AccountID:      e.AccountID,
+			Username:       names[e.AccountID],
+			Chips:          e.Chips,
+			Eliminated:     e.Eliminated,
+			EliminatedAt:   e.EliminatedAt,
+			FinishPosition: e.FinishPosition,
+			Prize:          e.Prize,
+		}
+		if !e.Eliminated {
+			s.TableID = seats[e.AccountID]
+		}
+		standings = append(standings, s)
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.FinishPosition != 0 || b.FinishPosition != 0 {
+			if a.FinishPosition == 0 {
+				return true
+			}
+			if b.FinishPosition == 0 {
+				return false
+			}
+			return a.FinishPosition < b.FinishPosition
+		}
+		return a.Chips > b.Chips
+	})
+
+	return &View{Tournament: t, Standings: standings}, nil
+}
+
+// startDue starts every tournament whose start time has passed.
+func (m *Manager) startDue() {
+	var due []uint
+	m.DB.Model(&models.Tournament{}).
+		Where("status = ? AND starts_at <= ?", models.TournamentRegistering, time.Now()).
+		Pluck("id", &due)
+	for _, id := range due {
+		m.start(id)
+	}
+}
+
+// start seats the entrants at tables and begins the first round. A tournament
+// with fewer than two entrants is cancelled and the buy-ins refunded.
+func (m *Manager) start(id uint) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Load the tournament under the lock so a join that just went through is not
+	// overwritten when it is saved
+	t := &models.Tournament{}
+	if err := m.DB.First(t, id).Error; err != nil || t.Status != models.TournamentRegistering {
+		return
+	}
+
+	var entries []models.TournamentEntry
+	m.DB.Where("tournament_id = ?", t.ID).Find(&entries)
+
+	if len(entries) < 2 {
+		for _, e := range entries {
+			m.credit(e.AccountID, t.BuyIn)
+		}
+		t.Status = models.TournamentCancelled
+		m.DB.Save(t)
+		log.Println("Tournament", t.ID, "cancelled, not enough players")
+		return
+	}
+
+	r := &running{
+		model:   t,
+		entries: make(map[uint]*models.TournamentEntry),
+		seats:   make(map[uint]string),
+	}
+	order := make([]uint, 0, len(entries))
+	for i := range entries {
+		r.entries[entries[i].AccountID] = &entries[i]
+		order = append(order, entries[i].AccountID)
+	}
+
+	numTables := (len(order) + t.TableSize - 1) / t.TableSize
+	for i := 0; i < numTables; i++ {
+		m.openTable(r)
+	}
+	for i, id := range order {
+		r.seats[id] = r.tables[i%numTables].ID
+	}
+
+	t.Status = models.TournamentRunning
+	t.Round = 1
+	m.DB.Save(t)
+	m.running[t.ID] = r
+	log.Println("Tournament", t.ID, "started with", len(order), "players at", numTables, "tables")
+}
+
+// openTable creates a new tournament table and adds it to r.
+func (m *Manager) openTable(r *running) *table {
+	tb := &table{}
+	game := blackjack.NewBlackJackInstanceWithOptions(m.DB, blackjack.Options{
+		Wallet:        &chipWallet{m: m, r: r},
+		CanJoin:       func(playerID uint) bool { return m.canJoin(r, tb, playerID) },
+		OnRoundEnd:    func(b *blackjack.BlackJackInstance) { m.handFinished(r, tb) },
+		KeepWhenEmpty: true,
+	})
+	tb.ID = m.GIM.AddPrivateGame(blackjack.GameName, string(blackjack.StandardMode), game)
+	tb.Game = game
+	r.tables = append(r.tables, tb)
+	go func() {
+		<-game.Done()
+		m.tableClosed(r, tb)
+	}()
+	return tb
+}
+
+// closeTables closes tables the tournament no longer uses. It waits for the requests
+// already sent to each table, such as the kicks telling players where they finished
+// or where to move, to run first, so it does so in the background: it may be called
+// from a table's own game loop.
+func (m *Manager) closeTables(tables []*table) {
+	go func() {
+		for _, tb := range tables {
+			// Requests run in order, so once Seats answers the kicks before it have run
+			tb.Game.Seats()
+			m.GIM.RemoveGame(tb.ID)
+		}
+	}()
+}
+
+// tableClosed is called once a tournament table has closed. Tables the tournament
+// closed itself are already gone from r. Any other table was closed from outside,
+// e.g. force-closed by an admin, and its players are reseated at the other tables,
+// opening new ones if they would not fit.
+func (m *Manager) tableClosed(r *running, tb *table) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if r.model.Status != models.TournamentRunning {
+		return
+	}
+	index := -1
+	for i, other := range r.tables {
+		if other == tb {
+			index = i
+		}
+	}
+	if index < 0 {
+		return
+	}
+	r.tables = append(r.tables[:index], r.tables[index+1:]...)
+
+	needed := (len(r.seats) + r.model.TableSize - 1) / r.model.TableSize
+	for len(r.tables) < needed {
+		m.openTable(r)
+	}
+	for playerID, tableID := range r.seats {
+		if tableID != tb.ID {
+			continue
+		}
+		target := r.tables[0]
+		for _, other := range r.tables {
+			if m.seated(r, other) < m.seated(r, target) {
+				target = other
+			}
+		}
+		r.seats[playerID] = target.ID
+	}
+	log.Println("Tournament", r.model.ID, "table", tb.ID, "was closed, its players were reseated")
+
+	// The closed table may have been the one the round was waiting for
+	for _, other := range r.tables {
+		if !other.waiting {
+			return
+		}
+	}
+	m.endRound(r)
+}
+
+// seated returns the number of players assigned to tb.
+func (m *Manager) seated(r *running, tb *table) int {
+	n := 0
+	for _, tableID := range r.seats {
+		if tableID == tb.ID {
+			n++
+		}
+	}
+	return n
+}
+
+// canJoin only admits players who are still in the tournament and assigned to tb.
+func (m *Manager) canJoin(r *running, tb *table, playerID uint) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := r.entries[playerID]
+	if !ok || e.Eliminated {
+		return false
+	}
+	return r.seats[playerID] == tb.ID
+}
+
+// handFinished is called by a table's game loop after every hand. Once every table
+// has played the hands for this round, the round is closed.
+func (m *Manager) handFinished(r *running, tb *table) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if tb.waiting || r.model.Status != models.TournamentRunning {
+		return
+	}
+
+	tb.hands++
+	if tb.hands < r.model.HandsPerRound {
+		return
+	}
+	tb.waiting = true
+	tb.Game.SetPaused(true)
+
+	for _, other := range r.tables {
+		if !other.waiting {
+			return
+		}
+	}
+	m.endRound(r)
+}
+
+// endRound eliminates the lowest stacks, then either finishes the tournament or
+// rebalances the tables and starts the next round. Must be called with m.mu held.
+func (m *Manager) endRound(r *running) {
+	t := r.model
+	paid := len(strings.Split(t.Payouts, ","))
+
+	alive := r.alive()
+	// Lowest stacks first
+	sort.SliceStable(alive, func(i, j int) bool { return alive[i].Chips < alive[j].Chips })
+
+	busted := 0
+	for _, e := range alive {
+		if e.Chips == 0 {
+			busted++
+		}
+	}
+	eliminate := t.EliminatePerRound
+	if eliminate > len(alive)-paid {
+		eliminate = len(alive) - paid
+	}
+	if eliminate < busted {
+		eliminate = busted
+	}
+	if eliminate > len(alive)-1 {
+		eliminate = len(alive) - 1
+	}
+
+	for i := 0; i < eliminate; i++ {
+		e := alive[i]
+		e.Eliminated = true
+		e.EliminatedAt = t.Round
+		e.FinishPosition = len(alive) - i
+		m.DB.Save(e)
+		m.kick(r, e.AccountID, fmt.Sprintf("You have been eliminated in place %d", e.FinishPosition), "")
+		delete(r.seats, e.AccountID)
+	}
+
+	remaining := len(alive) - eliminate
+	if remaining <= paid || remaining <= 1 {
+		m.finish(r)
+		return
+	}
+
+	t.Round++
+	m.DB.Save(t)
+	m.rebalance(r)
+
+	for _, tb := range r.tables {
+		tb.hands = 0
+		tb.waiting = false
+		tb.Game.SetPaused(false)
+	}
+}
+
+// rebalance closes tables that are no longer needed and evens out the number of
+// players per table, moving players from the fullest table to the emptiest one.
+func (m *Manager) rebalance(r *running) {
+	counts := func() map[string]int {
+		c := make(map[string]int)
+		for _, tb := range r.tables {
+			c[tb.ID] = 0
+		}
+		for _, id := range r.seats {
+			c[id]++
+		}
+		return c
+	}
+
+	needed := (len(r.seats) + r.model.TableSize - 1) / r.model.TableSize
+	for len(r.tables) > needed {
+		// Break up the table with the fewest players
+		c := counts()
+		sort.SliceStable(r.tables, func(i, j int) bool { return c[r.tables[i].ID] > c[r.tables[j].ID] })
+		closing := r.tables[len(r.tables)-1]
+		r.tables = r.tables[:len(r.tables)-1]
+
+		for playerID, tableID := range r.seats {
+			if tableID != closing.ID {
+				continue
+			}
+			c = counts()
+			target := r.tables[len(r.tables)-1]
+			for _, tb := range r.tables {
+				if c[tb.ID] < c[target.ID] {
+					target = tb
+				}
+			}
+			m.move(r, playerID, target)
+		}
+		m.closeTables([]*table{closing})
+	}
+
+	for {
+		c := counts()
+		largest, smallest := r.tables[0], r.tables[0]
+		for _, tb := range r.tables {
+			if c[tb.ID] > c[largest.ID] {
+				largest = tb
+			}
+			if c[tb.ID] < c[smallest.ID] {
+				smallest = tb
+			}
+		}
+		if c[largest.ID]-c[smallest.ID] <= 1 {
+			return
+		}
+		for playerID, tableID := range r.seats {
+			if tableID == largest.ID {
+				m.move(r, playerID, smallest)
+				break
+			}
+		}
+	}
+}
+
+// move reassigns a player to another table and tells their client to reconnect there.
+func (m *Manager) move(r *running, playerID uint, to *table) {
+	m.kick(r, playerID, "Moved to table "+to.ID, to.ID)
+	r.seats[playerID] = to.ID
+}
+
+// kick removes a player from the table they are currently assigned to.
+func (m *Manager) kick(r *running, playerID uint, notice string, moveTo string) {
+	for _, tb := range r.tables {
+		if tb.ID == r.seats[playerID] {
+			tb.Game.Kick(playerID, notice, moveTo)
+			return
+		}
+	}
+}
+
+// finish ranks the remaining players by stack, pays the prize pool and closes
+// the tournament tables. Must be called with m.mu held.
+func (m *Manager) finish(r *running) {
+	t := r.model
+	alive := r.alive()
+	sort.SliceStable(alive, func(i, j int) bool { return alive[i].Chips > alive[j].Chips })
+	for i, e := range alive {
+		e.FinishPosition = i + 1
+	}
+
+	m.payPrizes(t, r.entries)
+
+	for _, e := range alive {
+		m.kick(r, e.AccountID, fmt.Sprintf("Tournament finished, you placed %d", e.FinishPosition), "")
+	}
+	m.closeTables(r.tables)
+
+	t.Status = models.TournamentFinished
+	m.DB.Save(t)
+	delete(m.running, t.ID)
+	log.Println("Tournament", t.ID, "finished")
+}
+
+// payPrizes splits the prize pool by finishing position and credits the winners.
+// When fewer players finish than there are paid places, the pool is split over the
+// places that were reached in the same proportions. Any amount lost to rounding goes
+// to the winner.
+func (m *Manager) payPrizes(t *models.Tournament, entries map[uint]*models.TournamentEntry) {
+	finishers := 0
+	for _, e := range entries {
+		if e.FinishPosition >= 1 {
+			finishers++
+		}
+	}
+	payouts := strings.Split(t.Payouts, ",")
+	if len(payouts) > finishers {
+		payouts = payouts[:finishers]
+	}
+	if len(payouts) == 0 {
+		return
+	}
+
+	pcts := make([]int, len(payouts))
+	sum := 0
+	for i, p := range payouts {
+		pcts[i], _ = strconv.Atoi(p)
+		sum += pcts[i]
+	}
+	prizes := make([]int, len(payouts))
+	total := 0
+	for i, pct := range pcts {
+		prizes[i] = t.PrizePool * pct / sum
+		total += prizes[i]
+	}
+	prizes[0] += t.PrizePool - total
+
+	for _, e := range entries {
+		if e.FinishPosition >= 1 && e.FinishPosition <= len(prizes) {
+			e.Prize = prizes[e.FinishPosition-1]
+			m.credit(e.AccountID, e.Prize)
+		}
+		m.DB.Save(e)
+	}
+}
+
+// credit adds amount to an account balance directly in the database.
+func (m *Manager) credit(accountID uint, amount int) {
+	if amount == 0 {
+		return
+	}
+	m.DB.Model(&models.Account{}).Where("id = ?", accountID).
+		Update("balance", gorm.Expr("balance + ?", amount))
+}
+
+// settleInterrupted finishes tournaments that were still running when the server
+// last stopped. The tables are gone, so the players are ranked by their saved stacks.
+func (m *Manager) settleInterrupted() {
+	var interrupted []models.Tournament
+	m.DB.Where("status = ?", models.TournamentRunning).Find(&interrupted)
+	for i := range interrupted {
+		t := &interrupted[i]
+		var entries []models.TournamentEntry
+		m.DB.Where("tournament_id = ?", t.ID).Find(&entries)
+
+		r := &running{model: t, entries: make(map[uint]*models.TournamentEntry)}
+		for j := range entries {
+			r.entries[entries[j].AccountID] = &entries[j]
+		}
+		alive := r.alive()
+		sort.SliceStable(alive, func(a, b int) bool { return alive[a].Chips > alive[b].Chips })
+		for j, e := range alive {
+			e.FinishPosition = j + 1
+		}
+		m.payPrizes(t, r.entries)
+
+		t.Status = models.TournamentFinished
+		m.DB.Save(t)
+		log.Println("Tournament", t.ID, "settled after restart")
+	}
+}
+
+// alive returns the entries that have not been eliminated, in registration order.
+func (r *running) alive() []*models.TournamentEntry {
+	alive := make([]*models.TournamentEntry, 0, len(r.entries))
+	for _, e := range r.entries {
+		if !e.Eliminated {
+			alive = append(alive, e)
+		}
+	}
+	sort.Slice(alive, func(i, j int) bool { return alive[i].ID < alive[j].ID })
+	return alive
+}
@@ -0,0 +1,51 @@
+// Package tournament runs elimination blackjack tournaments.
+// This file contains the wallet that lets tournament tables play with tournament chips
+// instead of account balances.
+package tournament
+
+import (
+	"cardgames/backend/libraries/blackjack"
+)
+
+// chipWallet implements blackjack.Wallet on top of the tournament entries.
+// Chip changes are saved to the entry so the standings always reflect the tables.
+// Tournament hands are not recorded as wagers.
+type chipWallet struct {
+	m *Manager
+	r *running
+}
+
+func (w *chipWallet) Balance(p *blackjack.Player) int {
+	w.m.mu.Lock()
+	defer w.m.mu.Unlock()
+
+	if e, ok := w.r.entries[p.ID]; ok {
+		return e.Chips
+	}
+	return 0
+}
+
+func (w *chipWallet) Debit(p *blackjack.Player, amount int) bool {
+	return w.adjust(p, -amount)
+}
+
+func (w *chipWallet) Credit(p *blackjack.Player, amount int) {
+	w.adjust(p, amount)
+}
+
+func (w *chipWallet) RecordWager(p *blackjack.Player) {}
+
+// adjust changes the player's chips by amount. It returns false, changing nothing,
+// if the player has no entry or would go below zero.
+func (w *chipWallet) adjust(p *blackjack.Player, amount int) bool {
+	w.m.mu.Lock()
+	defer w.m.mu.Unlock()
+
+	e, ok := w.r.entries[p.ID]
+	if !ok || e.Chips+amount < 0 {
+		return false
+	}
+	e.Chips += amount
+	w.m.DB.Save(e)
+	return true
+}
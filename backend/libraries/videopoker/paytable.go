@@ -0,0 +1,150 @@
+// Package videopoker runs single player Jacks or Better video poker.
+// This file contains the pay tables and the paylines a final hand is paid on.
+package videopoker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	carddeck "cardgames/backend/libraries/cardDeck"
+)
+
+// Payline is a hand that pays in Jacks or Better.
+type Payline string
+
+const (
+	RoyalFlush    Payline = "Royal Flush"
+	StraightFlush Payline = "Straight Flush"
+	FourOfAKind   Payline = "Four of a Kind"
+	FullHouse     Payline = "Full House"
+	Flush         Payline = "Flush"
+	Straight      Payline = "Straight"
+	ThreeOfAKind  Payline = "Three of a Kind"
+	TwoPair       Payline = "Two Pair"
+	JacksOrBetter Payline = "Jacks or Better"
+)
+
+// paylines lists every payline from best to worst.
+var paylines = []Payline{RoyalFlush, StraightFlush, FourOfAKind, FullHouse, Flush, Straight, ThreeOfAKind, TwoPair, JacksOrBetter}
+
+// PayTable says how much each payline returns per chip (coin) bet, stake included.
+// A pay of 1 gives the bet back. Like a machine's max-coin column, MaxCoinPays replaces
+// the pay of the paylines it lists on bets of MaxCoins chips or more.
+type PayTable struct {
+	Name        string          `json:"name"`
+	Pays        map[Payline]int `json:"pays"`
+	MaxCoins    int             `json:"maxCoins,omitempty"`    // bet that earns MaxCoinPays, 0 if there are none
+	MaxCoinPays map[Payline]int `json:"maxCoinPays,omitempty"` // per chip pays on a max-coin bet
+}
+
+// Pay returns how much the payline returns per chip on a bet of bet chips.
+func (pt PayTable) Pay(line Payline, bet int) int {
+	if pay, ok := pt.MaxCoinPays[line]; ok && pt.MaxCoins > 0 && bet >= pt.MaxCoins {
+		return pay
+	}
+	return pt.Pays[line]
+}
+
+// validate checks that every payline has a pay, none are negative, and max-coin pays
+// are only given with a max-coin bet.
+func (pt PayTable) validate() error {
+	if pt.Name == "" {
+		return fmt.Errorf("pay table has no name")
+	}
+	for _, line := range paylines {
+		pay, ok := pt.Pays[line]
+		if !ok || pay < 0 {
+			return fmt.Errorf("pay table %q: missing or negative pay for %s", pt.Name, line)
+		}
+	}
+	if len(pt.MaxCoinPays) > 0 && pt.MaxCoins < 1 {
+		return fmt.Errorf("pay table %q: max-coin pays without maxCoins", pt.Name)
+	}
+	for line, pay := range pt.MaxCoinPays {
+		if _, ok := pt.Pays[line]; !ok || pay < 0 {
+			return fmt.Errorf("pay table %q: unknown payline or negative max-coin pay for %s", pt.Name, line)
+		}
+	}
+	return nil
+}
+
+// DefaultPayTable is the pay table used when a hand does not name one.
+const DefaultPayTable = "9/6"
+
+// defaultPayTables returns the built-in pay tables, named after what the full house and
+// the flush pay. The royal flush pays 250 per chip, and 800 per chip on bets of five
+// chips or more, like a five coin machine.
+func defaultPayTables() map[string]PayTable {
+	table := func(name string, fullHouse, flush int) PayTable {
+		return PayTable{Name: name, MaxCoins: 5, MaxCoinPays: map[Payline]int{RoyalFlush: 800}, Pays: map[Payline]int{
+			RoyalFlush:    250,
+			StraightFlush: 50,
+			FourOfAKind:   25,
+			FullHouse:     fullHouse,
+			Flush:         flush,
+			Straight:      4,
+			ThreeOfAKind:  3,
+			TwoPair:       2,
+			JacksOrBetter: 1,
+		}}
+	}
+	tables := make(map[string]PayTable)
+	for _, pt := range []PayTable{table("9/6", 9, 6), table("8/5", 8, 5), table("7/5", 7, 5)} {
+		tables[pt.Name] = pt
+	}
+	return tables
+}
+
+// loadPayTables reads extra pay tables from a JSON file holding a list of PayTable,
+// and adds them to tables. A table with the name of a built-in one replaces it.
+func loadPayTables(path string, tables map[string]PayTable) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var loaded []PayTable
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return err
+	}
+	for _, pt := range loaded {
+		if err := pt.validate(); err != nil {
+			return err
+		}
+	}
+	for _, pt := range loaded {
+		tables[pt.Name] = pt
+	}
+	return nil
+}
+
+// classify returns the payline a 5 card hand pays on, or "" if it does not pay.
+func classify(cards []carddeck.Card) Payline {
+	hand := carddeck.EvaluatePoker(cards)
+	top := int(hand.Score>>16) & 0xF // rank of the highest straight card or the pair, 14 for an ace
+
+	switch hand.Category {
+	case carddeck.StraightFlush:
+		if top == 14 {
+			return RoyalFlush
+		}
+		return StraightFlush
+	case carddeck.FourOfAKind:
+		return FourOfAKind
+	case carddeck.FullHouse:
+		return FullHouse
+	case carddeck.Flush:
+		return Flush
+	case carddeck.Straight:
+		return Straight
+	case carddeck.ThreeOfAKind:
+		return ThreeOfAKind
+	case carddeck.TwoPair:
+		return TwoPair
+	case carddeck.OnePair:
+		if top >= 11 {
+			return JacksOrBetter
+		}
+	}
+	return ""
+}
@@ -0,0 +1,276 @@
+// Package videopoker runs single player Jacks or Better video poker.
+// A hand is two requests: the deal takes the bet from the account balance and deals
+// five cards, then the draw replaces the cards the player did not hold and pays the
+// final hand from the pay table. Every hand is recorded as a wager.
+//
+// Open hands are kept in memory, one per player, until they are drawn. They are also
+// saved to the database, so a hand dealt before a restart can still be drawn after it.
+package videopoker
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	carddeck "cardgames/backend/libraries/cardDeck"
+	"cardgames/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// GameName is the game type video poker wagers are recorded with.
+const GameName = "video_poker"
+
+// Bet limits for a single hand.
+const (
+	MinBet = 1
+	MaxBet = 500
+)
+
+// handSize is the number of cards in a video poker hand.
+const handSize = 5
+
+// Errors returned by the Manager.
+var (
+	ErrHandInProgress    = errors.New("finish the current hand first")
+	ErrNoHand            = errors.New("no hand in progress")
+	ErrUnknownPayTable   = errors.New("unknown pay table")
+	ErrInvalidBet        = errors.New("invalid bet")
+	ErrInvalidHold       = errors.New("invalid hold")
+	ErrInsufficientFunds = errors.New("insufficient balance")
+)
+
+// Hand is a dealt hand waiting for the draw.
+type Hand struct {
+	PayTable string          `json:"payTable"`
+	Bet      int             `json:"bet"`
+	Cards    []carddeck.Card `json:"cards"`
+	DealtAt  time.Time       `json:"dealtAt"`
+	deck     carddeck.Deck   // the rest of the deck, the draw comes from here
+}
+
+// Result is the outcome of a drawn hand.
+type Result struct {
+	Cards   []carddeck.Card `json:"cards"`
+	Held    []int           `json:"held"`
+	Payline Payline         `json:"payline,omitempty"` // empty when the hand does not pay
+	Pay     int             `json:"pay"`               // chips returned per chip bet
+	Payout  int             `json:"payout"`            // chips returned, stake included
+	Balance int             `json:"balance"`
+}
+
+// Manager deals and settles video poker hands.
+type Manager struct {
+	DB        *gorm.DB
+	payTables map[string]PayTable
+	hands     map[uint]*Hand // open hands by account ID
+	mu        sync.Mutex
+}
+
+// NewManager creates a video poker manager with the built-in pay tables. Extra pay tables
+// can be loaded from the JSON file named by the VIDEO_POKER_PAYTABLES environment variable.
+func NewManager(db *gorm.DB) *Manager {
+	m := &Manager{
+		DB:        db,
+		payTables: defaultPayTables(),
+		hands:     make(map[uint]*Hand),
+	}
+	if path := os.Getenv("VIDEO_POKER_PAYTABLES"); path != "" {
+		if err := loadPayTables(path, m.payTables); err != nil {
+			log.Println("Failed to load video poker pay tables:", err)
+		}
+	}
+	m.loadHands()
+	return m
+}
+
+// loadHands picks up the hands that were dealt and not drawn before the server stopped.
+// A hand on a pay table that no longer exists is refunded instead.
+func (m *Manager) loadHands() {
+	var rows []models.VideoPokerHand
+	if err := m.DB.Find(&rows).Error; err != nil {
+		log.Println("Failed to load open video poker hands:", err)
+		return
+	}
+	for _, row := range rows {
+		h := &Hand{PayTable: row.PayTable, Bet: row.Bet, DealtAt: row.DealtAt}
+		errCards := json.Unmarshal(row.Cards, &h.Cards)
+		errDeck := json.Unmarshal(row.Deck, &h.deck)
+		if _, ok := m.payTables[row.PayTable]; ok && errCards == nil && errDeck == nil && len(h.Cards) == handSize {
+			m.hands[row.AccountID] = h
+			continue
+		}
+
+		err := m.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Delete(&row).Error; err != nil {
+				return err
+			}
+			return tx.Model(&models.Account{}).
+				Where("id = ?", row.AccountID).
+				Update("balance", gorm.Expr("balance + ?", row.Bet)).Error
+		})
+		if err != nil {
+			log.Println("Failed to refund video poker hand of player", row.AccountID, ":", err)
+			continue
+		}
+		log.Println("Refunded", row.Bet, "chips for a video poker hand of player", row.AccountID, "that could not be restored")
+	}
+}
+
+// PayTables returns every pay table, sorted by name.
+func (m *Manager) PayTables() []PayTable {
+	tables := make([]PayTable, 0, len(m.payTables))
+	for _, pt := range m.payTables {
+		tables = append(tables, pt)
+	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+	return tables
+}
+
+// Current returns the player's open hand, if they have one.
+func (m *Manager) Current(playerID uint) (*Hand, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, ok := m.hands[playerID]
+	return h, ok
+}
+
+// Deal takes the bet from the player's balance and deals a new hand on the named pay
+// table, or the default one if payTable is empty.
+func (m *Manager) Deal(playerID uint, bet int, payTable string) (*Hand, error) {
+	if payTable == "" {
+		payTable = DefaultPayTable
+	}
+	if _, ok := m.payTables[payTable]; !ok {
+		return nil, ErrUnknownPayTable
+	}
+	if bet < MinBet || bet > MaxBet {
+		return nil, ErrInvalidBet
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.hands[playerID]; ok {
+		return nil, ErrHandInProgress
+	}
+
+	deck := carddeck.New()
+	deck.Shuffle()
+	h := &Hand{PayTable: payTable, Bet: bet, DealtAt: time.Now()}
+	for i := 0; i < handSize; i++ {
+		h.Cards = append(h.Cards, deck.Draw())
+	}
+	h.deck = deck
+
+	cards, err := json.Marshal(h.Cards)
+	if err != nil {
+		return nil, err
+	}
+	rest, err := json.Marshal(h.deck)
+	if err != nil {
+		return nil, err
+	}
+
+	// Take the bet and save the hand together, so a restart never loses one without the other
+	err = m.DB.Transaction(func(tx *gorm.DB) error {
+		// Only take the bet if the balance still covers it
+		res := tx.Model(&models.Account{}).
+			Where("id = ? AND balance >= ?", playerID, bet).
+			Update("balance", gorm.Expr("balance - ?", bet))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrInsufficientFunds
+		}
+		return tx.Create(&models.VideoPokerHand{
+			AccountID: playerID,
+			PayTable:  payTable,
+			Bet:       bet,
+			Cards:     cards,
+			Deck:      rest,
+			DealtAt:   h.DealtAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.hands[playerID] = h
+	return h, nil
+}
+
+// Draw replaces every card of the player's open hand except the ones at the held
+// positions (0 to 4), pays the final hand and records it as a wager.
+func (m *Manager) Draw(playerID uint, held []int) (*Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, ok := m.hands[playerID]
+	if !ok {
+		return nil, ErrNoHand
+	}
+	var keep [handSize]bool
+	for _, i := range held {
+		if i < 0 || i >= handSize || keep[i] {
+			return nil, ErrInvalidHold
+		}
+		keep[i] = true
+	}
+
+	// The open hand is only changed once the draw has been settled
+	cards := append([]carddeck.Card(nil), h.Cards...)
+	deck := h.deck
+	for i := range cards {
+		if !keep[i] {
+			cards[i] = deck.Draw()
+		}
+	}
+
+	pt := m.payTables[h.PayTable]
+	line := classify(cards)
+	result := &Result{Cards: cards, Held: held, Payline: line}
+	if line != "" {
+		result.Pay = pt.Pay(line, h.Bet)
+	}
+	result.Payout = h.Bet * result.Pay
+
+	// Pay the hand, record it and remove the saved hand together, so a restart cannot
+	// draw it a second time
+	err := m.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.VideoPokerHand{}, "account_id = ?", playerID).Error; err != nil {
+			return err
+		}
+		if result.Payout > 0 {
+			err := tx.Model(&models.Account{}).
+				Where("id = ?", playerID).
+				Update("balance", gorm.Expr("balance + ?", result.Payout)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Create(&models.Wager{
+			AccountID:   playerID,
+			WagerAmount: h.Bet,
+			WagerWon:    result.Payout > h.Bet,
+			AmountWon:   result.Payout,
+			GameType:    GameName,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	delete(m.hands, playerID)
+
+	var account models.Account
+	if err := m.DB.First(&account, playerID).Error; err == nil {
+		result.Balance = account.Balance
+	}
+	return result, nil
+}
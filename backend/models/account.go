@@ -23,4 +23,5 @@ type Account struct {
 	OwnedColors  string `gorm:"default:'__'"`     // Owned color themes, encoded as string slots
 	EquipedItem  int    // Currently equipped cosmetic item index
 	EquipedColor int    // Currently equipped color theme index
+	IsAdmin      bool   `gorm:"default:false" json:"-"` // Can use the admin API, granted through ADMIN_EMAILS
 }
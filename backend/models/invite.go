@@ -0,0 +1,23 @@
+// Package models defines the data structures and database models for the card games application.
+// This file contains the Invite model used for shareable private table invite links.
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Invite is an invitation to a private table created by the table host.
+// The invite is shared as a signed token; this row tracks its expiry and how
+// many times it has been used.
+type Invite struct {
+	gorm.Model
+
+	GameID    string    `gorm:"not null;index"` // Private table the invite is for
+	HostID    uint      `gorm:"not null"`       // Host who created the invite
+	TargetID  uint      `gorm:"default:0"`      // Friend the invite is for, 0 if anyone with the link may use it
+	ExpiresAt time.Time `gorm:"not null"`
+	MaxUses   int       `gorm:"not null"`
+	Uses      int       `gorm:"default:0"`
+}
@@ -0,0 +1,24 @@
+// Package models defines the data structures and database models for the card games application.
+// This file contains the Notification model for messages shown to a user, such as table invites.
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// Notification kinds.
+const (
+	NotificationTableInvite = "table_invite"
+	NotificationEventOpened = "event_opened" // a scheduled table event the user subscribed to has opened
+)
+
+// Notification is a message for a single user.
+type Notification struct {
+	gorm.Model
+
+	AccountID uint   `gorm:"not null;index"` // User the notification is for
+	Kind      string `gorm:"not null"`
+	Message   string
+	Data      string // JSON payload for the client, depends on Kind
+	Read      bool   `gorm:"default:false"`
+}
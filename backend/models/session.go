@@ -0,0 +1,18 @@
+// Package models defines the data structures and database models for the card games application.
+// This file contains the Session model, a login session kept in the database so it
+// survives a server restart.
+package models
+
+import "time"
+
+// Session is a logged in user's session. A user has a session for each device they are
+// logged in on.
+type Session struct {
+	ID        string    `gorm:"primaryKey"` // hex SHA-256 of the ID in the session cookie
+	UserID    uint      `gorm:"not null;index"`
+	Expiry    time.Time `gorm:"index"`
+	UserAgent string
+	IP        string
+	CreatedAt time.Time
+	LastSeen  time.Time
+}
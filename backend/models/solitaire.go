@@ -0,0 +1,31 @@
+// Package models defines the data structures and database models for the card games application.
+// This file contains the SolitaireResult model used by Klondike leaderboards.
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SolitaireResult is a completed game of Klondike solitaire.
+// Results of the daily deal carry its date so they can be ranked against each other.
+type SolitaireResult struct {
+	gorm.Model
+
+	AccountID  uint   `gorm:"not null;index"`
+	DailyDate  string `gorm:"index"`    // Date of the daily deal as YYYY-MM-DD, empty for a random deal
+	DrawCount  int    `gorm:"not null"` // Cards turned from the stock at a time, 1 or 3
+	Moves      int    `gorm:"not null"`
+	Score      int
+	DurationMs int64 `gorm:"not null"` // Time from the deal to the last card reaching a foundation
+}
+
+// SolitaireDailyAttempt records that a player was dealt the daily deal of a date. A
+// player gets one attempt a day, so they cannot learn the deal and then replay it.
+type SolitaireDailyAttempt struct {
+	AccountID uint   `gorm:"primaryKey;autoIncrement:false"`
+	DailyDate string `gorm:"primaryKey"` // Date of the daily deal as YYYY-MM-DD
+	DrawCount int    `gorm:"not null"`
+	CreatedAt time.Time
+}
@@ -0,0 +1,44 @@
+// Package models defines the data structures and database models for the card games application.
+// This file contains the TableEvent and EventSubscription models used by scheduled table events.
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Table event status values.
+const (
+	EventScheduled = "scheduled" // waiting for StartsAt
+	EventOpen      = "open"      // the table is open until ClosesAt
+	EventFinished  = "finished"
+	EventCancelled = "cancelled"
+)
+
+// TableEvent is a table opened at a set time with preset rules and kept open for a
+// set duration, such as a weekly high-roller table. A recurring event is scheduled
+// again RepeatDays after it opened once it has closed.
+type TableEvent struct {
+	gorm.Model
+
+	Name            string
+	CreatorID       uint      `gorm:"not null"`
+	Game            string    `gorm:"not null"` // registered game name, e.g. "blackjack"
+	Mode            string    // table mode, empty for the game's default
+	Rules           string    // JSON game rules as the lobby sends them, empty for the defaults
+	StartsAt        time.Time `gorm:"index"` // next time the table opens
+	DurationMinutes int       `gorm:"not null"`
+	RepeatDays      int       `gorm:"default:0"` // 0 for a one-off event, 7 for a weekly one
+	Status          string    `gorm:"default:'scheduled'"`
+	TableID         string    // table of the event while it is open
+	ClosesAt        time.Time // when the open table closes
+}
+
+// EventSubscription is a user who wants to be notified when an event opens.
+type EventSubscription struct {
+	gorm.Model
+
+	EventID   uint `gorm:"not null;uniqueIndex:idx_event_subscriber"`
+	AccountID uint `gorm:"not null;uniqueIndex:idx_event_subscriber;index"`
+}
@@ -0,0 +1,19 @@
+// Package models defines the data structures and database models for the card games application.
+// This file contains the TableSnapshot model, the saved state of a game table so it
+// survives a server restart.
+package models
+
+import "time"
+
+// TableSnapshot is the last saved state of a game table. The game decides what goes
+// in State, it is handed back to the game when the table is restored on boot.
+type TableSnapshot struct {
+	ID        string `gorm:"primaryKey"` // table ID, kept across restarts so links and invites still work
+	Game      string // registered game name
+	Mode      string
+	Private   bool
+	Scheduled bool   // opened by the event scheduler
+	State     []byte // game specific JSON
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
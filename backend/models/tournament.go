@@ -0,0 +1,52 @@
+// Package models defines the data structures and database models for the card games application.
+// This file contains the Tournament and TournamentEntry models used by elimination tournaments.
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Tournament status values.
+const (
+	TournamentRegistering = "registering"
+	TournamentRunning     = "running"
+	TournamentFinished    = "finished"
+	TournamentCancelled   = "cancelled" // Not enough entrants, buy-ins were refunded
+)
+
+// Tournament represents an elimination blackjack tournament.
+// Players pay BuyIn from their account balance into PrizePool and receive
+// StartingChips tournament chips that are only valid inside the tournament.
+type Tournament struct {
+	gorm.Model
+
+	Name              string
+	CreatorID         uint      `gorm:"not null"`
+	BuyIn             int       `gorm:"not null"` // Paid from Account.Balance when registering
+	StartingChips     int       `gorm:"not null"` // Tournament chips each entrant starts with
+	HandsPerRound     int       `gorm:"not null"` // Hands played at every table before eliminations
+	EliminatePerRound int       `gorm:"not null"` // Lowest stacks knocked out after each round
+	TableSize         int       `gorm:"not null"` // Maximum players seated at one table
+	MaxPlayers        int       `gorm:"not null"`
+	Payouts           string    `gorm:"not null"` // Comma separated prize percentages, e.g. "50,30,20"
+	PrizePool         int       `gorm:"default:0"`
+	Round             int       `gorm:"default:0"`
+	StartsAt          time.Time // Registration closes and play begins at this time
+	Status            string    `gorm:"default:'registering'"`
+}
+
+// TournamentEntry is a player's registration in a tournament. Once the tournament
+// has finished it doubles as the final results record for that player.
+type TournamentEntry struct {
+	gorm.Model
+
+	TournamentID   uint `gorm:"not null;index"`
+	AccountID      uint `gorm:"not null;index"`
+	Chips          int  // Current tournament chip stack
+	Eliminated     bool `gorm:"default:false"`
+	EliminatedAt   int  // Round the player was knocked out in
+	FinishPosition int  // Final placing, 1 is the winner. 0 until decided
+	Prize          int  `gorm:"default:0"` // Amount paid to Account.Balance at the end
+}
@@ -0,0 +1,17 @@
+// Package models defines the data structures and database models for the card games application.
+// This file contains the VideoPokerHand model, a dealt video poker hand kept until it is
+// drawn so the bet is not lost if the server restarts in between.
+package models
+
+import "time"
+
+// VideoPokerHand is a video poker hand that has been dealt and not drawn yet. A player
+// has at most one.
+type VideoPokerHand struct {
+	AccountID uint   `gorm:"primaryKey;autoIncrement:false"`
+	PayTable  string `gorm:"not null"`
+	Bet       int    `gorm:"not null"`
+	Cards     []byte // JSON of the five dealt cards
+	Deck      []byte // JSON of the rest of the deck, the draw comes from here
+	DealtAt   time.Time
+}
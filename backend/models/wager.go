@@ -12,4 +12,5 @@ type Wager struct {
 	WagerAmount int  `gorm:"not null"` // Amount in cents (to avoid floating point issues)
 	WagerWon    bool `gorm:"default:false"` // Whether the wager was won
 	AmountWon  int  `gorm:"default:0"`   // Amount won
+	GameType    string `gorm:"default:'blackjack';index"` // Game the wager was made in, e.g. "blackjack", "holdem" or "baccarat"
 }
@@ -32,6 +32,10 @@ function Blackjack() {
   useEffect(() => {
     console.log("Lobby ID:", id);
     const handleMessage = (message) => {
+      // Chat messages share the socket but do not carry game state
+      if (message.Type === "chat") {
+        return;
+      }
       setGameState(message);
     };
 
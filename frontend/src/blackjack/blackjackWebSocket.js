@@ -32,7 +32,7 @@ class BlackjackWebSocket {
     // In development, use the current host (Vite dev server will proxy WebSocket)
     // In production, use the actual host
     const host = window.location.host;
-    const wsUrl = `${protocol}//${host}/api/ws/BlackJack/${this.gameID}`;
+    const wsUrl = `${protocol}//${host}/api/ws/blackjack/${this.gameID}`;
 
     console.log("Connecting to WebSocket:", wsUrl);
 
@@ -49,6 +49,21 @@ export const logoutUser = () =>
     method: "POST",
   });
 
+// List the devices the user is logged in on, last seen first; `current` marks this one
+export const listSessions = () => request("/api/sessions");
+
+// Log out one of the user's sessions by the id from listSessions
+export const revokeSession = (sessionId) =>
+  request(`/api/sessions/${sessionId}`, {
+    method: "DELETE",
+  });
+
+// Log out everywhere, this device included
+export const logoutEverywhere = () =>
+  request("/api/sessions", {
+    method: "DELETE",
+  });
+
 export const getCurrency = () => request("/api/currency");
 
 // Add currency amount to user's balance
@@ -123,8 +138,105 @@ export const openLootbox = () =>
 // Join or create a game lobby
 // game: "blackjack", "uno", "poker", etc.
 // visibility: "public" or "private"
-export const joinLobby = (game, visibility) =>
+// mode: "standard" for real-money tables, "practice" for play-money tables
+// or "trainer" for play-money tables with the card counting trainer;
+// blackjack also has "spanish21" and "free_bet" for real-money variant tables
+// preferences: matchmaking options for public tables, all optional:
+// { stake, sitWithFriends, fastPace, noBots }; the response's reason says why the table was picked
+export const joinLobby = (game, visibility, mode = "standard", preferences = {}) =>
+  request("/api/lobby", {
+    method: "POST",
+    body: JSON.stringify({ game, visibility, mode, ...preferences }),
+  });
+
+// List the public tables for the table browser
+// filters: { game, mode, phase, open, maxStake, sort, order }, all optional;
+// sort is "players", "open", "stakes" or "id" and order "desc" reverses it
+export const listTables = (filters = {}) => {
+  const params = new URLSearchParams();
+  for (const [key, value] of Object.entries(filters)) {
+    if (value !== undefined && value !== null && value !== "") {
+      params.set(key, value);
+    }
+  }
+  const query = params.toString();
+  return request(query ? `/api/lobby/tables?${query}` : "/api/lobby/tables");
+};
+
+// Join a public table picked from the table browser
+export const joinTable = (gameId) =>
   request("/api/lobby", {
     method: "POST",
-    body: JSON.stringify({ game, visibility }),
+    body: JSON.stringify({ gameId }),
+  });
+
+// Swap a private table's password for a single-use join token, valid for a minute;
+// connect with /api/ws/{game}/{gameId}?joinToken=<token> so the password stays out of the URL
+export const getJoinToken = (gameId, password) =>
+  request(`/api/tables/${gameId}/join-token`, {
+    method: "POST",
+    body: JSON.stringify({ password }),
+  });
+
+// Stream live lobby changes over Server-Sent Events
+// handlers: { tables, table_created, table_closed, occupancy, friend_seated },
+// each called with the parsed event data; returns a function that closes the stream
+export const subscribeLobby = (handlers = {}) => {
+  const source = new EventSource("/api/lobby/events", { withCredentials: true });
+  for (const [event, handler] of Object.entries(handlers)) {
+    source.addEventListener(event, (e) => handler(JSON.parse(e.data)));
+  }
+  return () => source.close();
+};
+
+// List the scheduled and open table events, soonest first, with whether you are subscribed
+export const listEvents = () => request("/api/events");
+
+// Get (or stop getting) a notification whenever a table event opens
+export const subscribeEvent = (eventId, subscribed = true) =>
+  request(`/api/events/${eventId}/subscribe`, {
+    method: subscribed ? "POST" : "DELETE",
+  });
+
+// Admin: list every table with its phase, players, shoe depth, uptime and rounds played
+export const adminListTables = () => request("/api/admin/tables");
+
+// Admin: full state of one table, hidden cards included
+export const adminGetTable = (gameId) => request(`/api/admin/tables/${gameId}`);
+
+// Admin: pause or resume a table; a paused table finishes its round and deals no new one
+export const adminPauseTable = (gameId, paused = true) =>
+  request(`/api/admin/tables/${gameId}/${paused ? "pause" : "resume"}`, {
+    method: "POST",
+  });
+
+// Admin: close a table right away, chips in an unsettled round are refunded
+export const adminCloseTable = (gameId) =>
+  request(`/api/admin/tables/${gameId}/close`, {
+    method: "POST",
+  });
+
+// Admin: list every table event, finished and cancelled ones included
+export const adminListEvents = () => request("/api/admin/events");
+
+// Admin: schedule a table event
+// settings: { name, game, mode, rules, startsAt, durationMinutes, repeatDays };
+// rules are the game's rules as for a private table, repeatDays is 0 for a one-off event
+export const adminCreateEvent = (settings) =>
+  request("/api/admin/events", {
+    method: "POST",
+    body: JSON.stringify(settings),
+  });
+
+// Admin: change an event that is not open, with the same settings as adminCreateEvent
+export const adminUpdateEvent = (eventId, settings) =>
+  request(`/api/admin/events/${eventId}`, {
+    method: "PUT",
+    body: JSON.stringify(settings),
+  });
+
+// Admin: cancel an event; an open event closes its table once the round in progress is over
+export const adminCancelEvent = (eventId) =>
+  request(`/api/admin/events/${eventId}`, {
+    method: "DELETE",
   });